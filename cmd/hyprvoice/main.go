@@ -2,9 +2,16 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -12,40 +19,91 @@ import (
 	"github.com/leonardotrapani/hyprvoice/internal/bus"
 	"github.com/leonardotrapani/hyprvoice/internal/config"
 	"github.com/leonardotrapani/hyprvoice/internal/daemon"
+	"github.com/leonardotrapani/hyprvoice/internal/injection"
+	"github.com/leonardotrapani/hyprvoice/internal/llm"
+	"github.com/leonardotrapani/hyprvoice/internal/pipeline"
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+	"github.com/leonardotrapani/hyprvoice/internal/transcriber"
 	"github.com/spf13/cobra"
 )
 
 // version is set at build time via -ldflags "-X main.version=x.y.z"
 var version = "dev"
 
+// Exit codes, so scripts can tell a rejected command from a daemon that isn't reachable
+const (
+	exitOK          = 0
+	exitDaemonError = 1 // The daemon replied ERR
+	exitFailure     = 2 // Anything else: daemon not running, bad arguments, config errors
+)
+
+// quiet suppresses printing daemon replies (--quiet); the exit code still reports the outcome
+var quiet bool
+
+// connectAttempts is how often commands try to reach the daemon (--connect-attempts)
+var connectAttempts int
+
 func main() {
-	_ = rootCmd.Execute()
+	err := rootCmd.Execute()
+	var daemonErr *daemonError
+	switch {
+	case err == nil:
+		os.Exit(exitOK)
+	case errors.As(err, &daemonErr):
+		os.Exit(exitDaemonError)
+	default:
+		os.Exit(exitFailure)
+	}
 }
 
 var rootCmd = &cobra.Command{
-	Use:   "hyprvoice",
-	Short: "Voice-powered typing for Wayland/Hyprland",
+	Use:          "hyprvoice",
+	Short:        "Voice-powered typing for Wayland/Hyprland",
+	SilenceUsage: true,
 }
 
 func init() {
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Don't print the daemon's reply; check the exit code instead")
+	rootCmd.PersistentFlags().IntVar(&connectAttempts, "connect-attempts", bus.DefaultDialAttempts, "Connection attempts, with backoff, before giving up on the daemon (1 = fail at once)")
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		bus.SetDialAttempts(connectAttempts)
+	}
 	rootCmd.AddCommand(
 		serveCmd(),
 		toggleCmd(),
 		cancelCmd(),
+		confirmCmd(),
+		injectCmd(),
+		discardCmd(),
 		statusCmd(),
+		waitCmd(),
+		infoCmd(),
 		versionCmd(),
 		stopCmd(),
 		configureCmd(),
 		modeCmd(),
+		continueCmd(),
+		langCmd(),
+		levelCmd(),
+		providerCmd(),
+		modelCmd(),
+		transcribeCmd(),
+		llmTestCmd(),
+		micTestCmd(),
+		keybindsCmd(),
 		showCmd(),
 	)
 }
 
 func serveCmd() *cobra.Command {
-	return &cobra.Command{
+	var allowUnknownModel bool
+
+	cmd := &cobra.Command{
 		Use:   "serve",
 		Short: "Run the daemon",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			config.AllowUnknownModels(allowUnknownModel)
+
 			d, err := daemon.New()
 			if err != nil {
 				return fmt.Errorf("failed to create daemon: %w", err)
@@ -53,136 +111,1018 @@ func serveCmd() *cobra.Command {
 			return d.Run()
 		},
 	}
+
+	cmd.Flags().BoolVar(&allowUnknownModel, "allow-unknown-model", false, "Accept transcription and LLM models outside the known provider lists")
+	return cmd
 }
 
 func toggleCmd() *cobra.Command {
-	return &cobra.Command{
+	var tag string
+
+	cmd := &cobra.Command{
 		Use:   "toggle",
 		Short: "Toggle recording on/off",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			resp, err := bus.SendCommand('t')
+			resp, err := bus.SendToggleCommand(tag)
 			if err != nil {
 				return fmt.Errorf("failed to toggle recording: %w", err)
 			}
-			fmt.Print(resp)
+			return printResponse(resp)
+		},
+	}
+
+	cmd.Flags().StringVar(&tag, "tag", "", "Label the dictation this toggle starts, e.g. standup (shown in logs and failsafe entries)")
+	return cmd
+}
+
+func statusCmd() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Get current recording status",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if asJSON {
+				return printInfoJSON("status")
+			}
+			resp, err := bus.SendCommand('s')
+			if err != nil {
+				return fmt.Errorf("failed to get status: %w", err)
+			}
+			return printResponse(resp)
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, `Print {"status":...} as JSON`)
+	return cmd
+}
+
+// waitPollInterval is how often "hyprvoice wait" asks the daemon for its status
+const waitPollInterval = 100 * time.Millisecond
+
+func waitCmd() *cobra.Command {
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Block until the current dictation finishes",
+		Long: `Block until the daemon is back to idle, then exit 0 if the dictation
+succeeded or 1 if it ended in an error. Returns at once when nothing is running,
+reporting the outcome of the last dictation.
+
+Example:
+  hyprvoice toggle && hyprvoice wait && notify-send "Dictation done"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var deadline time.Time
+			if timeout > 0 {
+				deadline = time.Now().Add(timeout)
+			}
+
+			info, err := fetchInfo()
+			if err != nil {
+				return err
+			}
+			waited := false
+			for info.Status != string(pipeline.Idle) {
+				if !deadline.IsZero() && time.Now().After(deadline) {
+					return fmt.Errorf("still %s after %v", info.Status, timeout)
+				}
+				time.Sleep(waitPollInterval)
+				waited = true
+				if info, err = fetchInfo(); err != nil {
+					return err
+				}
+			}
+			if waited {
+				// Errors reach the daemon just before the pipeline goes idle, give them a moment
+				time.Sleep(waitPollInterval)
+				if info, err = fetchInfo(); err != nil {
+					return err
+				}
+			}
+
+			if info.LastError != "" {
+				return &daemonError{fmt.Errorf("dictation failed: %s error", info.LastError)}
+			}
+			if !quiet {
+				fmt.Println("idle")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "Give up after this long (0 = wait forever)")
+	return cmd
+}
+
+// fetchInfo asks the daemon for its 'i' snapshot
+func fetchInfo() (bus.Info, error) {
+	resp, err := bus.SendCommand('i')
+	if err != nil {
+		return bus.Info{}, fmt.Errorf("failed to get info: %w", err)
+	}
+	parsed, err := parseResponse(resp)
+	if err != nil {
+		return bus.Info{}, err
+	}
+	var info bus.Info
+	if err := json.Unmarshal([]byte(parsed.Fields["json"]), &info); err != nil {
+		return bus.Info{}, fmt.Errorf("invalid info reply: %w", err)
+	}
+	return info, nil
+}
+
+func infoCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "info",
+		Short: "Print daemon status, mode, and uptime as JSON",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resp, err := bus.SendCommand('i')
+			if err != nil {
+				return fmt.Errorf("failed to get info: %w", err)
+			}
+			parsed, err := parseResponse(resp)
+			if err != nil {
+				return err
+			}
+			if !quiet {
+				fmt.Println(parsed.Fields["json"])
+			}
+			return nil
+		},
+	}
+}
+
+func versionCmd() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print application version",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if asJSON {
+				return printJSON(map[string]any{"version": version})
+			}
+			fmt.Printf("hyprvoice %s\n", version)
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, `Print {"version":...} as JSON`)
+	return cmd
+}
+
+func stopCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the daemon",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resp, err := bus.SendCommand('q')
+			if err != nil {
+				return fmt.Errorf("failed to stop daemon: %w", err)
+			}
+			return printResponse(resp)
+		},
+	}
+}
+
+func cancelCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cancel",
+		Short: "Cancel current operation",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resp, err := bus.SendCommand('c')
+			if err != nil {
+				return fmt.Errorf("failed to cancel operation: %w", err)
+			}
+			return printResponse(resp)
+		},
+	}
+}
+
+func confirmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "confirm",
+		Short: "Inject the transcription awaiting confirmation",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resp, err := bus.SendCommand('y')
+			if err != nil {
+				return fmt.Errorf("failed to confirm transcription: %w", err)
+			}
+			return printResponse(resp)
+		},
+	}
+}
+
+func injectCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "inject",
+		Short: "Inject the transcription held by behavior.explicit_inject",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resp, err := bus.SendCommand('j')
+			if err != nil {
+				return fmt.Errorf("failed to inject transcription: %w", err)
+			}
+			return printResponse(resp)
+		},
+	}
+}
+
+func discardCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "discard",
+		Short: "Discard the transcription awaiting confirmation",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resp, err := bus.SendCommand('n')
+			if err != nil {
+				return fmt.Errorf("failed to discard transcription: %w", err)
+			}
+			return printResponse(resp)
+		},
+	}
+}
+
+func configureCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "configure",
+		Short: "Interactive configuration setup",
+		Long: `Interactive configuration wizard for hyprvoice.
+This will guide you through setting up:
+- Transcription provider (OpenAI or Groq) and task (transcribe or translate)
+- API keys and model selection
+- Audio and text injection preferences
+- Notification settings
+
+With --dry-run the resulting config is validated and printed instead of saved.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInteractiveConfig(dryRun)
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the resulting config instead of writing it")
+	return cmd
+}
+
+func modeCmd() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "mode [raw|llm|reset]",
+		Short: "Get or set processing mode",
+		Long: `Get or set the post-transcription processing mode.
+
+With no arguments: displays the current processing mode.
+With an argument: sets the processing mode for the current session.
+The session mode wins over processing.mode until "reset", a daemon restart,
+or a config reload that changes processing.mode.
+
+Modes:
+  raw  - Direct transcription output (default)
+  llm  - Clean up transcription using AI (removes filler words, fixes punctuation)
+
+Examples:
+  hyprvoice mode        # Show current mode
+  hyprvoice mode raw    # Switch to raw mode
+  hyprvoice mode llm    # Switch to LLM cleanup mode
+  hyprvoice mode reset  # Go back to processing.mode from the config
+  hyprvoice mode --json # Print {"mode":"raw"}`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				if asJSON {
+					return printInfoJSON("mode")
+				}
+				// Get current mode
+				resp, err := bus.SendModeCommand("")
+				if err != nil {
+					return fmt.Errorf("failed to get mode: %w", err)
+				}
+				return printResponse(resp)
+			}
+
+			// Set mode
+			mode := args[0]
+			if mode != "raw" && mode != "llm" && mode != "reset" {
+				return fmt.Errorf("invalid mode: %s (must be 'raw', 'llm' or 'reset')", mode)
+			}
+
+			resp, err := bus.SendModeCommand(mode)
+			if err != nil {
+				return fmt.Errorf("failed to set mode: %w", err)
+			}
+			if asJSON {
+				if _, err := parseResponse(resp); err != nil {
+					return err
+				}
+				return printInfoJSON("mode")
+			}
+			return printResponse(resp)
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, `Print {"mode":...} as JSON`)
+	return cmd
+}
+
+func continueCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "continue [on|off]",
+		Short: "Get or set mid-sentence dictation",
+		Long: `Get or set continue-sentence mode for the current session.
+
+When on, the first word of each transcription is lowercased so it can be
+dictated into the middle of an existing sentence.
+
+Examples:
+  hyprvoice continue      # Show current setting
+  hyprvoice continue on   # Lowercase the first word
+  hyprvoice continue off  # Keep Whisper's capitalization`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				resp, err := bus.SendContinueCommand("")
+				if err != nil {
+					return fmt.Errorf("failed to get continue-sentence setting: %w", err)
+				}
+				return printResponse(resp)
+			}
+
+			value := args[0]
+			if value != "on" && value != "off" {
+				return fmt.Errorf("invalid value: %s (must be 'on' or 'off')", value)
+			}
+
+			resp, err := bus.SendContinueCommand(value)
+			if err != nil {
+				return fmt.Errorf("failed to set continue-sentence setting: %w", err)
+			}
+			return printResponse(resp)
+		},
+	}
+}
+
+func langCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lang [code|auto]",
+		Short: "Get or set the transcription language",
+		Long: `Get or set the transcription language for the current session.
+
+The language also selects the Whisper prompt: an entry for it under
+[transcription.prompts] replaces transcription.prompt, so vocabulary bias
+follows the language you dictate in.
+
+Examples:
+  hyprvoice lang        # Show current language
+  hyprvoice lang it     # Dictate in Italian
+  hyprvoice lang auto   # Let Whisper detect the language`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				resp, err := bus.SendLanguageCommand("")
+				if err != nil {
+					return fmt.Errorf("failed to get language: %w", err)
+				}
+				return printResponse(resp)
+			}
+
+			language := args[0]
+			if language != "auto" && !config.IsValidLanguageCode(language) {
+				return fmt.Errorf("invalid language: %s (must be 'auto' or an ISO-639-1 code like 'en' or 'it')", language)
+			}
+
+			resp, err := bus.SendLanguageCommand(language)
+			if err != nil {
+				return fmt.Errorf("failed to set language: %w", err)
+			}
+			return printResponse(resp)
+		},
+	}
+}
+
+func levelCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "level [minimal|moderate|thorough|custom]",
+		Short: "Get or set the LLM intervention level",
+		Long: `Get or set the LLM intervention level for the current session.
+
+The level only matters while the processing mode is "llm". A per-level model
+under [llm.models] follows the level. "custom" needs llm.custom_prompt.
+
+Examples:
+  hyprvoice level            # Show current level
+  hyprvoice level minimal    # Only fix typos and punctuation
+  hyprvoice level thorough   # Rewrite for clarity`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				resp, err := bus.SendLevelCommand("")
+				if err != nil {
+					return fmt.Errorf("failed to get level: %w", err)
+				}
+				return printResponse(resp)
+			}
+
+			level := args[0]
+			if !llm.IsValidLevel(level) {
+				return fmt.Errorf("invalid level: %s (must be one of %s)", level, strings.Join(llm.Levels, ", "))
+			}
+
+			resp, err := bus.SendLevelCommand(level)
+			if err != nil {
+				return fmt.Errorf("failed to set level: %w", err)
+			}
+			return printResponse(resp)
+		},
+	}
+}
+
+func providerCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "provider [openai|groq|azure-openai|reset]",
+		Short: "Get or set the transcription provider",
+		Long: `Get or set the transcription provider for the current session.
+
+Another provider than the configured one takes its API key from the
+environment (OPENAI_API_KEY, GROQ_API_KEY or AZURE_OPENAI_API_KEY) and starts
+from its default model. Setting or resetting the provider also resets the model.
+
+Examples:
+  hyprvoice provider         # Show current provider
+  hyprvoice provider groq    # Transcribe with Groq
+  hyprvoice provider reset   # Back to transcription.provider`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				resp, err := bus.SendProviderCommand("")
+				if err != nil {
+					return fmt.Errorf("failed to get provider: %w", err)
+				}
+				return printResponse(resp)
+			}
+
+			provider := args[0]
+			if provider != "reset" && !slices.Contains(config.TranscriptionProviders, provider) {
+				return fmt.Errorf("invalid provider: %s (must be 'reset' or one of %s)", provider, strings.Join(config.TranscriptionProviders, ", "))
+			}
+
+			resp, err := bus.SendProviderCommand(provider)
+			if err != nil {
+				return fmt.Errorf("failed to set provider: %w", err)
+			}
+			return printResponse(resp)
+		},
+	}
+}
+
+func modelCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "model [name|reset]",
+		Short: "Get or set the transcription model",
+		Long: `Get or set the transcription model for the current session.
+
+The daemon checks the model against the current provider and task, so switch
+the provider first. For azure-openai the model is the deployment name.
+
+Examples:
+  hyprvoice model                          # Show current model
+  hyprvoice model whisper-large-v3-turbo   # Quick notes
+  hyprvoice model whisper-large-v3         # Important dictation
+  hyprvoice model reset                    # Back to the configured model`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				resp, err := bus.SendModelCommand("")
+				if err != nil {
+					return fmt.Errorf("failed to get model: %w", err)
+				}
+				return printResponse(resp)
+			}
+
+			resp, err := bus.SendModelCommand(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to set model: %w", err)
+			}
+			return printResponse(resp)
+		},
+	}
+}
+
+func transcribeCmd() *cobra.Command {
+	var mode string
+	var format string
+	var inject bool
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "transcribe <file.wav>",
+		Short: "Transcribe a WAV file instead of recording",
+		Long: `Send an audio file through the configured transcriber (and the processing.pipeline
+stages, including LLM cleanup when processing.mode is "llm") and print the result. The daemon does not need to be running.
+
+The file must be 16 kHz mono 16-bit PCM WAV, the format hyprvoice records. Convert
+other audio with:
+  ffmpeg -i input.m4a -ar 16000 -ac 1 -c:a pcm_s16le output.wav
+
+Non-text formats (--format or transcription.response_format) print the provider's
+output unchanged and skip processing and injection.
+
+Examples:
+  hyprvoice transcribe sample.wav               # Print the transcription
+  hyprvoice transcribe sample.wav --mode raw    # Skip LLM cleanup
+  hyprvoice transcribe sample.wav --inject      # Also type it into the focused window
+  hyprvoice transcribe talk.wav --format srt > talk.srt  # Subtitles (OpenAI)
+  hyprvoice transcribe memo.wav --timeout 30s   # Only the first 30 seconds`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if mode != "" {
+				if mode != "raw" && mode != "llm" {
+					return fmt.Errorf("invalid mode: %s (must be 'raw' or 'llm')", mode)
+				}
+				cfg.Processing.Mode = mode
+			}
+			if format != "" {
+				cfg.Transcription.ResponseFormat = format
+			}
+			if cmd.Flags().Changed("timeout") {
+				cfg.Recording.Timeout = timeout
+			}
+			if err := cfg.Validate(); err != nil {
+				return fmt.Errorf("invalid config: %w", err)
+			}
+
+			audio, err := transcriber.LoadWAVFile(args[0])
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("timeout") {
+				audio = truncateAudio(audio, cfg.Recording.Timeout)
+			}
+
+			text, err := transcribeAudio(context.Background(), cfg, audio)
+			if err != nil {
+				return err
+			}
+			fmt.Println(text)
+
+			if inject && !transcriber.IsEmptyTranscription(text) && cfg.Transcription.ResponseFormat == transcriber.ResponseFormatText {
+				text = cfg.Injection.Prefix + text + cfg.Injection.Suffix
+				if err := injection.NewInjector(cfg.ToInjectionConfig()).Inject(context.Background(), text, ""); err != nil {
+					return fmt.Errorf("failed to inject text: %w", err)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&mode, "mode", "", "Processing mode for this run: raw or llm (default from config)")
+	cmd.Flags().StringVar(&format, "format", "", "Output format: text, json, verbose_json, srt, or vtt (default from config)")
+	cmd.Flags().BoolVar(&inject, "inject", false, "Inject the result into the focused window after printing it")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "Override recording.timeout: only transcribe this much of the file, as if recording stopped there")
+	return cmd
+}
+
+// truncateAudio cuts 16 kHz mono 16-bit PCM to at most d, the way recording.timeout
+// ends a live recording
+func truncateAudio(audio []byte, d time.Duration) []byte {
+	const bytesPerSecond = 16000 * 2
+	limit := int(d.Seconds() * bytesPerSecond)
+	limit -= limit % 2 // Keep whole samples
+	if limit < len(audio) {
+		fmt.Fprintf(os.Stderr, "Transcribing the first %v of %v (--timeout)\n", d,
+			time.Duration(float64(len(audio))/bytesPerSecond*float64(time.Second)).Round(time.Millisecond))
+		return audio[:limit]
+	}
+	return audio
+}
+
+// transcribeAudio feeds raw PCM through the configured transcriber as a single frame,
+// then runs the processing.pipeline stages when the output is plain text
+func transcribeAudio(ctx context.Context, cfg *config.Config, audio []byte) (string, error) {
+	transcriberConfig := cfg.ToTranscriberConfig()
+	transcriberConfig.ResponseFormat = cfg.Transcription.ResponseFormat
+	transcriberConfig.Realtime = false // The whole file is already here: one upload beats streaming it
+	t, err := transcriber.NewTranscriber(transcriberConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to create transcriber: %w", err)
+	}
+
+	frameCh := make(chan recording.AudioFrame, 1)
+	if _, err := t.Start(ctx, frameCh); err != nil {
+		return "", fmt.Errorf("failed to start transcriber: %w", err)
+	}
+	frameCh <- recording.AudioFrame{Data: audio, Timestamp: time.Now()}
+	close(frameCh)
+
+	if err := t.Stop(ctx); err != nil {
+		return "", err
+	}
+	result, err := t.GetFinalTranscription()
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve transcription: %w", err)
+	}
+	text := result.Text
+
+	if text == "" || cfg.Transcription.ResponseFormat != transcriber.ResponseFormatText {
+		return text, nil
+	}
+	stages, err := pipeline.NewStages(cfg)
+	if err != nil {
+		return "", err
+	}
+	processed, err := pipeline.RunStages(ctx, stages, text)
+	if err != nil {
+		return "", fmt.Errorf("processing failed: %w", err)
+	}
+	return processed, nil
+}
+
+func llmTestCmd() *cobra.Command {
+	var input string
+	var level string
+
+	cmd := &cobra.Command{
+		Use:   "llm-test",
+		Short: "Run the LLM cleanup on sample text",
+		Long: `Send text through the configured LLM processor and print the cleaned result,
+without recording or transcribing. Useful for tuning llm.custom_prompt and comparing
+levels. The text comes from --input, or from stdin when --input is not given.
+
+The result goes to stdout; the model, level and timing go to stderr. The daemon
+does not need to be running, and processing.mode does not have to be "llm".
+
+Examples:
+  hyprvoice llm-test --input "um so like the thing is"
+  hyprvoice llm-test --level thorough < notes.txt
+  hyprvoice llm-test --level custom --input "..."   # Try llm.custom_prompt`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			cfg.Processing.Mode = "llm"
+			if level != "" {
+				if !llm.IsValidLevel(level) {
+					return fmt.Errorf("invalid level: %s (must be one of %s)", level, strings.Join(llm.Levels, ", "))
+				}
+				cfg.LLM.Level = level
+			}
+			if err := cfg.Validate(); err != nil {
+				return fmt.Errorf("invalid config: %w", err)
+			}
+
+			text := input
+			if !cmd.Flags().Changed("input") {
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					return fmt.Errorf("failed to read stdin: %w", err)
+				}
+				text = string(data)
+			}
+			text = strings.TrimSpace(text)
+			if text == "" {
+				return fmt.Errorf("no input text (use --input or pipe text on stdin)")
+			}
+
+			llmConfig := cfg.ToLLMConfig()
+			processor, err := llm.NewProcessor(llmConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create LLM processor: %w", err)
+			}
+
+			start := time.Now()
+			output, err := processor.Process(context.Background(), text)
+			if err != nil {
+				return fmt.Errorf("LLM processing failed: %w", err)
+			}
+			fmt.Println(output)
+			fmt.Fprintf(os.Stderr, "model %s, level %s, %d -> %d characters in %s\n",
+				llmConfig.ModelForLevel(), llmConfig.Level, len(text), len(output), time.Since(start).Round(time.Millisecond))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&input, "input", "", "Text to process (default: read stdin)")
+	cmd.Flags().StringVar(&level, "level", "", "LLM level for this run: minimal, moderate, thorough, or custom (default from config)")
+	return cmd
+}
+
+func micTestCmd() *cobra.Command {
+	var duration time.Duration
+	var play bool
+	var transcribe bool
+
+	cmd := &cobra.Command{
+		Use:   "mic-test",
+		Short: "Record a short sample and report microphone levels",
+		Long: `Record from the configured device for a few seconds and report the peak and RMS
+level, so a muted, missing, or clipping microphone is caught before dictating.
+Nothing is injected, and the daemon does not need to be running.
+
+With --transcribe the sample also goes through the configured transcriber and the
+processing.pipeline stages, checking the whole chain from microphone to text.
+That is a billed API request, so it only happens when asked for.
+
+Examples:
+  hyprvoice mic-test                 # Record 3 seconds and report levels
+  hyprvoice mic-test --duration 5s   # Record longer
+  hyprvoice mic-test --play          # Play the sample back afterwards
+  hyprvoice mic-test --transcribe    # Also transcribe the sample`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if err := cfg.Validate(); err != nil {
+				return fmt.Errorf("invalid config: %w", err)
+			}
+			if duration <= 0 {
+				return fmt.Errorf("invalid duration: %s", duration)
+			}
+
+			recordingConfig := cfg.ToRecordingConfig()
+
+			ctx, cancel := context.WithTimeout(context.Background(), duration)
+			defer cancel()
+
+			recorder := recording.NewRecorder(recordingConfig)
+			frameCh, errCh, err := recorder.Start(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to start recording: %w", err)
+			}
+			defer recorder.Stop()
+
+			device := recorder.Device()
+			if device == "" {
+				device = "the default input"
+			}
+			fmt.Printf("Recording %s from %s via %s, speak now...\n", duration, device, recorder.Backend())
+
+			var audio []byte
+			for frame := range frameCh {
+				audio = append(audio, frame.Data...)
+			}
+			if err := <-errCh; err != nil {
+				return fmt.Errorf("recording failed: %w", err)
+			}
+			if len(audio) == 0 {
+				return fmt.Errorf("no audio captured: check that the device exists (recording.device) and is not in use")
+			}
+
+			peak, rms := recording.Levels(audio)
+			seconds := float64(len(audio)) / float64(2*recordingConfig.SampleRate*recordingConfig.Channels)
+			fmt.Printf("Captured: %.1fs\n", seconds)
+			fmt.Printf("Peak:     %.1f dBFS\n", peak)
+			fmt.Printf("RMS:      %.1f dBFS\n", rms)
+			fmt.Println(micVerdict(peak, rms))
+
+			if play {
+				fmt.Println("Playing back...")
+				if err := playAudio(recorder.Backend(), recordingConfig, audio); err != nil {
+					return fmt.Errorf("playback failed: %w", err)
+				}
+			}
+
+			if transcribe {
+				transcriberConfig := cfg.ToTranscriberConfig()
+				fmt.Printf("Transcribing via %s %s...\n", transcriberConfig.Provider, transcriberConfig.Model)
+
+				// Live dictation injects plain text, whatever "hyprvoice transcribe" is set to print
+				cfg.Transcription.ResponseFormat = transcriber.ResponseFormatText
+				start := time.Now()
+				text, err := transcribeAudio(context.Background(), cfg, audio)
+				if err != nil {
+					return fmt.Errorf("transcription failed: %w", err)
+				}
+				elapsed := time.Since(start).Round(time.Millisecond)
+				if transcriber.IsEmptyTranscription(text) {
+					fmt.Printf("Transcript: (no speech recognized, %s)\n", elapsed)
+				} else {
+					fmt.Printf("Transcript: %s (%s)\n", text, elapsed)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&duration, "duration", 3*time.Second, "How long to record")
+	cmd.Flags().BoolVar(&play, "play", false, "Play the recording back afterwards")
+	cmd.Flags().BoolVar(&transcribe, "transcribe", false, "Also transcribe the sample with the configured provider (a billed API request)")
+	return cmd
+}
+
+// micVerdict turns measured levels into advice for the mic-test command
+func micVerdict(peak, rms float64) string {
+	switch {
+	case peak <= -60:
+		return "No signal: the microphone looks muted, or recording.device points at the wrong source"
+	case peak >= -0.5:
+		return "Clipping: the input is too loud, lower the microphone gain"
+	case rms < -50:
+		return "Very quiet: move closer to the microphone or raise its gain"
+	default:
+		return "OK: levels look good for dictation"
+	}
+}
+
+// playAudio plays raw 16-bit PCM with the player matching the recording backend
+func playAudio(backend string, cfg recording.Config, audio []byte) error {
+	name, args := "pw-play", []string{
+		"--format", recording.FormatS16, // Frames are always s16 after capture
+		"--rate", strconv.Itoa(cfg.SampleRate),
+		"--channels", strconv.Itoa(cfg.Channels),
+		"-",
+	}
+	if backend == recording.BackendPulse {
+		name, args = "paplay", []string{
+			"--raw",
+			"--format=s16le",
+			"--rate=" + strconv.Itoa(cfg.SampleRate),
+			"--channels=" + strconv.Itoa(cfg.Channels),
+		}
+	}
+
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(audio)
+	return cmd.Run()
+}
+
+// printResponse prints a daemon reply, reporting ERR replies as a command error instead
+func printResponse(resp string) error {
+	if _, err := parseResponse(resp); err != nil {
+		return err
+	}
+	if !quiet {
+		fmt.Print(resp)
+	}
+	return nil
+}
+
+// printInfoJSON asks the daemon for its info snapshot and prints the given keys as a JSON object
+func printInfoJSON(keys ...string) error {
+	resp, err := bus.SendCommand('i')
+	if err != nil {
+		return fmt.Errorf("failed to get info: %w", err)
+	}
+	parsed, err := parseResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	var info map[string]any
+	if err := json.Unmarshal([]byte(parsed.Fields["json"]), &info); err != nil {
+		return fmt.Errorf("invalid info reply: %w", err)
+	}
+	out := make(map[string]any, len(keys))
+	for _, key := range keys {
+		value, ok := info[key]
+		if !ok {
+			return fmt.Errorf("daemon info has no %q field (daemon too old?)", key)
+		}
+		out[key] = value
+	}
+	return printJSON(out)
 }
 
-func statusCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "status",
-		Short: "Get current recording status",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			resp, err := bus.SendCommand('s')
-			if err != nil {
-				return fmt.Errorf("failed to get status: %w", err)
-			}
-			fmt.Print(resp)
-			return nil
-		},
+// printJSON prints v as one line of JSON unless --quiet is set
+func printJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if !quiet {
+		fmt.Println(string(data))
 	}
+	return nil
 }
 
-func versionCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "version",
-		Short: "Print application version",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Printf("hyprvoice %s\n", version)
-		},
-	}
+// daemonError is an ERR reply from the daemon, as opposed to a failure to reach it
+type daemonError struct {
+	err error
 }
 
-func stopCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "stop",
-		Short: "Stop the daemon",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			resp, err := bus.SendCommand('q')
-			if err != nil {
-				return fmt.Errorf("failed to stop daemon: %w", err)
-			}
-			fmt.Print(resp)
-			return nil
-		},
+func (e *daemonError) Error() string { return e.err.Error() }
+func (e *daemonError) Unwrap() error { return e.err }
+
+// parseResponse parses a daemon reply, turning ERR replies into a *daemonError
+func parseResponse(resp string) (bus.Response, error) {
+	parsed, err := bus.ParseResponse(resp)
+	if err != nil {
+		return bus.Response{}, err
+	}
+	if err := parsed.Err(); err != nil {
+		return bus.Response{}, &daemonError{err}
 	}
+	return parsed, nil
 }
 
-func cancelCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "cancel",
-		Short: "Cancel current operation",
+func keybindsCmd() *cobra.Command {
+	var mod string
+	var key string
+	var useSignal bool
+
+	cmd := &cobra.Command{
+		Use:   "keybinds",
+		Short: "Print Hyprland bind lines for your config",
+		Long: `Print ready-to-paste Hyprland bind lines for ~/.config/hypr/hyprland.conf.
+
+The lines follow the current config: mode and level binds appear when an LLM
+API key is available, confirm/discard binds when behavior.confirm_before_inject
+is on, inject/discard binds when behavior.explicit_inject is on, and language
+binds for each entry under [transcription.prompts].
+
+Examples:
+  hyprvoice keybinds                              # SUPER+R toggles recording
+  hyprvoice keybinds --mod "SUPER CTRL" --key V   # Different base shortcut
+  hyprvoice keybinds --signal                     # Toggle with SIGUSR1, no socket client
+  hyprvoice keybinds >> ~/.config/hypr/hyprland.conf`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			resp, err := bus.SendCommand('c')
+			cfg, err := config.Load()
 			if err != nil {
-				return fmt.Errorf("failed to cancel operation: %w", err)
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			pidPath := ""
+			if useSignal {
+				if pidPath, err = bus.PidPath(); err != nil {
+					return fmt.Errorf("failed to locate the daemon PID file: %w", err)
+				}
 			}
-			fmt.Print(resp)
+			fmt.Print(hyprlandBinds(cfg, mod, key, pidPath))
 			return nil
 		},
 	}
-}
 
-func configureCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "configure",
-		Short: "Interactive configuration setup",
-		Long: `Interactive configuration wizard for hyprvoice.
-This will guide you through setting up:
-- Transcription provider (OpenAI or Groq)
-- API keys and model selection
-- Audio and text injection preferences
-- Notification settings`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return runInteractiveConfig()
-		},
-	}
+	cmd.Flags().StringVar(&mod, "mod", "SUPER", "Modifier keys for the binds")
+	cmd.Flags().StringVar(&key, "key", "R", "Key that toggles recording")
+	cmd.Flags().BoolVar(&useSignal, "signal", false, "Toggle by sending SIGUSR1 to the daemon instead of hyprvoice toggle")
+	return cmd
 }
 
-func modeCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "mode [raw|llm]",
-		Short: "Get or set processing mode",
-		Long: `Get or set the post-transcription processing mode.
+// hyprlandBinds renders bind lines for the features enabled in cfg. A non-empty pidPath
+// makes the toggle bind signal the daemon whose PID it holds instead of running a client.
+func hyprlandBinds(cfg *config.Config, mod, key, pidPath string) string {
+	var b strings.Builder
+	bind := func(extra, key, command, comment string) {
+		mods := mod
+		if extra != "" && !slices.Contains(strings.Fields(mod), extra) {
+			mods += " " + extra
+		}
+		fmt.Fprintf(&b, "bind = %s, %s, exec, %s", mods, key, command)
+		if comment != "" {
+			fmt.Fprintf(&b, "  # %s", comment)
+		}
+		b.WriteString("\n")
+	}
 
-With no arguments: displays the current processing mode.
-With an argument: sets the processing mode for the current session.
+	b.WriteString("# Hyprvoice (generated by hyprvoice keybinds)\n")
+	toggle := "hyprvoice toggle"
+	if pidPath != "" {
+		toggle = fmt.Sprintf("kill -USR1 \"$(cat '%s')\"", pidPath)
+	}
+	bind("", key, toggle, "Start/stop recording")
+	bind("SHIFT", "C", "hyprvoice cancel", "Cancel the current operation")
 
-Modes:
-  raw  - Direct transcription output (default)
-  llm  - Clean up transcription using AI (removes filler words, fixes punctuation)
+	if cfg.Behavior.ConfirmBeforeInject {
+		bind("SHIFT", "Y", "hyprvoice confirm", "Inject the pending transcription")
+	}
+	if cfg.Behavior.ExplicitInject {
+		bind("SHIFT", "I", "hyprvoice inject", "Type the transcription at the cursor")
+	}
+	if cfg.Behavior.ConfirmBeforeInject || cfg.Behavior.ExplicitInject {
+		bind("SHIFT", "N", "hyprvoice discard", "Drop the pending transcription")
+	}
 
-Examples:
-  hyprvoice mode        # Show current mode
-  hyprvoice mode raw    # Switch to raw mode
-  hyprvoice mode llm    # Switch to LLM cleanup mode`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) == 0 {
-				// Get current mode
-				resp, err := bus.SendModeCommand("")
-				if err != nil {
-					return fmt.Errorf("failed to get mode: %w", err)
-				}
-				fmt.Print(resp)
-				return nil
+	if cfg.ToLLMConfig().APIKey != "" {
+		b.WriteString("\n# Processing mode and LLM level\n")
+		bind("ALT", "R", "hyprvoice mode raw", "Raw transcription")
+		bind("ALT", "L", "hyprvoice mode llm", "LLM cleanup")
+		for i, level := range llm.Levels {
+			if level == "custom" && cfg.LLM.CustomPrompt == "" {
+				continue
 			}
+			bind("ALT", strconv.Itoa(i+1), "hyprvoice level "+level, "")
+		}
+	}
 
-			// Set mode
-			mode := args[0]
-			if mode != "raw" && mode != "llm" {
-				return fmt.Errorf("invalid mode: %s (must be 'raw' or 'llm')", mode)
-			}
+	if len(cfg.Transcription.Prompts) > 0 {
+		languages := make([]string, 0, len(cfg.Transcription.Prompts))
+		for language := range cfg.Transcription.Prompts {
+			languages = append(languages, language)
+		}
+		sort.Strings(languages)
+		languages = append([]string{"auto"}, languages...)
 
-			resp, err := bus.SendModeCommand(mode)
-			if err != nil {
-				return fmt.Errorf("failed to set mode: %w", err)
+		b.WriteString("\n# Transcription language\n")
+		for i, language := range languages {
+			if i > 9 {
+				break // Number keys run out
 			}
-			fmt.Print(resp)
-			return nil
-		},
+			bind("CTRL", strconv.Itoa(i), "hyprvoice lang "+language, "")
+		}
 	}
+	return b.String()
 }
 
 func showCmd() *cobra.Command {
@@ -202,36 +1142,100 @@ func showCmd() *cobra.Command {
 			fmt.Printf("Config file: %s\n\n", configPath)
 
 			fmt.Println("[recording]")
+			fmt.Printf("  backend            = %s\n", getRecordingBackend(cfg))
 			fmt.Printf("  sample_rate        = %d\n", cfg.Recording.SampleRate)
 			fmt.Printf("  channels           = %d\n", cfg.Recording.Channels)
 			fmt.Printf("  format             = %s\n", cfg.Recording.Format)
 			fmt.Printf("  buffer_size        = %d\n", cfg.Recording.BufferSize)
 			fmt.Printf("  device             = %s\n", cfg.Recording.Device)
+			fmt.Printf("  device_fallback    = %v\n", cfg.Recording.DeviceFallback)
 			fmt.Printf("  channel_buffer_size = %d\n", cfg.Recording.ChannelBufferSize)
 			fmt.Printf("  timeout            = %s\n", cfg.Recording.Timeout)
+			fmt.Printf("  start_delay        = %s\n", cfg.Recording.StartDelay)
+			fmt.Printf("  trim_silence       = %v\n", cfg.Recording.TrimSilence)
+			fmt.Printf("  timeout_warning    = %.2f\n", cfg.Recording.TimeoutWarning)
 			fmt.Println()
 
 			fmt.Println("[transcription]")
 			fmt.Printf("  provider           = %s\n", cfg.Transcription.Provider)
+			fmt.Printf("  task               = %s\n", getTranscriptionTask(cfg))
 			fmt.Printf("  api_key            = %s\n", maskAPIKey(cfg.Transcription.APIKey))
+			if cfg.Transcription.APIKeyRef != "" {
+				fmt.Printf("  api_key_ref        = %s\n", cfg.Transcription.APIKeyRef)
+			}
 			fmt.Printf("  language           = %s\n", cfg.Transcription.Language)
 			fmt.Printf("  model              = %s\n", cfg.Transcription.Model)
+			if cfg.Transcription.Provider == "azure-openai" {
+				fmt.Printf("  endpoint           = %s\n", cfg.Transcription.Endpoint)
+				fmt.Printf("  api_version        = %s\n", cfg.Transcription.APIVersion)
+			}
+			fmt.Printf("  realtime           = %v\n", cfg.Transcription.Realtime)
+			fmt.Printf("  start              = %s\n", getTranscriptionStart(cfg))
+			fmt.Printf("  finalize_timeout   = %s\n", getFinalizeTimeout(cfg))
+			fmt.Printf("  hallucination_phrases = %v\n", getHallucinationPhrases(cfg))
+			fmt.Printf("  repetition_filter  = %v (max %d)\n", cfg.Transcription.RepetitionFilter, getMaxRepetitions(cfg))
+			fmt.Printf("  response_format    = %s\n", getResponseFormat(cfg))
+			fmt.Printf("  allow_unknown_model = %v\n", cfg.Transcription.AllowUnknownModel)
+			fmt.Printf("  prompt             = %s\n", cfg.Transcription.Prompt)
+			for _, language := range sortedKeys(cfg.Transcription.Prompts) {
+				fmt.Printf("  prompts.%-10s = %s\n", language, cfg.Transcription.Prompts[language])
+			}
 			fmt.Println()
 
 			fmt.Println("[injection]")
 			fmt.Printf("  backends           = %v\n", cfg.Injection.Backends)
+			fmt.Printf("  strategy           = %s\n", getInjectionStrategy(cfg))
+			fmt.Printf("  retries_per_backend = %d\n", getRetriesPerBackend(cfg))
+			fmt.Printf("  humanize           = %v (%s-%s)\n", cfg.Injection.Humanize, cfg.Injection.HumanizeMinDelay, cfg.Injection.HumanizeMaxDelay)
 			fmt.Printf("  ydotool_timeout    = %s\n", cfg.Injection.YdotoolTimeout)
 			fmt.Printf("  wtype_timeout      = %s\n", cfg.Injection.WtypeTimeout)
 			fmt.Printf("  clipboard_timeout  = %s\n", cfg.Injection.ClipboardTimeout)
+			fmt.Printf("  timeout_per_char   = %s\n", cfg.Injection.TimeoutPerChar)
+			fmt.Printf("  max_timeout        = %s\n", getMaxTimeout(cfg))
+			fmt.Printf("  focus_window       = %v\n", cfg.Injection.FocusWindowEnabled())
+			fmt.Printf("  focus_before_type  = %v\n", cfg.Injection.FocusBeforeType)
+			fmt.Printf("  compositor         = %s\n", getCompositor(cfg))
+			if cfg.Injection.FocusCommand != "" {
+				fmt.Printf("  focus_command      = %s\n", cfg.Injection.FocusCommand)
+			}
+			fmt.Printf("  pre_inject_delay   = %s\n", cfg.Injection.PreInjectDelay)
+			fmt.Printf("  keyboard_layout    = %s\n", getKeyboardLayout(cfg))
+			fmt.Printf("  window_retries     = %d (%v apart)\n", cfg.Injection.WindowRetries, getWindowRetryDelay(cfg))
+			fmt.Printf("  deny_classes       = %v\n", cfg.Injection.DenyClasses)
+			fmt.Printf("  clipboard_selection = %s\n", getClipboardSelection(cfg))
+			fmt.Printf("  clipboard_mime     = %s\n", getClipboardMIME(cfg))
+			fmt.Printf("  pre_keys           = %v\n", cfg.Injection.PreKeys)
+			fmt.Printf("  post_keys          = %v\n", cfg.Injection.PostKeys)
+			fmt.Printf("  prefix             = %q\n", cfg.Injection.Prefix)
+			fmt.Printf("  suffix             = %q\n", cfg.Injection.Suffix)
+			fmt.Printf("  verify_clipboard   = %v\n", cfg.Injection.VerifyClipboard)
+			fmt.Printf("  file_path          = %s\n", cfg.Injection.FilePath)
+			fmt.Printf("  file_mode          = %s\n", getFileMode(cfg))
 			fmt.Println()
 
 			fmt.Println("[notifications]")
 			fmt.Printf("  enabled            = %v\n", cfg.Notifications.Enabled)
 			fmt.Printf("  type               = %s\n", cfg.Notifications.Type)
+			fmt.Printf("  error_urgency      = %s\n", getErrorUrgency(cfg))
+			fmt.Printf("  error_timeout      = %s\n", cfg.Notifications.ErrorTimeout)
+			fmt.Printf("  info_urgency       = %s\n", getInfoUrgency(cfg))
+			fmt.Printf("  info_timeout       = %s\n", cfg.Notifications.InfoTimeout)
+			fmt.Printf("  update_in_place    = %v\n", cfg.Notifications.UpdateInPlace)
 			fmt.Println()
 
 			fmt.Println("[processing]")
 			fmt.Printf("  mode               = %s\n", getProcessingMode(cfg))
+			fmt.Printf("  continue_sentence  = %v\n", cfg.Processing.ContinueSentence)
+			fmt.Printf("  pipeline           = [%s]\n", strings.Join(getProcessingPipeline(cfg), ", "))
+			for _, from := range sortedKeys(cfg.Processing.Replacements) {
+				fmt.Printf("  replacements.%s = %s\n", from, cfg.Processing.Replacements[from])
+			}
+			for _, trigger := range sortedKeys(cfg.Processing.Snippets) {
+				fmt.Printf("  snippets.%s = %s\n", trigger, truncateString(cfg.Processing.Snippets[trigger], 50))
+			}
+			if cfg.Processing.Command != "" {
+				fmt.Printf("  command            = %s (timeout %v)\n", cfg.Processing.Command, getCommandTimeout(cfg))
+			}
 			fmt.Println()
 
 			if cfg.Processing.Mode == "llm" {
@@ -239,19 +1243,46 @@ func showCmd() *cobra.Command {
 				fmt.Printf("  provider           = %s\n", getLLMProvider(cfg))
 				fmt.Printf("  api_key            = %s\n", maskAPIKey(cfg.LLM.APIKey))
 				fmt.Printf("  model              = %s\n", getLLMModel(cfg))
+				if cfg.LLM.Provider == "azure-openai" {
+					fmt.Printf("  endpoint           = %s\n", cfg.LLM.Endpoint)
+					fmt.Printf("  api_version        = %s\n", cfg.LLM.APIVersion)
+				}
 				fmt.Printf("  level              = %s\n", getLLMLevel(cfg))
 				if cfg.LLM.Level == "custom" {
 					fmt.Printf("  custom_prompt      = %s\n", truncateString(cfg.LLM.CustomPrompt, 50))
 				}
+				for _, level := range sortedKeys(cfg.LLM.Models) {
+					fmt.Printf("  models.%-11s = %s\n", level, cfg.LLM.Models[level])
+				}
+				fmt.Printf("  allow_unknown_model = %v\n", cfg.LLM.AllowUnknownModel)
+				fmt.Printf("  min_output_ratio   = %.2f\n", cfg.LLM.MinOutputRatio)
+				fmt.Printf("  fallback_to_raw    = %v\n", cfg.LLM.FallbackToRaw)
+				fmt.Printf("  strip_formatting   = %v\n", cfg.LLM.StripFormatting)
 				fmt.Println()
 			}
 
+			fmt.Println("[behavior]")
+			fmt.Printf("  confirm_before_inject = %v\n", cfg.Behavior.ConfirmBeforeInject)
+			fmt.Printf("  confirm_except_classes = %v\n", cfg.Behavior.ConfirmExceptClasses)
+			fmt.Printf("  confirm_timeout    = %s\n", getConfirmTimeout(cfg))
+			fmt.Printf("  explicit_inject    = %v\n", cfg.Behavior.ExplicitInject)
+			fmt.Printf("  state_file         = %s\n", cfg.Behavior.StateFile)
+			fmt.Printf("  toggle_during_injection = %s\n", getToggleDuringInjection(cfg))
+			fmt.Printf("  on_focus_change    = %s\n", getOnFocusChange(cfg))
+			fmt.Printf("  max_command_length = %d\n", getMaxCommandLength(cfg))
+			fmt.Printf("  rapid_mode         = %v\n", cfg.Behavior.RapidMode)
+			fmt.Printf("  failsafe_file      = %s\n", cfg.Behavior.FailsafeFile)
+			fmt.Printf("  toggle_debounce    = %s\n", cfg.Behavior.ToggleDebounce)
+			fmt.Printf("  autostart_recording = %v\n", cfg.Behavior.AutostartRecording)
+			fmt.Printf("  merge_window       = %s\n", cfg.Behavior.MergeWindow)
+			fmt.Println()
+
 			return nil
 		},
 	}
 }
 
-func runInteractiveConfig() error {
+func runInteractiveConfig(dryRun bool) error {
 	fmt.Println("🎤 Hyprvoice Configuration Wizard")
 	fmt.Println("==================================")
 	fmt.Println()
@@ -268,12 +1299,15 @@ func runInteractiveConfig() error {
 	fmt.Println("📝 Transcription Configuration")
 	fmt.Println("------------------------------")
 
+	// Map legacy provider names so the prompts below show provider + task
+	cfg.Transcription.Provider, cfg.Transcription.Task = transcriber.NormalizeProvider(cfg.Transcription.Provider, cfg.Transcription.Task)
+
 	// Provider selection
 	for {
 		fmt.Println("Select transcription provider:")
-		fmt.Println("  1. openai            - OpenAI Whisper API (cloud-based)")
-		fmt.Println("  2. groq-transcription - Groq Whisper API (fast transcription)")
-		fmt.Println("  3. groq-translation   - Groq Whisper API (translate to English)")
+		fmt.Println("  1. openai - OpenAI Whisper API (cloud-based, transcription and translation)")
+		fmt.Println("  2. groq   - Groq Whisper API (fast transcription and translation)")
+		fmt.Println("  3. azure-openai - Whisper or GPT-4o transcribe deployment on Azure OpenAI")
 		fmt.Printf("Provider [1-3] (current: %s): ", cfg.Transcription.Provider)
 		if !scanner.Scan() {
 			break
@@ -283,14 +1317,14 @@ func runInteractiveConfig() error {
 			break // keep current
 		}
 		switch input {
-		case "1":
+		case "1", "openai":
 			cfg.Transcription.Provider = "openai"
-		case "2":
-			cfg.Transcription.Provider = "groq-transcription"
-		case "3":
-			cfg.Transcription.Provider = "groq-translation"
-		case "openai", "groq-transcription", "groq-translation":
-			cfg.Transcription.Provider = input
+		case "2", "groq":
+			cfg.Transcription.Provider = "groq"
+		case "3", "azure-openai":
+			cfg.Transcription.Provider = "azure-openai"
+		case "groq-transcription", "groq-translation":
+			cfg.Transcription.Provider, cfg.Transcription.Task = transcriber.NormalizeProvider(input, "")
 		default:
 			fmt.Println("❌ Error: invalid provider. Please enter 1, 2, 3 or provider name.")
 			fmt.Println()
@@ -299,10 +1333,57 @@ func runInteractiveConfig() error {
 		break
 	}
 
-	// Model selection based on provider
-	switch cfg.Transcription.Provider {
-	case "openai":
-		fmt.Println("\nOpenAI Model:")
+	// Task selection
+	for {
+		fmt.Println("\nTask:")
+		fmt.Println("  1. transcribe - Text in the spoken language")
+		fmt.Println("  2. translate  - Translate speech to English text")
+		fmt.Printf("Task [1-2] (current: %s): ", cfg.Transcription.Task)
+		if !scanner.Scan() {
+			break
+		}
+		input := strings.TrimSpace(scanner.Text())
+		switch input {
+		case "1", transcriber.TaskTranscribe:
+			cfg.Transcription.Task = transcriber.TaskTranscribe
+		case "2", transcriber.TaskTranslate:
+			cfg.Transcription.Task = transcriber.TaskTranslate
+		case "":
+			// keep current
+		default:
+			fmt.Println("❌ Error: please enter 1, 2, transcribe, or translate.")
+			continue
+		}
+		break
+	}
+
+	// Model selection based on provider and task
+	switch {
+	case cfg.Transcription.Provider == "azure-openai":
+		fmt.Printf("\nAzure OpenAI endpoint (current: %s, leave empty to use AZURE_OPENAI_ENDPOINT env var): ", cfg.Transcription.Endpoint)
+		if scanner.Scan() {
+			if input := strings.TrimSpace(scanner.Text()); input != "" {
+				cfg.Transcription.Endpoint = input
+			}
+		}
+		for {
+			fmt.Printf("Deployment name (current: %s): ", cfg.Transcription.Model)
+			if !scanner.Scan() {
+				break
+			}
+			if input := strings.TrimSpace(scanner.Text()); input != "" {
+				cfg.Transcription.Model = input
+			}
+			if cfg.Transcription.Model != "" {
+				break
+			}
+			fmt.Println("❌ Error: the deployment name is required.")
+		}
+	case cfg.Transcription.Provider == "openai" && cfg.Transcription.Task == transcriber.TaskTranslate:
+		fmt.Println("\nOpenAI Translation Model: whisper-1 (the only model supported for translation)")
+		cfg.Transcription.Model = "whisper-1"
+	case cfg.Transcription.Provider == "openai":
+		fmt.Println("\nOpenAI Model: whisper-1, gpt-4o-transcribe (most accurate), or gpt-4o-mini-transcribe")
 		fmt.Printf("Model (current: %s): ", cfg.Transcription.Model)
 		if scanner.Scan() {
 			input := strings.TrimSpace(scanner.Text())
@@ -312,7 +1393,7 @@ func runInteractiveConfig() error {
 				cfg.Transcription.Model = "whisper-1"
 			}
 		}
-	case "groq-transcription":
+	case cfg.Transcription.Task == transcriber.TaskTranscribe:
 		for {
 			fmt.Println("\nGroq Transcription Model:")
 			fmt.Println("  1. whisper-large-v3       - Standard model")
@@ -330,7 +1411,7 @@ func runInteractiveConfig() error {
 			case "whisper-large-v3", "whisper-large-v3-turbo":
 				cfg.Transcription.Model = input
 			case "":
-				if cfg.Transcription.Model == "" {
+				if cfg.Transcription.Model != "whisper-large-v3" && cfg.Transcription.Model != "whisper-large-v3-turbo" {
 					cfg.Transcription.Model = "whisper-large-v3-turbo"
 				}
 			default:
@@ -339,7 +1420,7 @@ func runInteractiveConfig() error {
 			}
 			break
 		}
-	case "groq-translation":
+	default:
 		for {
 			fmt.Println("\nGroq Translation Model:")
 			fmt.Println("  Note: Translation only supports whisper-large-v3 (turbo not available)")
@@ -358,9 +1439,12 @@ func runInteractiveConfig() error {
 
 	// API Key (provider-aware)
 	var envVarName string
-	if cfg.Transcription.Provider == "openai" {
+	switch cfg.Transcription.Provider {
+	case "openai":
 		envVarName = "OPENAI_API_KEY"
-	} else {
+	case "azure-openai":
+		envVarName = "AZURE_OPENAI_API_KEY"
+	default:
 		envVarName = "GROQ_API_KEY"
 	}
 	fmt.Printf("\nAPI Key (current: %s, leave empty to use %s env var): ", maskAPIKey(cfg.Transcription.APIKey), envVarName)
@@ -372,7 +1456,7 @@ func runInteractiveConfig() error {
 	}
 
 	// Language
-	if cfg.Transcription.Provider == "groq-translation" {
+	if cfg.Transcription.Task == transcriber.TaskTranslate {
 		fmt.Printf("\nSource language hint (empty for auto-detect, current: %s): ", cfg.Transcription.Language)
 		fmt.Println("\n  Note: Translation always outputs English. Language hints at source audio language.")
 	} else {
@@ -393,6 +1477,8 @@ func runInteractiveConfig() error {
 		fmt.Println("  - ydotool:   Best for Chromium/Electron apps (requires ydotoold daemon)")
 		fmt.Println("  - wtype:     Native Wayland typing (may fail on some Chromium apps)")
 		fmt.Println("  - clipboard: Copies to clipboard only (most reliable, needs manual paste)")
+		fmt.Println("  - clipboard-copy: Copies and never focuses or pastes (transcribe-and-copy)")
+		fmt.Println("  - file:      Writes to a file an editor plugin watches (no keystrokes)")
 		fmt.Println()
 		fmt.Println("Recommended: ydotool,wtype,clipboard (full fallback chain)")
 		fmt.Println()
@@ -409,14 +1495,14 @@ func runInteractiveConfig() error {
 		invalidBackends := make([]string, 0)
 		for _, b := range backends {
 			b = strings.TrimSpace(b)
-			if b == "ydotool" || b == "wtype" || b == "clipboard" {
+			if b == "ydotool" || b == "wtype" || b == "clipboard" || b == injection.BackendClipboardCopy || b == "file" {
 				validBackends = append(validBackends, b)
 			} else if b != "" {
 				invalidBackends = append(invalidBackends, b)
 			}
 		}
 		if len(invalidBackends) > 0 {
-			fmt.Printf("❌ Error: invalid backend(s): %s. Valid: ydotool, wtype, clipboard.\n", strings.Join(invalidBackends, ", "))
+			fmt.Printf("❌ Error: invalid backend(s): %s. Valid: ydotool, wtype, clipboard, clipboard-copy, file.\n", strings.Join(invalidBackends, ", "))
 			fmt.Println()
 			continue
 		}
@@ -439,6 +1525,27 @@ func runInteractiveConfig() error {
 		}
 	}
 
+	// The file backend needs somewhere to write
+	for _, b := range cfg.Injection.Backends {
+		if b != "file" {
+			continue
+		}
+		for {
+			fmt.Printf("File for the file backend (current: %s): ", cfg.Injection.FilePath)
+			if !scanner.Scan() {
+				break
+			}
+			if input := strings.TrimSpace(scanner.Text()); input != "" {
+				cfg.Injection.FilePath = input
+			}
+			if cfg.Injection.FilePath != "" {
+				break
+			}
+			fmt.Println("❌ Error: the file backend needs a file path.")
+		}
+		break
+	}
+
 	fmt.Println()
 
 	// Configure notifications
@@ -611,6 +1718,13 @@ func runInteractiveConfig() error {
 		return err
 	}
 
+	if dryRun {
+		fmt.Println("📄 Resulting configuration (dry run, not saved):")
+		fmt.Println()
+		fmt.Print(renderConfig(cfg))
+		return nil
+	}
+
 	// Save configuration
 	fmt.Println("💾 Saving configuration...")
 	if err := saveConfig(cfg); err != nil {
@@ -657,10 +1771,10 @@ func runInteractiveConfig() error {
 	return nil
 }
 
-func formatBackends(backends []string) string {
-	quoted := make([]string, len(backends))
-	for i, b := range backends {
-		quoted[i] = fmt.Sprintf(`"%s"`, b)
+func formatStringList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf(`"%s"`, escapeTomlString(v))
 	}
 	return strings.Join(quoted, ", ")
 }
@@ -687,68 +1801,171 @@ func saveConfig(cfg *config.Config) error {
 	}
 	defer file.Close()
 
-	configContent := fmt.Sprintf(`# Hyprvoice Configuration
+	if _, err := file.WriteString(renderConfig(cfg)); err != nil {
+		return fmt.Errorf("failed to write config content: %w", err)
+	}
+
+	return nil
+}
+
+// renderConfig formats cfg as the commented config.toml that saveConfig writes
+func renderConfig(cfg *config.Config) string {
+	return fmt.Sprintf(`# Hyprvoice Configuration
 # This file is automatically generated with defaults.
 # Edit values as needed - changes are applied immediately without daemon restart.
 
 # Audio Recording Configuration
 [recording]
+  backend = "%s"         # Audio capture: "pipewire" (pw-record), "pulse" (parec), or "auto" (PipeWire, falling back to PulseAudio)
   sample_rate = %d          # Audio sample rate in Hz (16000 recommended for speech)
   channels = %d                 # Number of audio channels (1 = mono, 2 = stereo)
-  format = "%s"               # Audio format (s16 = 16-bit signed integers)
+  format = "%s"               # Capture format: s16 (default), s24, s32 or f32; others are converted to s16
   buffer_size = %d           # Internal buffer size in bytes (larger = less CPU, more latency)
-  device = "%s"                  # PipeWire audio device (empty = use default microphone)
+  device = "%s"                  # Audio device / source name (empty = use default microphone)
+  device_fallback = [%s]         # Devices tried in order, first one plugged in wins, e.g. ["alsa_input.usb-headset", "alsa_input.pci-builtin"] (falls back to device)
   channel_buffer_size = %d     # Audio frame buffer size (frames to buffer)
   timeout = "%s"               # Maximum recording duration (e.g., "30s", "2m", "5m")
+  start_delay = "%s"           # Discard audio captured right after toggling (e.g., "200ms" to drop keybind clicks)
+  trim_silence = %v         # Cut leading and trailing silence before upload (faster, fewer hallucinations on quiet starts)
+  timeout_warning = %.2f        # Notify once this fraction of timeout has passed, e.g. 0.9 = 30s left of 5m (0 = no warning)
 
 # Speech Transcription Configuration
 [transcription]
-  provider = "%s"          # Transcription service: "openai", "groq-transcription", or "groq-translation"
+  provider = "%s"          # Transcription service: "openai", "groq", or "azure-openai"
+  task = "%s"          # "transcribe" (keep spoken language) or "translate" (output English)
   api_key = "%s"                 # API key (or set OPENAI_API_KEY/GROQ_API_KEY environment variable)
+  api_key_ref = "%s"             # Read the key from the system keyring instead, e.g. "keyring:hyprvoice/openai"
   language = "%s"                # Language code (empty for auto-detect, "en", "it", "es", "fr", etc.)
-  model = "%s"          # Model: OpenAI="whisper-1", Groq="whisper-large-v3" or "whisper-large-v3-turbo"
+  model = "%s"          # Model: OpenAI="whisper-1", "gpt-4o-transcribe" or "gpt-4o-mini-transcribe", Groq="whisper-large-v3" or "whisper-large-v3-turbo" (empty = provider default); for azure-openai, the deployment name
+  endpoint = "%s"                # azure-openai only: resource URL, e.g. "https://myresource.openai.azure.com" (or AZURE_OPENAI_ENDPOINT)
+  api_version = "%s"             # azure-openai only: api-version query parameter (empty = 2024-06-01)
+  realtime = %v             # OpenAI transcribe only: stream audio while recording over the realtime websocket (batch upload if it fails)
+  start = "%s"          # When the transcriber opens: "on_record" (while recording) or "on_inject" (only once recording stops)
+  finalize_timeout = "%s"      # Time for the upload, LLM cleanup and injection once recording stops, separate from recording.timeout
+  hallucination_phrases = [%s]  # Results matching these exactly are discarded ([] to disable)
+  repetition_filter = %v     # Collapse phrases Whisper loops on ("thank you thank you thank you ...") to one copy
+  max_repetitions = %d          # Back-to-back copies of a phrase kept as real speech before it counts as a loop
+  response_format = "%s"     # "hyprvoice transcribe" output: "text", "json", "verbose_json", "srt", "vtt" (srt/vtt OpenAI only; dictation always uses text)
+  allow_unknown_model = %v  # Accept models outside the provider's known list (custom or newer endpoints)
+  prompt = "%s"                  # Whisper prompt to bias vocabulary and spelling, e.g. "Kubernetes, Hyprland, gRPC"
+
+# Per-language prompts (the entry for the current language replaces prompt)
+[transcription.prompts]
+%s
 
 # Text Injection Configuration
 [injection]
   backends = [%s]  # Ordered fallback chain (tries each until one succeeds)
+  strategy = "%s"      # "sequential" (fallback chain) or "parallel" (prepare all at once, one writes at a time, first success wins)
+  retries_per_backend = %d      # Attempts per backend before falling through to the next (1 = no retry)
+  humanize = %v             # Type character by character with random delays (ydotool/wtype) for apps that reject bulk input
+  humanize_min_delay = "%s"  # Shortest delay between humanized keystrokes
+  humanize_max_delay = "%s" # Longest delay between humanized keystrokes
   ydotool_timeout = "%s"       # Timeout for ydotool commands
   wtype_timeout = "%s"         # Timeout for wtype commands
   clipboard_timeout = "%s"     # Timeout for clipboard operations
+  timeout_per_char = "%s"    # Extra ydotool/wtype time per character so long dictations don't time out ("0s" = fixed timeouts)
+  max_timeout = "%s"           # Upper bound for the scaled ydotool/wtype timeout
+  focus_window = %v          # Refocus the window that was active when recording started (false = type/copy into the current window)
+  focus_before_type = %v    # Also refocus it before ydotool/wtype type (by default only clipboard refocuses before pasting)
+  compositor = "%s"          # Window tracking: "auto" (detect), "hyprland" (hyprctl), "sway" (swaymsg), or "generic" (no window tracking)
+  focus_command = "%s"           # Shell command that focuses the recorded window, {addr} is its quoted address, e.g. "hyprctl dispatch focuswindow address:{addr}" (empty = built-in)
+  pre_inject_delay = "%s"      # Pause after focusing and before typing or pasting, for apps that drop the first characters ("0s" = none)
+  keyboard_layout = "%s"       # Your XKB layout, e.g. "de" or "fr"; ydotool passes text it would mistype on to the next backend
+  window_retries = %d           # Ask the compositor again this many times when the active window comes back empty right after the keybind
+  window_retry_delay = "%s"  # Pause between those attempts
+  deny_classes = [%s]            # Never inject into these window classes, e.g. ["KeePassXC", "Bitwarden"] (Hyprland/Sway only)
+  clipboard_selection = "%s"  # Clipboard backend target: "clipboard" (Ctrl+V), "primary" (middle-click), or "both"
+  clipboard_mime = "%s"  # MIME type the text is copied as (wl-copy --type)
+  pre_keys = [%s]                # Key combos pressed before the text, e.g. ["i"] (vim insert mode) or ["ctrl+l"]
+  post_keys = [%s]               # Key combos pressed after the text, e.g. ["Escape"] or ["Return"]
+  prefix = "%s"                  # Text added before every dictation, e.g. "- " for bullet notes
+  suffix = "%s"                  # Text added after every dictation, e.g. "\n"
+  verify_clipboard = %v     # Read the clipboard back with wl-paste before pasting; skip the paste if it doesn't match
+  file_path = "%s"               # File the "file" backend writes to for editors that watch it, e.g. "$XDG_RUNTIME_DIR/hyprvoice.txt"
+  file_mode = "%s"         # File backend: "append" (one dictation per line) or "overwrite" (latest dictation only)
 
 # Desktop Notification Configuration
 [notifications]
   enabled = %v               # Enable desktop notifications
   type = "%s"             # Notification type ("desktop", "log", "none")
+  error_urgency = "%s"   # Urgency for errors: "low", "normal", or "critical" (critical usually stays until dismissed)
+  error_timeout = "%s"         # How long errors stay up, e.g. "10s" (0 = notification server default)
+  info_urgency = "%s"      # Urgency for status messages like "Recording Started"
+  info_timeout = "%s"          # How long status messages stay up, e.g. "2s" (0 = notification server default)
+  update_in_place = %v       # Update one notification per dictation instead of stacking (needs libnotify 0.7.9+)
 
 # Post-Transcription Processing Configuration
 [processing]
   mode = "%s"                 # Processing mode: "raw" (direct transcription) or "llm" (AI cleanup)
+  continue_sentence = %v    # Lowercase the first word so dictation fits mid-sentence (toggle with "hyprvoice continue")
+  pipeline = [%s]  # Post-processing stages in order; "llm" only runs when mode = "llm"
+  command = "%s"                 # Pipe the text through this shell command ("command" stage), e.g. "~/bin/fix-terms"
+  command_timeout = "%s"       # Give up on the command after this long and keep its input
+
+# Whole-word, case-insensitive substitutions applied by the "replace" stage
+[processing.replacements]
+%s
+
+# Dictations that consist only of a trigger phrase are replaced by its expansion ("snippets" stage)
+[processing.snippets]
+%s
 
 # LLM Configuration (used when processing.mode = "llm")
 [llm]
-  provider = "%s"          # LLM provider (currently only "openai" supported)
-  api_key = "%s"                 # API key (or use OPENAI_API_KEY environment variable)
+  provider = "%s"          # LLM provider: "openai" or "azure-openai"
+  api_key = "%s"                 # API key (or use OPENAI_API_KEY, or AZURE_OPENAI_API_KEY for azure-openai)
+  endpoint = "%s"                # azure-openai only: resource URL (or AZURE_OPENAI_ENDPOINT); model names the deployment
+  api_version = "%s"             # azure-openai only: api-version query parameter (empty = 2024-06-01)
   model = "%s"        # Model to use for text cleanup
   level = "%s"           # Intervention level: "minimal", "moderate", "thorough", or "custom"
   custom_prompt = "%s"           # Custom system prompt (used when level = "custom")
+  allow_unknown_model = %v  # Accept models outside the known OpenAI chat model list
+  min_output_ratio = %.2f       # Keep the raw text when the LLM output is shorter than this fraction of it, e.g. 0.5 (0 = only reject empty output)
+  fallback_to_raw = %v       # Inject the raw text when the LLM fails (false = copy it to the clipboard and notify instead)
+  strip_formatting = %v     # Remove "Here is the cleaned text:" preambles, wrapping quotes, bullets and markdown the LLM adds anyway
+
+# Per-level model overrides (levels not listed use llm.model)
+[llm.models]
+%s
+
+# Behavior Configuration
+[behavior]
+  confirm_before_inject = %v  # Show the transcription and wait for "hyprvoice confirm" or "hyprvoice discard" before injecting
+  confirm_except_classes = [%s]    # Window classes that skip the confirmation, e.g. ["obsidian"] (Hyprland/Sway only)
+  confirm_timeout = "%s"         # How long a transcription waits for "hyprvoice confirm" or "hyprvoice inject" before it is copied to the clipboard and failsafe_file
+  explicit_inject = %v        # Toggle stops and transcribes, then the text waits for "hyprvoice inject" (or "hyprvoice discard")
+  state_file = "%s"                # Write the current status (idle, recording, ...) to this file for status bars (empty = disabled)
+  toggle_during_injection = "%s"  # Toggle while typing: "abort", "ignore" (let it finish), or "abort-and-clear-clipboard"
+  on_focus_change = "%s"     # When focus leaves the window dictation started in: "ignore", "warn", or "cancel" (Hyprland/Sway only)
+  max_command_length = %d     # Longest control socket command in bytes; longer lines get "ERR code=too_long"
+  rapid_mode = %v             # Keep recording after each inject; toggle injects a snippet, "hyprvoice cancel" ends the session
+  failsafe_file = "%s"             # Append transcriptions that could not be injected to this file (empty = disabled)
+  toggle_debounce = "%s"         # Ignore a toggle this soon after the previous one, e.g. "200ms" for a bouncy keybind ("0s" = off)
+  autostart_recording = %v    # Start recording as soon as the daemon launches, as if toggled (pair with rapid_mode for always-on dictation)
+  merge_window = "%s"            # Dictating into the same window this soon after the last injection continues that text instead of starting anew, e.g. "10s" ("0s" = off)
 
 # Backend explanations:
 # - "ydotool": Uses ydotool (requires ydotoold daemon running). Most compatible with Chromium/Electron apps.
 # - "wtype": Uses wtype for Wayland. May have issues with some Chromium-based apps.
 # - "clipboard": Copies text to clipboard only (most reliable, but requires manual paste).
+# - "clipboard-copy": Copies text to clipboard and never focuses or pastes; you paste by hand.
+# - "file": Writes text to file_path so an editor plugin can insert it (no keystrokes).
 #
 # The backends are tried in order. First successful one wins.
 #
 # Provider explanations:
 # - "openai": OpenAI Whisper API (cloud-based, requires OPENAI_API_KEY)
-# - "groq-transcription": Groq Whisper API for transcription (fast, requires GROQ_API_KEY)
-#     Models: whisper-large-v3 or whisper-large-v3-turbo
-# - "groq-translation": Groq Whisper API for translation to English (always outputs English text)
-#     Models: whisper-large-v3 only (turbo not supported for translation)
+#     task = "translate" requires model "whisper-1"
+# - "groq": Groq Whisper API (fast, requires GROQ_API_KEY)
+#     task = "transcribe": whisper-large-v3 or whisper-large-v3-turbo
+#     task = "translate":  whisper-large-v3 only (turbo not supported for translation)
+# - "azure-openai": Azure OpenAI resource (requires AZURE_OPENAI_API_KEY and endpoint or AZURE_OPENAI_ENDPOINT)
+#     model is the deployment name; api_version defaults to 2024-06-01
 #
 # Language codes: Use empty string ("") for automatic detection, or specific codes like:
 # "en" (English), "it" (Italian), "es" (Spanish), "fr" (French), "de" (German), etc.
-# For groq-translation, the language field hints at the source audio language for better accuracy.
+# For task = "translate", the language field hints at the source audio language for better accuracy.
 #
 # Processing mode explanations:
 # - "raw": Direct transcription output without any post-processing
@@ -760,36 +1977,140 @@ func saveConfig(cfg *config.Config) error {
 # - "thorough": Full rewrite - restructure for clarity and flow while preserving meaning
 # - "custom":   Use your own system prompt defined in custom_prompt
 `,
+		getRecordingBackend(cfg),
 		cfg.Recording.SampleRate,
 		cfg.Recording.Channels,
 		cfg.Recording.Format,
 		cfg.Recording.BufferSize,
 		cfg.Recording.Device,
+		formatStringList(cfg.Recording.DeviceFallback),
 		cfg.Recording.ChannelBufferSize,
 		cfg.Recording.Timeout,
+		cfg.Recording.StartDelay,
+		cfg.Recording.TrimSilence,
+		cfg.Recording.TimeoutWarning,
 		cfg.Transcription.Provider,
+		getTranscriptionTask(cfg),
 		cfg.Transcription.APIKey,
+		escapeTomlString(cfg.Transcription.APIKeyRef),
 		cfg.Transcription.Language,
 		cfg.Transcription.Model,
-		formatBackends(cfg.Injection.Backends),
+		escapeTomlString(cfg.Transcription.Endpoint),
+		escapeTomlString(cfg.Transcription.APIVersion),
+		cfg.Transcription.Realtime,
+		getTranscriptionStart(cfg),
+		getFinalizeTimeout(cfg),
+		formatStringList(cfg.Transcription.HallucinationPhrases),
+		cfg.Transcription.RepetitionFilter,
+		getMaxRepetitions(cfg),
+		getResponseFormat(cfg),
+		cfg.Transcription.AllowUnknownModel,
+		escapeTomlString(cfg.Transcription.Prompt),
+		formatTranscriptionPrompts(cfg.Transcription.Prompts),
+		formatStringList(cfg.Injection.Backends),
+		getInjectionStrategy(cfg),
+		getRetriesPerBackend(cfg),
+		cfg.Injection.Humanize,
+		cfg.Injection.HumanizeMinDelay,
+		cfg.Injection.HumanizeMaxDelay,
 		cfg.Injection.YdotoolTimeout,
 		cfg.Injection.WtypeTimeout,
 		cfg.Injection.ClipboardTimeout,
+		cfg.Injection.TimeoutPerChar,
+		getMaxTimeout(cfg),
+		cfg.Injection.FocusWindowEnabled(),
+		cfg.Injection.FocusBeforeType,
+		getCompositor(cfg),
+		escapeTomlString(cfg.Injection.FocusCommand),
+		cfg.Injection.PreInjectDelay,
+		escapeTomlString(getKeyboardLayout(cfg)),
+		cfg.Injection.WindowRetries,
+		getWindowRetryDelay(cfg),
+		formatStringList(cfg.Injection.DenyClasses),
+		getClipboardSelection(cfg),
+		escapeTomlString(getClipboardMIME(cfg)),
+		formatStringList(cfg.Injection.PreKeys),
+		formatStringList(cfg.Injection.PostKeys),
+		escapeTomlString(cfg.Injection.Prefix),
+		escapeTomlString(cfg.Injection.Suffix),
+		cfg.Injection.VerifyClipboard,
+		escapeTomlString(cfg.Injection.FilePath),
+		getFileMode(cfg),
 		cfg.Notifications.Enabled,
 		cfg.Notifications.Type,
+		getErrorUrgency(cfg),
+		cfg.Notifications.ErrorTimeout,
+		getInfoUrgency(cfg),
+		cfg.Notifications.InfoTimeout,
+		cfg.Notifications.UpdateInPlace,
 		getProcessingMode(cfg),
+		cfg.Processing.ContinueSentence,
+		formatStringList(getProcessingPipeline(cfg)),
+		escapeTomlString(cfg.Processing.Command),
+		getCommandTimeout(cfg),
+		formatStringTable(cfg.Processing.Replacements, `"hyper voice" = "Hyprvoice"`),
+		formatStringTable(cfg.Processing.Snippets, `"my email" = "jane@example.com"`),
 		getLLMProvider(cfg),
 		cfg.LLM.APIKey,
+		escapeTomlString(cfg.LLM.Endpoint),
+		escapeTomlString(cfg.LLM.APIVersion),
 		getLLMModel(cfg),
 		getLLMLevel(cfg),
 		escapeTomlString(cfg.LLM.CustomPrompt),
+		cfg.LLM.AllowUnknownModel,
+		cfg.LLM.MinOutputRatio,
+		cfg.LLM.FallbackToRaw,
+		cfg.LLM.StripFormatting,
+		formatLLMModels(cfg.LLM.Models),
+		cfg.Behavior.ConfirmBeforeInject,
+		formatStringList(cfg.Behavior.ConfirmExceptClasses),
+		getConfirmTimeout(cfg),
+		cfg.Behavior.ExplicitInject,
+		escapeTomlString(cfg.Behavior.StateFile),
+		getToggleDuringInjection(cfg),
+		getOnFocusChange(cfg),
+		getMaxCommandLength(cfg),
+		cfg.Behavior.RapidMode,
+		escapeTomlString(cfg.Behavior.FailsafeFile),
+		cfg.Behavior.ToggleDebounce,
+		cfg.Behavior.AutostartRecording,
+		cfg.Behavior.MergeWindow,
 	)
+}
 
-	if _, err := file.WriteString(configContent); err != nil {
-		return fmt.Errorf("failed to write config content: %w", err)
+func getFinalizeTimeout(cfg *config.Config) time.Duration {
+	if cfg.Transcription.FinalizeTimeout == 0 {
+		return config.DefaultFinalizeTimeout
 	}
+	return cfg.Transcription.FinalizeTimeout
+}
 
-	return nil
+func getConfirmTimeout(cfg *config.Config) time.Duration {
+	if cfg.Behavior.ConfirmTimeout == 0 {
+		return config.DefaultConfirmTimeout
+	}
+	return cfg.Behavior.ConfirmTimeout
+}
+
+func getMaxTimeout(cfg *config.Config) time.Duration {
+	if cfg.Injection.MaxTimeout == 0 {
+		return injection.DefaultMaxTimeout
+	}
+	return cfg.Injection.MaxTimeout
+}
+
+func getCommandTimeout(cfg *config.Config) time.Duration {
+	if cfg.Processing.CommandTimeout == 0 {
+		return config.DefaultCommandTimeout
+	}
+	return cfg.Processing.CommandTimeout
+}
+
+func getProcessingPipeline(cfg *config.Config) []string {
+	if cfg.Processing.Pipeline == nil {
+		return config.DefaultProcessingPipeline
+	}
+	return cfg.Processing.Pipeline
 }
 
 func getProcessingMode(cfg *config.Config) string {
@@ -799,6 +2120,182 @@ func getProcessingMode(cfg *config.Config) string {
 	return cfg.Processing.Mode
 }
 
+func getTranscriptionTask(cfg *config.Config) string {
+	_, task := transcriber.NormalizeProvider(cfg.Transcription.Provider, cfg.Transcription.Task)
+	return task
+}
+
+// formatLLMModels renders the [llm.models] table body, or commented examples when empty
+func formatLLMModels(models map[string]string) string {
+	if len(models) == 0 {
+		return "  # minimal = \"gpt-4o-mini\"\n  # thorough = \"gpt-4o\""
+	}
+	lines := make([]string, 0, len(models))
+	for _, level := range sortedKeys(models) {
+		lines = append(lines, fmt.Sprintf("  %s = \"%s\"", level, escapeTomlString(models[level])))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatTranscriptionPrompts renders the [transcription.prompts] table body, or a commented example when empty
+func formatTranscriptionPrompts(prompts map[string]string) string {
+	if len(prompts) == 0 {
+		return "  # it = \"Riunione su Kubernetes, Hyprland e gRPC.\""
+	}
+	lines := make([]string, 0, len(prompts))
+	for _, language := range sortedKeys(prompts) {
+		lines = append(lines, fmt.Sprintf("  %s = \"%s\"", language, escapeTomlString(prompts[language])))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatStringTable renders a table body with quoted keys, or a commented example when empty
+func formatStringTable(values map[string]string, example string) string {
+	if len(values) == 0 {
+		return "  # " + example
+	}
+	lines := make([]string, 0, len(values))
+	for _, key := range sortedKeys(values) {
+		lines = append(lines, fmt.Sprintf("  \"%s\" = \"%s\"", escapeTomlString(key), escapeTomlString(values[key])))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func getRecordingBackend(cfg *config.Config) string {
+	if cfg.Recording.Backend == "" {
+		return "pipewire"
+	}
+	return cfg.Recording.Backend
+}
+
+func getInjectionStrategy(cfg *config.Config) string {
+	if cfg.Injection.Strategy == "" {
+		return "sequential"
+	}
+	return cfg.Injection.Strategy
+}
+
+func getRetriesPerBackend(cfg *config.Config) int {
+	if cfg.Injection.RetriesPerBackend == 0 {
+		return 1
+	}
+	return cfg.Injection.RetriesPerBackend
+}
+
+func getCompositor(cfg *config.Config) string {
+	if cfg.Injection.Compositor == "" {
+		return "auto"
+	}
+	return cfg.Injection.Compositor
+}
+
+func getWindowRetryDelay(cfg *config.Config) time.Duration {
+	if cfg.Injection.WindowRetryDelay == 0 {
+		return injection.DefaultWindowRetryDelay
+	}
+	return cfg.Injection.WindowRetryDelay
+}
+
+func getToggleDuringInjection(cfg *config.Config) string {
+	if cfg.Behavior.ToggleDuringInjection == "" {
+		return config.ToggleInjectionAbort
+	}
+	return cfg.Behavior.ToggleDuringInjection
+}
+
+func getOnFocusChange(cfg *config.Config) string {
+	if cfg.Behavior.OnFocusChange == "" {
+		return config.FocusChangeIgnore
+	}
+	return cfg.Behavior.OnFocusChange
+}
+
+func getFileMode(cfg *config.Config) string {
+	if cfg.Injection.FileMode == "" {
+		return injection.FileModeAppend
+	}
+	return cfg.Injection.FileMode
+}
+
+func getKeyboardLayout(cfg *config.Config) string {
+	if cfg.Injection.KeyboardLayout == "" {
+		return injection.LayoutUS
+	}
+	return cfg.Injection.KeyboardLayout
+}
+
+func getClipboardMIME(cfg *config.Config) string {
+	if cfg.Injection.ClipboardMIME == "" {
+		return injection.DefaultClipboardMIME
+	}
+	return cfg.Injection.ClipboardMIME
+}
+
+func getClipboardSelection(cfg *config.Config) string {
+	if cfg.Injection.ClipboardSelection == "" {
+		return "clipboard"
+	}
+	return cfg.Injection.ClipboardSelection
+}
+
+func getErrorUrgency(cfg *config.Config) string {
+	if cfg.Notifications.ErrorUrgency == "" {
+		return "critical"
+	}
+	return cfg.Notifications.ErrorUrgency
+}
+
+func getInfoUrgency(cfg *config.Config) string {
+	if cfg.Notifications.InfoUrgency == "" {
+		return "normal"
+	}
+	return cfg.Notifications.InfoUrgency
+}
+
+func getMaxRepetitions(cfg *config.Config) int {
+	if cfg.Transcription.MaxRepetitions == 0 {
+		return transcriber.DefaultMaxRepetitions
+	}
+	return cfg.Transcription.MaxRepetitions
+}
+
+func getMaxCommandLength(cfg *config.Config) int {
+	if cfg.Behavior.MaxCommandLength == 0 {
+		return bus.DefaultMaxCommandLength
+	}
+	return cfg.Behavior.MaxCommandLength
+}
+
+func getTranscriptionStart(cfg *config.Config) string {
+	if cfg.Transcription.Start == "" {
+		return config.TranscriptionStartOnRecord
+	}
+	return cfg.Transcription.Start
+}
+
+func getResponseFormat(cfg *config.Config) string {
+	if cfg.Transcription.ResponseFormat == "" {
+		return transcriber.ResponseFormatText
+	}
+	return cfg.Transcription.ResponseFormat
+}
+
+func getHallucinationPhrases(cfg *config.Config) []string {
+	if cfg.Transcription.HallucinationPhrases == nil {
+		return transcriber.DefaultHallucinationPhrases
+	}
+	return cfg.Transcription.HallucinationPhrases
+}
+
 func getLLMProvider(cfg *config.Config) string {
 	if cfg.LLM.Provider == "" {
 		return "openai"
@@ -8,14 +8,140 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
+	"unicode/utf8"
 )
 
 const (
 	SockName = "control.sock"
 	PidName  = "hyprvoice.pid"
+
+	// ProtoVersion is bumped whenever a socket command or response format changes
+	ProtoVersion = 8
+
+	// DefaultMaxCommandLength is the longest command line the daemon reads before replying "ERR code=too_long"
+	DefaultMaxCommandLength = 64 * 1024
+
+	// DefaultDialAttempts is how often clients try to connect before giving up, which rides
+	// out a daemon restart (about 350ms of backoff in total)
+	DefaultDialAttempts = 4
 )
 
+// dialRetryDelay is the pause after the first failed connection attempt, doubled after each further one
+const dialRetryDelay = 50 * time.Millisecond
+
+// Info is the daemon snapshot returned by the 'i' command as "INFO <json>\n"
+type Info struct {
+	Status        string `json:"status"`
+	Proto         int    `json:"proto"`
+	Mode          string `json:"mode"`
+	Continue      string `json:"continue"`
+	Task          string `json:"task"`
+	Provider      string `json:"provider"` // Transcription provider
+	Model         string `json:"model"`    // Transcription model
+	Language      string `json:"language"` // Empty means auto-detect
+	Level         string `json:"level"`    // LLM intervention level
+	UptimeSeconds int64  `json:"uptime_seconds"`
+
+	LastTranscriptionMs int64  `json:"last_transcription_ms"` // Duration of the last transcription (0 = none yet)
+	LastError           string `json:"last_error"`            // Error kind of the latest dictation ("" = none)
+}
+
+// Response kinds. Getters reply with the name of the value asked for, e.g. "STATUS status=idle".
+const (
+	KindOK       = "OK"
+	KindErr      = "ERR"
+	KindStatus   = "STATUS"
+	KindInfo     = "INFO"
+	KindMode     = "MODE"
+	KindContinue = "CONTINUE"
+	KindLanguage = "LANGUAGE"
+	KindLevel    = "LEVEL"
+	KindProvider = "PROVIDER"
+	KindModel    = "MODEL"
+)
+
+// Response is one daemon reply: a kind followed by space-separated key=value pairs.
+// INFO replies carry a JSON object instead of pairs.
+type Response struct {
+	Kind   string
+	Fields map[string]string
+}
+
+// FormatResponse renders a reply line from a kind and alternating keys and values.
+// Values that are empty or contain spaces, quotes, '=' or control characters are Go-quoted.
+func FormatResponse(kind string, pairs ...string) string {
+	var b strings.Builder
+	b.WriteString(kind)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		b.WriteString(" " + pairs[i] + "=" + quoteValue(pairs[i+1]))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func quoteValue(value string) string {
+	if value == "" || !utf8.ValidString(value) || strings.ContainsAny(value, " \"=\\") ||
+		strings.IndexFunc(value, func(r rune) bool { return !strconv.IsPrint(r) }) >= 0 {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// ParseResponse splits a reply line into its kind and fields. For INFO replies the JSON
+// payload is returned under the "json" field.
+func ParseResponse(line string) (Response, error) {
+	line = strings.TrimSuffix(line, "\n")
+	kind, rest, _ := strings.Cut(line, " ")
+	if kind == "" {
+		return Response{}, fmt.Errorf("empty response")
+	}
+	resp := Response{Kind: kind, Fields: map[string]string{}}
+	if kind == KindInfo {
+		resp.Fields["json"] = rest
+		return resp, nil
+	}
+
+	for rest != "" {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok || key == "" || strings.Contains(key, " ") {
+			return Response{}, fmt.Errorf("malformed field in response %q", line)
+		}
+		if !strings.HasPrefix(value, "\"") {
+			resp.Fields[key], rest, _ = strings.Cut(value, " ")
+			continue
+		}
+
+		quoted, err := strconv.QuotedPrefix(value)
+		if err != nil {
+			return Response{}, fmt.Errorf("malformed quoted value in response %q: %w", line, err)
+		}
+		resp.Fields[key], _ = strconv.Unquote(quoted)
+		rest = value[len(quoted):]
+		if rest != "" && !strings.HasPrefix(rest, " ") {
+			return Response{}, fmt.Errorf("malformed field in response %q", line)
+		}
+		rest = strings.TrimPrefix(rest, " ")
+	}
+	return resp, nil
+}
+
+// Err returns an error describing an ERR reply, or nil for any other kind
+func (r Response) Err() error {
+	if r.Kind != KindErr {
+		return nil
+	}
+	msg := r.Fields["code"]
+	for _, key := range []string{"value", "command", "message"} {
+		if v, ok := r.Fields[key]; ok {
+			msg += fmt.Sprintf(" (%s: %s)", key, v)
+		}
+	}
+	return fmt.Errorf("daemon error: %s", msg)
+}
+
 type pidManager struct {
 	path string
 }
@@ -50,8 +176,15 @@ func (pm *pidManager) checkExisting() error {
 	}
 
 	if pm.isProcessAlive(pid) {
-		log.Printf("Process %d is alive, daemon already running", pid)
-		return fmt.Errorf("daemon already running with PID %d", pid)
+		// The PID may have been reused by an unrelated process after a crash,
+		// so only a daemon answering on the socket counts as running
+		if daemonResponds() {
+			log.Printf("Process %d is alive and answering on the socket, daemon already running", pid)
+			return fmt.Errorf("daemon already running with PID %d", pid)
+		}
+		log.Printf("Process %d is alive but no daemon answers on the socket, removing stale PID file", pid)
+		pm.removeStaleFile()
+		return nil
 	}
 
 	log.Printf("Process %d not alive, removing stale PID file", pid)
@@ -101,6 +234,41 @@ func (pm *pidManager) isProcessAlive(pid int) bool {
 	return true
 }
 
+// probeTimeout bounds the socket probe in checkExisting
+const probeTimeout = time.Second
+
+// daemonResponds dials the control socket and asks for the status, reporting whether a
+// daemon answered with a well-formed reply
+func daemonResponds() bool {
+	sm, err := newSocketManager()
+	if err != nil {
+		return false
+	}
+	conn, err := net.DialTimeout("unix", sm.path, probeTimeout)
+	if err != nil {
+		log.Printf("Socket probe: %v", err)
+		return false
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(probeTimeout))
+
+	if _, err := conn.Write([]byte("s\n")); err != nil {
+		log.Printf("Socket probe: %v", err)
+		return false
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		log.Printf("Socket probe: %v", err)
+		return false
+	}
+	resp, err := ParseResponse(line)
+	if err != nil {
+		log.Printf("Socket probe: %v", err)
+		return false
+	}
+	return resp.Kind == KindStatus
+}
+
 func (pm *pidManager) removeStaleFile() {
 	if err := os.Remove(pm.path); err != nil && !os.IsNotExist(err) {
 		log.Printf("Warning: failed to remove stale PID file: %v", err)
@@ -162,7 +330,24 @@ func SockPath() (string, error) {
 	return getSockPath()
 }
 
-func Listen() (net.Listener, error) {
+// PidPath returns the file the running daemon writes its PID to
+func PidPath() (string, error) {
+	return getPidPath()
+}
+
+// Transport carries the control protocol between the daemon and its clients
+type Transport interface {
+	Listen() (net.Listener, error)
+	Dial() (net.Conn, error)
+}
+
+// transport is the unix socket unless a test swapped it with SetTransport
+var transport Transport = socketTransport{}
+
+// socketTransport is the unix socket in the user cache directory
+type socketTransport struct{}
+
+func (socketTransport) Listen() (net.Listener, error) {
 	sm, err := newSocketManager()
 	if err != nil {
 		return nil, err
@@ -170,7 +355,7 @@ func Listen() (net.Listener, error) {
 	return sm.listen()
 }
 
-func Dial() (net.Conn, error) {
+func (socketTransport) Dial() (net.Conn, error) {
 	sm, err := newSocketManager()
 	if err != nil {
 		return nil, err
@@ -178,6 +363,40 @@ func Dial() (net.Conn, error) {
 	return sm.dial()
 }
 
+// SetTransport replaces the transport behind Listen and Dial so tests can run a daemon
+// and its clients in one process. It returns a function restoring the previous one.
+func SetTransport(t Transport) (restore func()) {
+	previous := transport
+	transport = t
+	return func() { transport = previous }
+}
+
+// dialAttempts is how often Dial tries to connect, see SetDialAttempts
+var dialAttempts = DefaultDialAttempts
+
+// SetDialAttempts sets how often Dial tries to connect before giving up (values below 1 mean once)
+func SetDialAttempts(attempts int) {
+	dialAttempts = max(attempts, 1)
+}
+
+func Listen() (net.Listener, error) {
+	return transport.Listen()
+}
+
+// Dial connects to the daemon, retrying with backoff so a command sent while the daemon
+// restarts still reaches it. A daemon that stays down fails after dialAttempts tries.
+func Dial() (net.Conn, error) {
+	delay := dialRetryDelay
+	for attempt := 1; ; attempt++ {
+		conn, err := transport.Dial()
+		if err == nil || attempt >= dialAttempts {
+			return conn, err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
 func CheckExistingDaemon() error {
 	pm, err := newPidManager()
 	if err != nil {
@@ -222,27 +441,60 @@ func SendCommand(cmd byte) (string, error) {
 	return resp, nil
 }
 
+// SendToggleCommand toggles recording, labeling a dictation it starts with tag ("" = untagged)
+func SendToggleCommand(tag string) (string, error) {
+	return sendArgCommand('t', tag)
+}
+
 // SendModeCommand sends a mode command to the daemon
 // If mode is empty, it requests the current mode
 // If mode is non-empty, it sets the mode to the specified value
 func SendModeCommand(mode string) (string, error) {
+	return sendArgCommand('m', mode)
+}
+
+// SendContinueCommand gets ("") or sets ("on"/"off") continue-sentence mode
+func SendContinueCommand(value string) (string, error) {
+	return sendArgCommand('u', value)
+}
+
+// SendLanguageCommand gets ("") or sets ("auto" or a language code) the transcription language
+func SendLanguageCommand(language string) (string, error) {
+	return sendArgCommand('l', language)
+}
+
+// SendLevelCommand gets ("") or sets ("minimal", "moderate", "thorough", "custom") the LLM level
+func SendLevelCommand(level string) (string, error) {
+	return sendArgCommand('v', level)
+}
+
+// SendProviderCommand gets ("") or sets ("openai", "groq", "azure-openai" or "reset") the transcription provider
+func SendProviderCommand(provider string) (string, error) {
+	return sendArgCommand('p', provider)
+}
+
+// SendModelCommand gets ("") or sets (a model name or "reset") the transcription model
+func SendModelCommand(model string) (string, error) {
+	return sendArgCommand('o', model)
+}
+
+// sendArgCommand sends a command that optionally carries an argument.
+// Format: "m\n" for get, "m:llm\n" for set
+func sendArgCommand(cmd byte, arg string) (string, error) {
 	c, err := Dial()
 	if err != nil {
 		return "", fmt.Errorf("failed to connect to daemon: %w", err)
 	}
 	defer c.Close()
 
-	// Format: "m\n" for get, "m:llm\n" for set
-	var cmdStr string
-	if mode == "" {
-		cmdStr = "m\n"
-	} else {
-		cmdStr = fmt.Sprintf("m:%s\n", mode)
+	cmdStr := string(cmd) + "\n"
+	if arg != "" {
+		cmdStr = fmt.Sprintf("%c:%s\n", cmd, arg)
 	}
 
 	_, err = c.Write([]byte(cmdStr))
 	if err != nil {
-		return "", fmt.Errorf("failed to send mode command: %w", err)
+		return "", fmt.Errorf("failed to send %c command: %w", cmd, err)
 	}
 
 	resp, err := bufio.NewReader(c).ReadString('\n')
@@ -1,8 +1,13 @@
 package bus
 
 import (
+	"bufio"
+	"errors"
+	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -63,6 +68,61 @@ func TestPidManager_CheckExisting(t *testing.T) {
 			t.Errorf("checkExisting() error = %v, want no error", err)
 		}
 	})
+
+	// A live PID only counts when a daemon answers on the socket
+	t.Run("live PID without a daemon", func(t *testing.T) {
+		tempDir := t.TempDir()
+		t.Setenv("XDG_CACHE_HOME", tempDir)
+		pidPath := filepath.Join(tempDir, "hyprvoice", PidName)
+		os.MkdirAll(filepath.Dir(pidPath), 0755)
+		if err := os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+			t.Fatalf("Failed to create PID file: %v", err)
+		}
+
+		pm, err := newPidManager()
+		if err != nil {
+			t.Fatalf("Failed to create PID manager: %v", err)
+		}
+		if err := pm.checkExisting(); err != nil {
+			t.Errorf("checkExisting() error = %v, want no error for a reused PID", err)
+		}
+		if _, err := os.Stat(pidPath); !os.IsNotExist(err) {
+			t.Error("checkExisting() should remove the stale PID file")
+		}
+	})
+
+	t.Run("live PID with a daemon", func(t *testing.T) {
+		tempDir := t.TempDir()
+		t.Setenv("XDG_CACHE_HOME", tempDir)
+		pidPath := filepath.Join(tempDir, "hyprvoice", PidName)
+		os.MkdirAll(filepath.Dir(pidPath), 0755)
+		if err := os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+			t.Fatalf("Failed to create PID file: %v", err)
+		}
+
+		ln, err := Listen()
+		if err != nil {
+			t.Fatalf("Listen() error = %v", err)
+		}
+		defer ln.Close()
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			bufio.NewReader(conn).ReadString('\n')
+			conn.Write([]byte(FormatResponse(KindStatus, "status", "idle")))
+		}()
+
+		pm, err := newPidManager()
+		if err != nil {
+			t.Fatalf("Failed to create PID manager: %v", err)
+		}
+		if err := pm.checkExisting(); err == nil {
+			t.Error("checkExisting() should report the running daemon")
+		}
+	})
 }
 
 func TestPidManager_Create(t *testing.T) {
@@ -260,13 +320,13 @@ func TestSendCommand(t *testing.T) {
 			var response string
 			switch buf[0] {
 			case 't':
-				response = "OK toggled\n"
+				response = "OK action=toggled\n"
 			case 's':
 				response = "STATUS status=idle\n"
 			case 'q':
-				response = "OK quitting\n"
+				response = "OK action=quitting\n"
 			default:
-				response = "ERR unknown\n"
+				response = "ERR code=unknown_command\n"
 			}
 
 			conn.Write([]byte(response))
@@ -284,8 +344,8 @@ func TestSendCommand(t *testing.T) {
 		return
 	}
 
-	if response != "OK toggled\n" {
-		t.Errorf("SendCommand() = %q, want %q", response, "OK toggled\n")
+	if response != "OK action=toggled\n" {
+		t.Errorf("SendCommand() = %q, want %q", response, "OK action=toggled\n")
 	}
 }
 
@@ -439,3 +499,126 @@ func TestDial(t *testing.T) {
 		t.Errorf("Dial() returned nil connection")
 	}
 }
+
+// flakyTransport refuses its first dials, like a daemon that is still restarting
+type flakyTransport struct {
+	failures int
+	dials    int
+}
+
+func (f *flakyTransport) Listen() (net.Listener, error) { return nil, errors.New("not supported") }
+func (f *flakyTransport) Dial() (net.Conn, error) {
+	f.dials++
+	if f.dials <= f.failures {
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	server.Close()
+	return client, nil
+}
+
+func TestDial_Retries(t *testing.T) {
+	flaky := &flakyTransport{failures: 2}
+	defer SetTransport(flaky)()
+
+	conn, err := Dial()
+	if err != nil {
+		t.Fatalf("Dial() error = %v, want success after the daemon came back", err)
+	}
+	conn.Close()
+	if flaky.dials != 3 {
+		t.Errorf("dials = %d, want 3", flaky.dials)
+	}
+
+	// A daemon that stays down fails after the configured attempts
+	down := &flakyTransport{failures: 100}
+	SetTransport(down)
+	defer SetDialAttempts(DefaultDialAttempts)
+	SetDialAttempts(2)
+	if _, err := Dial(); err == nil {
+		t.Fatal("Dial() should fail when the daemon stays down")
+	}
+	if down.dials != 2 {
+		t.Errorf("dials = %d, want 2", down.dials)
+	}
+}
+
+func TestFormatResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  string
+		pairs []string
+		want  string
+	}{
+		{"bare kind", KindOK, nil, "OK\n"},
+		{"plain value", KindOK, []string{"action", "toggled"}, "OK action=toggled\n"},
+		{"multiple pairs", KindErr, []string{"code", "invalid_mode", "value", "shout"}, "ERR code=invalid_mode value=shout\n"},
+		{"space quoted", KindErr, []string{"code", "read_error", "message", "broken pipe"}, `ERR code=read_error message="broken pipe"` + "\n"},
+		{"empty quoted", KindErr, []string{"code", "unknown_command", "command", ""}, `ERR code=unknown_command command=""` + "\n"},
+		{"equals quoted", KindErr, []string{"code", "invalid_mode", "value", "a=b"}, `ERR code=invalid_mode value="a=b"` + "\n"},
+		{"newline quoted", KindErr, []string{"code", "unknown_command", "command", "\n"}, `ERR code=unknown_command command="\n"` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatResponse(tt.kind, tt.pairs...); got != tt.want {
+				t.Errorf("FormatResponse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Response
+		wantErr bool
+	}{
+		{"bare kind", "OK\n", Response{Kind: KindOK, Fields: map[string]string{}}, false},
+		{"plain pairs", "ERR code=invalid_mode value=shout\n", Response{Kind: KindErr, Fields: map[string]string{"code": "invalid_mode", "value": "shout"}}, false},
+		{"quoted value", `ERR code=read_error message="broken pipe" extra=1` + "\n", Response{Kind: KindErr, Fields: map[string]string{"code": "read_error", "message": "broken pipe", "extra": "1"}}, false},
+		{"info json", `INFO {"status":"idle","proto":2}` + "\n", Response{Kind: KindInfo, Fields: map[string]string{"json": `{"status":"idle","proto":2}`}}, false},
+		{"empty", "\n", Response{}, true},
+		{"missing equals", "OK toggled\n", Response{}, true},
+		{"unterminated quote", `ERR message="broken` + "\n", Response{}, true},
+		{"junk after quote", `ERR message="a"b` + "\n", Response{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResponse(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseResponse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseResponse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponse_RoundTrip(t *testing.T) {
+	values := []string{"toggled", "", "two words", "a=b", `say "hi"`, `back\slash`, "tab\there", "\x00"}
+	for _, v := range values {
+		resp, err := ParseResponse(FormatResponse(KindErr, "code", "x", "value", v))
+		if err != nil {
+			t.Fatalf("ParseResponse(FormatResponse(%q)) error = %v", v, err)
+		}
+		if resp.Fields["value"] != v {
+			t.Errorf("round trip of %q = %q", v, resp.Fields["value"])
+		}
+	}
+}
+
+func TestResponse_Err(t *testing.T) {
+	if err := (Response{Kind: KindOK, Fields: map[string]string{"action": "toggled"}}).Err(); err != nil {
+		t.Errorf("Err() for OK = %v, want nil", err)
+	}
+
+	resp := Response{Kind: KindErr, Fields: map[string]string{"code": "invalid_mode", "value": "shout"}}
+	want := "daemon error: invalid_mode (value: shout)"
+	if err := resp.Err(); err == nil || err.Error() != want {
+		t.Errorf("Err() = %v, want %q", err, want)
+	}
+}
@@ -3,11 +3,16 @@ package config
 import (
 	"fmt"
 	"log"
+	"mime"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/leonardotrapani/hyprvoice/internal/bus"
 	"github.com/leonardotrapani/hyprvoice/internal/injection"
 	"github.com/leonardotrapani/hyprvoice/internal/llm"
 	"github.com/leonardotrapani/hyprvoice/internal/recording"
@@ -21,88 +26,334 @@ type Config struct {
 	Notifications NotificationsConfig `toml:"notifications"`
 	Processing    ProcessingConfig    `toml:"processing"`
 	LLM           LLMConfig           `toml:"llm"`
+	Behavior      BehaviorConfig      `toml:"behavior"`
 }
 
 type ProcessingConfig struct {
-	Mode string `toml:"mode"` // "raw" (default) or "llm"
+	Mode             string            `toml:"mode"`              // "raw" (default) or "llm"
+	ContinueSentence bool              `toml:"continue_sentence"` // Lowercase the first word for mid-sentence dictation
+	Pipeline         []string          `toml:"pipeline"`          // Post-processing stages in order (default ["replace", "llm", "snippets"])
+	Replacements     map[string]string `toml:"replacements"`      // Whole-word, case-insensitive substitutions for the "replace" stage
+	Snippets         map[string]string `toml:"snippets"`          // Trigger phrases expanded by the "snippets" stage
+	Command          string            `toml:"command"`           // Shell command for the "command" stage: text on stdin, result on stdout
+	CommandTimeout   time.Duration     `toml:"command_timeout"`   // Kill the command after this long and keep its input (default 5s)
 }
 
+// Post-processing stage names accepted in processing.pipeline
+const (
+	StageReplace  = "replace"
+	StageLLM      = "llm"
+	StageSnippets = "snippets"
+	StageCommand  = "command"
+)
+
+// DefaultProcessingPipeline is used when processing.pipeline is not set
+var DefaultProcessingPipeline = []string{StageReplace, StageLLM, StageSnippets, StageCommand}
+
+// DefaultCommandTimeout bounds processing.command when command_timeout is not set
+const DefaultCommandTimeout = 5 * time.Second
+
+// DefaultFinalizeTimeout bounds the upload, processing and injection after a recording
+// stops when transcription.finalize_timeout is not set
+const DefaultFinalizeTimeout = 2 * time.Minute
+
+// DefaultConfirmTimeout is how long a transcription waits for confirm, inject or discard when
+// behavior.confirm_timeout is not set
+const DefaultConfirmTimeout = 2 * time.Minute
+
+// DefaultTimeoutWarning is the fraction of recording.timeout after which a warning is shown
+const DefaultTimeoutWarning = 0.9
+
 type LLMConfig struct {
-	Provider     string `toml:"provider"`      // "openai"
-	APIKey       string `toml:"api_key"`
-	Model        string `toml:"model"`         // Default: "gpt-4o-mini"
-	Level        string `toml:"level"`         // "minimal", "moderate", "thorough", or "custom"
-	CustomPrompt string `toml:"custom_prompt"` // Used when level is "custom"
+	Provider          string            `toml:"provider"` // "openai" or "azure-openai"
+	APIKey            string            `toml:"api_key"`
+	Endpoint          string            `toml:"endpoint"`            // azure-openai: resource URL (or AZURE_OPENAI_ENDPOINT)
+	APIVersion        string            `toml:"api_version"`         // azure-openai: api-version (default 2024-06-01)
+	Model             string            `toml:"model"`               // Default: "gpt-4o-mini"
+	Models            map[string]string `toml:"models"`              // Optional per-level model overrides, falling back to model
+	Level             string            `toml:"level"`               // "minimal", "moderate", "thorough", or "custom"
+	CustomPrompt      string            `toml:"custom_prompt"`       // Used when level is "custom"
+	AllowUnknownModel bool              `toml:"allow_unknown_model"` // Skip the known-model check (custom endpoints, new models)
+	MinOutputRatio    float64           `toml:"min_output_ratio"`    // Keep the input when the output is shorter than this fraction of it (0 = off)
+	FallbackToRaw     bool              `toml:"fallback_to_raw"`     // Inject the raw text when the LLM fails; false copies it to the clipboard instead (default true)
+	StripFormatting   bool              `toml:"strip_formatting"`    // Remove preambles, quotes, bullets and markdown the LLM adds to its answer
+}
+
+type BehaviorConfig struct {
+	ConfirmBeforeInject   bool          `toml:"confirm_before_inject"`   // Wait for confirm/discard before injecting
+	StateFile             string        `toml:"state_file"`              // File the daemon keeps updated with the current status (empty = disabled)
+	ToggleDuringInjection string        `toml:"toggle_during_injection"` // "abort" (default), "ignore", or "abort-and-clear-clipboard"
+	OnFocusChange         string        `toml:"on_focus_change"`         // "ignore" (default), "warn", or "cancel" when focus leaves the captured window
+	MaxCommandLength      int           `toml:"max_command_length"`      // Longest socket command line in bytes (default 65536)
+	RapidMode             bool          `toml:"rapid_mode"`              // Keep recording after each inject until an explicit stop
+	FailsafeFile          string        `toml:"failsafe_file"`           // File transcriptions are appended to when injection fails (empty = disabled)
+	ToggleDebounce        time.Duration `toml:"toggle_debounce"`         // Ignore a toggle this soon after the previous one (default 0 = off)
+	AutostartRecording    bool          `toml:"autostart_recording"`     // Start recording as soon as the daemon is up
+	MergeWindow           time.Duration `toml:"merge_window"`            // Continue the last injected text when dictating into the same window this soon after it (default 0 = off)
+	ConfirmExceptClasses  []string      `toml:"confirm_except_classes"`  // Window classes injected into without confirmation
+	ConfirmTimeout        time.Duration `toml:"confirm_timeout"`         // How long a transcription waits for confirm or inject before it is kept in the clipboard (default 2m)
+	ExplicitInject        bool          `toml:"explicit_inject"`         // Toggle only stops and transcribes; "hyprvoice inject" types the held text
 }
 
+// What a toggle does while text is being injected (behavior.toggle_during_injection)
+const (
+	ToggleInjectionAbort               = "abort"
+	ToggleInjectionIgnore              = "ignore"
+	ToggleInjectionAbortClearClipboard = "abort-and-clear-clipboard"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI api-version used when api_version is unset;
+// it serves both audio transcription and chat completions
+const DefaultAzureAPIVersion = "2024-06-01"
+
+// azureEndpoint returns the configured Azure OpenAI endpoint, falling back to AZURE_OPENAI_ENDPOINT
+func azureEndpoint(endpoint string) string {
+	if endpoint != "" {
+		return endpoint
+	}
+	return os.Getenv("AZURE_OPENAI_ENDPOINT")
+}
+
+// When the transcriber is opened (transcription.start)
+const (
+	TranscriptionStartOnRecord = "on_record"
+	TranscriptionStartOnInject = "on_inject"
+)
+
+// What happens when focus leaves the captured window before injection (behavior.on_focus_change)
+const (
+	FocusChangeIgnore = "ignore"
+	FocusChangeWarn   = "warn"
+	FocusChangeCancel = "cancel"
+)
+
 type RecordingConfig struct {
+	Backend           string        `toml:"backend"` // "pipewire" (default), "pulse", or "auto"
 	SampleRate        int           `toml:"sample_rate"`
 	Channels          int           `toml:"channels"`
 	Format            string        `toml:"format"`
 	BufferSize        int           `toml:"buffer_size"`
 	Device            string        `toml:"device"`
+	DeviceFallback    []string      `toml:"device_fallback"` // Devices tried in order before device; the first one plugged in wins
 	ChannelBufferSize int           `toml:"channel_buffer_size"`
 	Timeout           time.Duration `toml:"timeout"`
+	StartDelay        time.Duration `toml:"start_delay"`     // Discard audio for this long after recording starts (default 0)
+	TrimSilence       bool          `toml:"trim_silence"`    // Cut leading and trailing silence before upload
+	TimeoutWarning    float64       `toml:"timeout_warning"` // Notify after this fraction of timeout (default 0.9, 0 = off)
 }
 
 type TranscriptionConfig struct {
-	Provider string `toml:"provider"`
-	APIKey   string `toml:"api_key"`
-	Language string `toml:"language"`
-	Model    string `toml:"model"`
+	Provider             string            `toml:"provider"` // "openai", "groq", or "azure-openai"
+	Task                 string            `toml:"task"`     // "transcribe" (default) or "translate"
+	APIKey               string            `toml:"api_key"`
+	APIKeyRef            string            `toml:"api_key_ref"` // Read the key from the keyring instead, e.g. "keyring:hyprvoice/openai"
+	Language             string            `toml:"language"`
+	Model                string            `toml:"model"`                 // For azure-openai, the deployment name
+	Endpoint             string            `toml:"endpoint"`              // azure-openai: resource URL (or AZURE_OPENAI_ENDPOINT)
+	APIVersion           string            `toml:"api_version"`           // azure-openai: api-version (default 2024-06-01)
+	Realtime             bool              `toml:"realtime"`              // OpenAI only: stream audio over the realtime websocket while recording
+	Start                string            `toml:"start"`                 // When the transcriber opens: "on_record" (default) or "on_inject"
+	FinalizeTimeout      time.Duration     `toml:"finalize_timeout"`      // Time for the upload, processing and injection once recording stops (default 2m)
+	HallucinationPhrases []string          `toml:"hallucination_phrases"` // Results matching these are discarded
+	RepetitionFilter     bool              `toml:"repetition_filter"`     // Collapse looped phrases ("thank you thank you ...") (default true)
+	MaxRepetitions       int               `toml:"max_repetitions"`       // Back-to-back copies kept before a phrase counts as a loop (default 3)
+	ResponseFormat       string            `toml:"response_format"`       // Output of "hyprvoice transcribe": text (default), json, verbose_json, srt, vtt
+	AllowUnknownModel    bool              `toml:"allow_unknown_model"`   // Skip the per-provider model check
+	Prompt               string            `toml:"prompt"`                // Whisper prompt biasing vocabulary and spelling
+	Prompts              map[string]string `toml:"prompts"`               // Per-language prompts keyed by language code, replacing prompt
+
+	keyringKey string // Resolved from APIKeyRef at load time; never saved
 }
 
 type InjectionConfig struct {
-	Backends         []string      `toml:"backends"`
-	YdotoolTimeout   time.Duration `toml:"ydotool_timeout"`
-	WtypeTimeout     time.Duration `toml:"wtype_timeout"`
-	ClipboardTimeout time.Duration `toml:"clipboard_timeout"`
+	Backends           []string      `toml:"backends"`
+	YdotoolTimeout     time.Duration `toml:"ydotool_timeout"`
+	WtypeTimeout       time.Duration `toml:"wtype_timeout"`
+	ClipboardTimeout   time.Duration `toml:"clipboard_timeout"`
+	TimeoutPerChar     time.Duration `toml:"timeout_per_char"`    // Extra ydotool/wtype time per character (default 20ms, 0 = fixed)
+	MaxTimeout         time.Duration `toml:"max_timeout"`         // Cap on the scaled ydotool/wtype timeout (default 2m)
+	FocusWindow        *bool         `toml:"focus_window"`        // Refocus the recorded window before injecting (nil = default true)
+	FocusBeforeType    bool          `toml:"focus_before_type"`   // Also refocus it before ydotool/wtype type (default false)
+	Compositor         string        `toml:"compositor"`          // "auto" (default), "hyprland", "sway", or "generic"
+	FocusCommand       string        `toml:"focus_command"`       // Shell command that focuses {addr} instead of hyprctl/swaymsg (empty = built-in)
+	PreInjectDelay     time.Duration `toml:"pre_inject_delay"`    // Pause before typing or pasting so slow apps catch the first characters (0 = none)
+	KeyboardLayout     string        `toml:"keyboard_layout"`     // XKB layout name; ydotool skips text it would mistype on it (default "us")
+	WindowRetries      int           `toml:"window_retries"`      // Extra tries when the active window comes back empty (default 2, 0 = none)
+	WindowRetryDelay   time.Duration `toml:"window_retry_delay"`  // Pause between those tries (default 50ms)
+	Strategy           string        `toml:"strategy"`            // "sequential" (default) or "parallel"
+	RetriesPerBackend  int           `toml:"retries_per_backend"` // Attempts per backend before falling through (default 1)
+	Humanize           bool          `toml:"humanize"`            // Type character by character with random delays
+	HumanizeMinDelay   time.Duration `toml:"humanize_min_delay"`
+	HumanizeMaxDelay   time.Duration `toml:"humanize_max_delay"`
+	DenyClasses        []string      `toml:"deny_classes"`        // Window classes that never receive injected text
+	ClipboardSelection string        `toml:"clipboard_selection"` // "clipboard" (default), "primary", or "both"
+	ClipboardMIME      string        `toml:"clipboard_mime"`      // wl-copy --type (default "text/plain;charset=utf-8")
+	PreKeys            []string      `toml:"pre_keys"`            // Key combos pressed before the text (e.g. ["i"] for vim insert mode)
+	PostKeys           []string      `toml:"post_keys"`           // Key combos pressed after the text (e.g. ["Return"])
+	Prefix             string        `toml:"prefix"`              // Text added before every dictation (e.g. "- " for bullet notes)
+	Suffix             string        `toml:"suffix"`              // Text added after every dictation
+	VerifyClipboard    bool          `toml:"verify_clipboard"`    // Read the clipboard back before pasting (needs wl-paste)
+	FilePath           string        `toml:"file_path"`           // File the "file" backend writes to
+	FileMode           string        `toml:"file_mode"`           // "append" (default) or "overwrite"
+}
+
+// FocusWindowEnabled reports whether injection refocuses the recorded window, which it
+// does unless focus_window is explicitly false
+func (c InjectionConfig) FocusWindowEnabled() bool {
+	return c.FocusWindow == nil || *c.FocusWindow
 }
 
 type NotificationsConfig struct {
-	Enabled bool   `toml:"enabled"`
-	Type    string `toml:"type"` // "desktop", "log", "none"
+	Enabled       bool          `toml:"enabled"`
+	Type          string        `toml:"type"`            // "desktop", "log", "none"
+	ErrorUrgency  string        `toml:"error_urgency"`   // notify-send urgency for errors: "low", "normal", or "critical" (default)
+	ErrorTimeout  time.Duration `toml:"error_timeout"`   // How long errors stay up (0 = notification server default)
+	InfoUrgency   string        `toml:"info_urgency"`    // notify-send urgency for status messages (default "normal")
+	InfoTimeout   time.Duration `toml:"info_timeout"`    // How long status messages stay up (0 = notification server default)
+	UpdateInPlace bool          `toml:"update_in_place"` // Replace one notification per dictation instead of stacking them (default true)
 }
 
 func (c *Config) ToRecordingConfig() recording.Config {
 	return recording.Config{
+		Backend:           c.Recording.Backend,
 		SampleRate:        c.Recording.SampleRate,
 		Channels:          c.Recording.Channels,
 		Format:            c.Recording.Format,
 		BufferSize:        c.Recording.BufferSize,
 		Device:            c.Recording.Device,
+		DeviceFallback:    c.Recording.DeviceFallback,
 		ChannelBufferSize: c.Recording.ChannelBufferSize,
 		Timeout:           c.Recording.Timeout,
+		StartDelay:        c.Recording.StartDelay,
 	}
 }
 
+// effectivePrompt returns the prompt for the current language, falling back to prompt
+func (t TranscriptionConfig) effectivePrompt() string {
+	if prompt, ok := t.Prompts[t.Language]; ok && t.Language != "" {
+		return prompt
+	}
+	return t.Prompt
+}
+
 func (c *Config) ToTranscriberConfig() transcriber.Config {
+	provider, task := transcriber.NormalizeProvider(c.Transcription.Provider, c.Transcription.Task)
 	config := transcriber.Config{
-		Provider: c.Transcription.Provider,
-		APIKey:   c.Transcription.APIKey,
+		Provider: provider,
+		Task:     task,
+		APIKey:   c.Transcription.apiKey(),
 		Language: c.Transcription.Language,
 		Model:    c.Transcription.Model,
+		Prompt:   c.Transcription.effectivePrompt(),
+
+		APIVersion: c.Transcription.APIVersion,
+
+		TrimSilence: c.Recording.TrimSilence,
+		Realtime:    c.Transcription.Realtime,
+		Deferred:    c.Transcription.Start == TranscriptionStartOnInject,
+		// ResponseFormat stays empty: dictation always injects plain text
+	}
+
+	if config.Model == "" {
+		config.Model = transcriber.DefaultModel(provider, task)
 	}
 
 	// Check for API key in environment variables if not in config
-	if config.APIKey == "" {
-		switch c.Transcription.Provider {
-		case "openai":
-			config.APIKey = os.Getenv("OPENAI_API_KEY")
-		case "groq-transcription", "groq-translation":
-			config.APIKey = os.Getenv("GROQ_API_KEY")
-		}
+	if config.APIKey == "" && transcriptionKeyEnv[provider] != "" {
+		config.APIKey = os.Getenv(transcriptionKeyEnv[provider])
+	}
+	if provider == "azure-openai" {
+		config.Endpoint = azureEndpoint(c.Transcription.Endpoint)
 	}
 
 	return config
 }
 
+// transcriptionKeyEnv names the environment variable holding each provider's API key
+var transcriptionKeyEnv = map[string]string{
+	"openai":       "OPENAI_API_KEY",
+	"groq":         "GROQ_API_KEY",
+	"azure-openai": "AZURE_OPENAI_API_KEY",
+}
+
+// TranscriptionProviders lists the accepted transcription.provider values
+var TranscriptionProviders = []string{"openai", "groq", "azure-openai"}
+
+// WithTranscriptionOverride returns a copy of the config transcribing with provider and
+// model instead of the configured ones ("" keeps the configured value). The configured
+// model, key and endpoint belong to the configured provider, so another provider starts
+// from its default model and takes its key from the environment.
+func (c *Config) WithTranscriptionOverride(provider, model string) (*Config, error) {
+	cfg := *c
+	t := &cfg.Transcription
+	if provider != "" && provider != t.Provider {
+		if !slices.Contains(TranscriptionProviders, provider) {
+			return nil, fmt.Errorf("unsupported transcription provider: %s (must be %s)", provider, strings.Join(TranscriptionProviders, ", "))
+		}
+		t.Provider = provider
+		t.Model = ""
+		t.APIKey = ""
+		t.APIKeyRef = ""
+		t.keyringKey = ""
+		t.Endpoint = ""
+	}
+	if model != "" {
+		t.Model = model
+	}
+
+	if t.Provider == "azure-openai" {
+		if azureEndpoint(t.Endpoint) == "" {
+			return nil, fmt.Errorf("Azure OpenAI endpoint required: set AZURE_OPENAI_ENDPOINT")
+		}
+		if t.Model == "" {
+			return nil, fmt.Errorf("azure-openai needs a model (the deployment name)")
+		}
+		if t.APIVersion == "" {
+			t.APIVersion = DefaultAzureAPIVersion
+		}
+	}
+	if t.Model == "" {
+		t.Model = transcriber.DefaultModel(t.Provider, t.Task)
+	}
+	if err := cfg.validateTranscriptionModel(); err != nil {
+		return nil, err
+	}
+	if t.Realtime && t.Provider != "openai" {
+		return nil, fmt.Errorf("%s cannot stream while transcription.realtime is on", t.Provider)
+	}
+	if cfg.ToTranscriberConfig().APIKey == "" {
+		return nil, fmt.Errorf("%s API key required: set %s", t.Provider, transcriptionKeyEnv[t.Provider])
+	}
+	return &cfg, nil
+}
+
 func (c *Config) ToInjectionConfig() injection.Config {
 	return injection.Config{
-		Backends:         c.Injection.Backends,
-		YdotoolTimeout:   c.Injection.YdotoolTimeout,
-		WtypeTimeout:     c.Injection.WtypeTimeout,
-		ClipboardTimeout: c.Injection.ClipboardTimeout,
+		Backends:           c.Injection.Backends,
+		YdotoolTimeout:     c.Injection.YdotoolTimeout,
+		WtypeTimeout:       c.Injection.WtypeTimeout,
+		ClipboardTimeout:   c.Injection.ClipboardTimeout,
+		TimeoutPerChar:     c.Injection.TimeoutPerChar,
+		MaxTimeout:         c.Injection.MaxTimeout,
+		FocusWindow:        c.Injection.FocusWindowEnabled(),
+		FocusBeforeType:    c.Injection.FocusBeforeType,
+		Compositor:         c.Injection.Compositor,
+		FocusCommand:       c.Injection.FocusCommand,
+		PreInjectDelay:     c.Injection.PreInjectDelay,
+		KeyboardLayout:     c.Injection.KeyboardLayout,
+		Strategy:           c.Injection.Strategy,
+		RetriesPerBackend:  c.Injection.RetriesPerBackend,
+		Humanize:           c.Injection.Humanize,
+		HumanizeMinDelay:   c.Injection.HumanizeMinDelay,
+		HumanizeMaxDelay:   c.Injection.HumanizeMaxDelay,
+		DenyClasses:        c.Injection.DenyClasses,
+		ClipboardSelection: c.Injection.ClipboardSelection,
+		ClipboardMIME:      c.Injection.ClipboardMIME,
+		PreKeys:            c.Injection.PreKeys,
+		PostKeys:           c.Injection.PostKeys,
+		VerifyClipboard:    c.Injection.VerifyClipboard,
+		FilePath:           c.Injection.FilePath,
+		FileMode:           c.Injection.FileMode,
 	}
 }
 
@@ -111,12 +362,19 @@ func (c *Config) ToLLMConfig() llm.Config {
 		Provider:     c.LLM.Provider,
 		APIKey:       c.LLM.APIKey,
 		Model:        c.LLM.Model,
+		Models:       c.LLM.Models,
 		Level:        c.LLM.Level,
 		CustomPrompt: c.LLM.CustomPrompt,
+		APIVersion:   c.LLM.APIVersion,
 	}
 
 	// Check for API key in environment variable if not in config
-	if config.APIKey == "" {
+	if config.Provider == "azure-openai" {
+		config.Endpoint = azureEndpoint(c.LLM.Endpoint)
+		if config.APIKey == "" {
+			config.APIKey = os.Getenv("AZURE_OPENAI_API_KEY")
+		}
+	} else if config.APIKey == "" {
 		config.APIKey = os.Getenv("OPENAI_API_KEY")
 	}
 
@@ -145,87 +403,166 @@ func (c *Config) Validate() error {
 	if c.Recording.Format == "" {
 		return fmt.Errorf("invalid recording.format: empty")
 	}
+	if !recording.IsSupportedFormat(c.Recording.Format) {
+		return fmt.Errorf("invalid recording.format: %s (must be one of %s)", c.Recording.Format, strings.Join(recording.SupportedFormats, ", "))
+	}
 	if c.Recording.Timeout <= 0 {
 		return fmt.Errorf("invalid recording.timeout: %v", c.Recording.Timeout)
 	}
+	if c.Recording.Backend == "" {
+		c.Recording.Backend = recording.BackendPipeWire
+	}
+	validRecordingBackends := map[string]bool{recording.BackendPipeWire: true, recording.BackendPulse: true, recording.BackendAuto: true}
+	if !validRecordingBackends[c.Recording.Backend] {
+		return fmt.Errorf("invalid recording.backend: %s (must be pipewire, pulse, or auto)", c.Recording.Backend)
+	}
+	if c.Recording.StartDelay < 0 {
+		return fmt.Errorf("invalid recording.start_delay: %v", c.Recording.StartDelay)
+	}
+	if c.Recording.TimeoutWarning < 0 || c.Recording.TimeoutWarning >= 1 {
+		return fmt.Errorf("invalid recording.timeout_warning: %v (must be at least 0 and below 1)", c.Recording.TimeoutWarning)
+	}
+	for _, device := range c.Recording.DeviceFallback {
+		if strings.TrimSpace(device) == "" {
+			return fmt.Errorf("invalid recording.device_fallback: empty device name")
+		}
+	}
 
 	// Transcription
 	if c.Transcription.Provider == "" {
 		return fmt.Errorf("invalid transcription.provider: empty")
 	}
 
+	// Map legacy provider names ("groq-transcription", "groq-translation") to provider + task
+	c.migrateTranscriptionProvider()
+
+	if c.Transcription.Task != transcriber.TaskTranscribe && c.Transcription.Task != transcriber.TaskTranslate {
+		return fmt.Errorf("invalid transcription.task: %s (must be transcribe or translate)", c.Transcription.Task)
+	}
+
 	// Validate provider-specific settings
 	switch c.Transcription.Provider {
 	case "openai":
-		apiKey := c.Transcription.APIKey
+		apiKey := c.Transcription.apiKey()
 		if apiKey == "" {
 			apiKey = os.Getenv("OPENAI_API_KEY")
 		}
 		if apiKey == "" {
-			return fmt.Errorf("OpenAI API key required: not found in config (transcription.api_key) or environment variable (OPENAI_API_KEY)")
+			return fmt.Errorf("OpenAI API key required: not found in config (transcription.api_key or api_key_ref) or environment variable (OPENAI_API_KEY)")
 		}
 
-		// Validate language code if provided (empty string means auto-detect)
-		if c.Transcription.Language != "" && !isValidLanguageCode(c.Transcription.Language) {
-			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", c.Transcription.Language)
-		}
-
-	case "groq-transcription":
-		apiKey := c.Transcription.APIKey
+	case "groq":
+		apiKey := c.Transcription.apiKey()
 		if apiKey == "" {
 			apiKey = os.Getenv("GROQ_API_KEY")
 		}
 		if apiKey == "" {
-			return fmt.Errorf("Groq API key required: not found in config (transcription.api_key) or environment variable (GROQ_API_KEY)")
+			return fmt.Errorf("Groq API key required: not found in config (transcription.api_key or api_key_ref) or environment variable (GROQ_API_KEY)")
 		}
 
-		// Validate language code if provided (empty string means auto-detect)
-		if c.Transcription.Language != "" && !isValidLanguageCode(c.Transcription.Language) {
-			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", c.Transcription.Language)
-		}
-
-		// Validate Groq model
-		validGroqModels := map[string]bool{"whisper-large-v3": true, "whisper-large-v3-turbo": true}
-		if c.Transcription.Model != "" && !validGroqModels[c.Transcription.Model] {
-			return fmt.Errorf("invalid model for groq-transcription: %s (must be whisper-large-v3 or whisper-large-v3-turbo)", c.Transcription.Model)
-		}
-
-	case "groq-translation":
-		apiKey := c.Transcription.APIKey
+	case "azure-openai":
+		apiKey := c.Transcription.apiKey()
 		if apiKey == "" {
-			apiKey = os.Getenv("GROQ_API_KEY")
+			apiKey = os.Getenv("AZURE_OPENAI_API_KEY")
 		}
 		if apiKey == "" {
-			return fmt.Errorf("Groq API key required: not found in config (transcription.api_key) or environment variable (GROQ_API_KEY)")
+			return fmt.Errorf("Azure OpenAI API key required: not found in config (transcription.api_key or api_key_ref) or environment variable (AZURE_OPENAI_API_KEY)")
 		}
-
-		// For translation, language field hints at source language (output is always English)
-		if c.Transcription.Language != "" && !isValidLanguageCode(c.Transcription.Language) {
-			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", c.Transcription.Language)
+		if azureEndpoint(c.Transcription.Endpoint) == "" {
+			return fmt.Errorf("Azure OpenAI endpoint required: not found in config (transcription.endpoint) or environment variable (AZURE_OPENAI_ENDPOINT)")
 		}
-
-		// Validate Groq translation model - only whisper-large-v3 is supported (no turbo)
-		if c.Transcription.Model != "" && c.Transcription.Model != "whisper-large-v3" {
-			return fmt.Errorf("invalid model for groq-translation: %s (must be whisper-large-v3, turbo version not supported for translation)", c.Transcription.Model)
+		if c.Transcription.Model == "" {
+			return fmt.Errorf("invalid transcription.model: empty (azure-openai needs the deployment name)")
+		}
+		if c.Transcription.APIVersion == "" {
+			c.Transcription.APIVersion = DefaultAzureAPIVersion
 		}
 
 	default:
-		return fmt.Errorf("unsupported transcription.provider: %s (must be openai, groq-transcription, or groq-translation)", c.Transcription.Provider)
+		return fmt.Errorf("unsupported transcription.provider: %s (must be openai, groq, or azure-openai)", c.Transcription.Provider)
+	}
+
+	// Validate language code if provided (empty string means auto-detect).
+	// For translation, the language hints at the source audio (output is always English).
+	if c.Transcription.Language != "" && !IsValidLanguageCode(c.Transcription.Language) {
+		return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", c.Transcription.Language)
+	}
+	for language := range c.Transcription.Prompts {
+		if !IsValidLanguageCode(language) {
+			return fmt.Errorf("invalid transcription.prompts key: %s (must be an ISO-639-1 language code like 'en' or 'it')", language)
+		}
 	}
 
+	// An empty model gets the provider's default instead of blocking startup
 	if c.Transcription.Model == "" {
-		return fmt.Errorf("invalid transcription.model: empty")
+		c.Transcription.Model = transcriber.DefaultModel(c.Transcription.Provider, c.Transcription.Task)
+		log.Printf("Config: transcription.model not set, using %s", c.Transcription.Model)
+	}
+	if err := c.validateTranscriptionModel(); err != nil {
+		return err
+	}
+	if c.Transcription.Realtime {
+		provider, task := transcriber.NormalizeProvider(c.Transcription.Provider, c.Transcription.Task)
+		if provider != "openai" || task != transcriber.TaskTranscribe {
+			return fmt.Errorf("invalid transcription.realtime: requires provider openai with task transcribe (got %s %s)", provider, task)
+		}
+	}
+	if c.Transcription.Start == "" {
+		c.Transcription.Start = TranscriptionStartOnRecord
+	}
+	switch c.Transcription.Start {
+	case TranscriptionStartOnRecord:
+	case TranscriptionStartOnInject:
+		if c.Transcription.Realtime {
+			return fmt.Errorf("invalid transcription.start: on_inject cannot stream, set transcription.realtime = false")
+		}
+	default:
+		return fmt.Errorf("invalid transcription.start: %s (must be on_record or on_inject)", c.Transcription.Start)
+	}
+	if c.Transcription.FinalizeTimeout == 0 {
+		c.Transcription.FinalizeTimeout = DefaultFinalizeTimeout
+	}
+	if c.Transcription.FinalizeTimeout < 0 {
+		return fmt.Errorf("invalid transcription.finalize_timeout: %v (must not be negative)", c.Transcription.FinalizeTimeout)
+	}
+
+	// Hallucination filter (optional - defaults to the built-in phrase list, set to [] to disable)
+	if c.Transcription.HallucinationPhrases == nil {
+		c.Transcription.HallucinationPhrases = transcriber.DefaultHallucinationPhrases
+	}
+	if c.Transcription.MaxRepetitions == 0 {
+		c.Transcription.MaxRepetitions = transcriber.DefaultMaxRepetitions
+	}
+	if c.Transcription.MaxRepetitions < 1 {
+		return fmt.Errorf("invalid transcription.max_repetitions: %d (must be at least 1)", c.Transcription.MaxRepetitions)
+	}
+	if c.Transcription.ResponseFormat == "" {
+		c.Transcription.ResponseFormat = transcriber.ResponseFormatText
+	}
+	if !transcriber.IsSupportedResponseFormat(c.Transcription.Provider, c.Transcription.Model, c.Transcription.ResponseFormat) {
+		return fmt.Errorf("invalid transcription.response_format for %s %s: %s (must be %s)",
+			c.Transcription.Provider, c.Transcription.Model, c.Transcription.ResponseFormat,
+			strings.Join(transcriber.ResponseFormats(c.Transcription.Provider, c.Transcription.Model), ", "))
 	}
 
 	// Injection
 	if len(c.Injection.Backends) == 0 {
 		return fmt.Errorf("invalid injection.backends: empty (must have at least one backend)")
 	}
-	validBackends := map[string]bool{"ydotool": true, "wtype": true, "clipboard": true}
+	validBackends := map[string]bool{"ydotool": true, "wtype": true, "clipboard": true, injection.BackendClipboardCopy: true, "file": true}
 	for _, backend := range c.Injection.Backends {
 		if !validBackends[backend] {
-			return fmt.Errorf("invalid injection.backends: unknown backend %q (must be ydotool, wtype, or clipboard)", backend)
+			return fmt.Errorf("invalid injection.backends: unknown backend %q (must be ydotool, wtype, clipboard, clipboard-copy, or file)", backend)
 		}
+		if backend == "file" && c.Injection.FilePath == "" {
+			return fmt.Errorf("invalid injection.file_path: empty (required by the file backend)")
+		}
+	}
+	if c.Injection.FileMode == "" {
+		c.Injection.FileMode = injection.FileModeAppend
+	}
+	if c.Injection.FileMode != injection.FileModeAppend && c.Injection.FileMode != injection.FileModeOverwrite {
+		return fmt.Errorf("invalid injection.file_mode: %s (must be append or overwrite)", c.Injection.FileMode)
 	}
 	if c.Injection.YdotoolTimeout <= 0 {
 		return fmt.Errorf("invalid injection.ydotool_timeout: %v", c.Injection.YdotoolTimeout)
@@ -236,12 +573,143 @@ func (c *Config) Validate() error {
 	if c.Injection.ClipboardTimeout <= 0 {
 		return fmt.Errorf("invalid injection.clipboard_timeout: %v", c.Injection.ClipboardTimeout)
 	}
+	if c.Injection.TimeoutPerChar < 0 {
+		return fmt.Errorf("invalid injection.timeout_per_char: %v (must not be negative)", c.Injection.TimeoutPerChar)
+	}
+	if c.Injection.MaxTimeout == 0 {
+		c.Injection.MaxTimeout = injection.DefaultMaxTimeout
+	}
+	if c.Injection.MaxTimeout < 0 {
+		return fmt.Errorf("invalid injection.max_timeout: %v (must not be negative)", c.Injection.MaxTimeout)
+	}
+	if c.Injection.Strategy == "" {
+		c.Injection.Strategy = injection.StrategySequential
+	}
+	if c.Injection.Strategy != injection.StrategySequential && c.Injection.Strategy != injection.StrategyParallel {
+		return fmt.Errorf("invalid injection.strategy: %s (must be sequential or parallel)", c.Injection.Strategy)
+	}
+	if c.Injection.RetriesPerBackend == 0 {
+		c.Injection.RetriesPerBackend = 1
+	}
+	if c.Injection.RetriesPerBackend < 1 {
+		return fmt.Errorf("invalid injection.retries_per_backend: %d (must be at least 1)", c.Injection.RetriesPerBackend)
+	}
+	if c.Injection.HumanizeMinDelay == 0 && c.Injection.HumanizeMaxDelay == 0 {
+		c.Injection.HumanizeMinDelay = injection.DefaultHumanizeMinDelay
+		c.Injection.HumanizeMaxDelay = injection.DefaultHumanizeMaxDelay
+	}
+	if c.Injection.HumanizeMinDelay < 0 || c.Injection.HumanizeMaxDelay < c.Injection.HumanizeMinDelay {
+		return fmt.Errorf("invalid injection.humanize delays: min %v, max %v (must be 0 <= min <= max)", c.Injection.HumanizeMinDelay, c.Injection.HumanizeMaxDelay)
+	}
+	if c.Injection.Compositor == "" {
+		c.Injection.Compositor = injection.CompositorAuto
+	}
+	validCompositors := map[string]bool{injection.CompositorAuto: true, injection.CompositorHyprland: true, injection.CompositorSway: true, injection.CompositorGeneric: true}
+	if !validCompositors[c.Injection.Compositor] {
+		return fmt.Errorf("invalid injection.compositor: %s (must be auto, hyprland, sway, or generic)", c.Injection.Compositor)
+	}
+	if c.Injection.FocusCommand != "" && !strings.Contains(c.Injection.FocusCommand, injection.FocusCommandPlaceholder) {
+		return fmt.Errorf("invalid injection.focus_command: %q has no %s placeholder for the window address", c.Injection.FocusCommand, injection.FocusCommandPlaceholder)
+	}
+	if c.Injection.FocusCommand != "" && c.Injection.Compositor == injection.CompositorGeneric {
+		return fmt.Errorf("invalid injection.focus_command: the generic compositor captures no window address for %s (use auto, hyprland or sway)", injection.FocusCommandPlaceholder)
+	}
+	if c.Injection.PreInjectDelay < 0 {
+		return fmt.Errorf("invalid injection.pre_inject_delay: %v (must not be negative)", c.Injection.PreInjectDelay)
+	}
+	if c.Injection.WindowRetries < 0 {
+		return fmt.Errorf("invalid injection.window_retries: %d (must be 0 or more)", c.Injection.WindowRetries)
+	}
+	if c.Injection.WindowRetryDelay == 0 {
+		c.Injection.WindowRetryDelay = injection.DefaultWindowRetryDelay
+	}
+	if c.Injection.WindowRetryDelay < 0 {
+		return fmt.Errorf("invalid injection.window_retry_delay: %v (must be positive)", c.Injection.WindowRetryDelay)
+	}
+
+	if c.Injection.ClipboardSelection == "" {
+		c.Injection.ClipboardSelection = injection.SelectionClipboard
+	}
+	validSelections := map[string]bool{injection.SelectionClipboard: true, injection.SelectionPrimary: true, injection.SelectionBoth: true}
+	if !validSelections[c.Injection.ClipboardSelection] {
+		return fmt.Errorf("invalid injection.clipboard_selection: %s (must be clipboard, primary, or both)", c.Injection.ClipboardSelection)
+	}
+	if c.Injection.KeyboardLayout == "" {
+		c.Injection.KeyboardLayout = injection.LayoutUS
+	}
+	if !injection.ValidKeyboardLayout(c.Injection.KeyboardLayout) {
+		return fmt.Errorf("invalid injection.keyboard_layout: %q (must be an XKB layout name like \"us\", \"de\" or \"fr\")", c.Injection.KeyboardLayout)
+	}
+	if c.Injection.ClipboardMIME == "" {
+		c.Injection.ClipboardMIME = injection.DefaultClipboardMIME
+	}
+	if mediaType, _, err := mime.ParseMediaType(c.Injection.ClipboardMIME); err != nil || !strings.Contains(mediaType, "/") {
+		return fmt.Errorf("invalid injection.clipboard_mime: %q (must be a MIME type like \"text/plain;charset=utf-8\")", c.Injection.ClipboardMIME)
+	}
+	for _, combo := range append(append([]string{}, c.Injection.PreKeys...), c.Injection.PostKeys...) {
+		if !injection.ValidKeyCombo(combo) {
+			return fmt.Errorf("invalid injection key combo: %q (use \"key\" or \"mod+key\", e.g. \"ctrl+l\")", combo)
+		}
+	}
 
 	// Notifications
 	validTypes := map[string]bool{"desktop": true, "log": true, "none": true}
 	if !validTypes[c.Notifications.Type] {
 		return fmt.Errorf("invalid notifications.type: %s (must be desktop, log, or none)", c.Notifications.Type)
 	}
+	if c.Notifications.ErrorUrgency == "" {
+		c.Notifications.ErrorUrgency = "critical"
+	}
+	if c.Notifications.InfoUrgency == "" {
+		c.Notifications.InfoUrgency = "normal"
+	}
+	validUrgencies := map[string]bool{"low": true, "normal": true, "critical": true}
+	if !validUrgencies[c.Notifications.ErrorUrgency] {
+		return fmt.Errorf("invalid notifications.error_urgency: %s (must be low, normal, or critical)", c.Notifications.ErrorUrgency)
+	}
+	if !validUrgencies[c.Notifications.InfoUrgency] {
+		return fmt.Errorf("invalid notifications.info_urgency: %s (must be low, normal, or critical)", c.Notifications.InfoUrgency)
+	}
+	if c.Notifications.ErrorTimeout < 0 {
+		return fmt.Errorf("invalid notifications.error_timeout: %v", c.Notifications.ErrorTimeout)
+	}
+	if c.Notifications.InfoTimeout < 0 {
+		return fmt.Errorf("invalid notifications.info_timeout: %v", c.Notifications.InfoTimeout)
+	}
+
+	// Behavior
+	if c.Behavior.ToggleDuringInjection == "" {
+		c.Behavior.ToggleDuringInjection = ToggleInjectionAbort
+	}
+	validToggles := map[string]bool{ToggleInjectionAbort: true, ToggleInjectionIgnore: true, ToggleInjectionAbortClearClipboard: true}
+	if !validToggles[c.Behavior.ToggleDuringInjection] {
+		return fmt.Errorf("invalid behavior.toggle_during_injection: %s (must be abort, ignore, or abort-and-clear-clipboard)", c.Behavior.ToggleDuringInjection)
+	}
+	if c.Behavior.OnFocusChange == "" {
+		c.Behavior.OnFocusChange = FocusChangeIgnore
+	}
+	validFocusActions := map[string]bool{FocusChangeIgnore: true, FocusChangeWarn: true, FocusChangeCancel: true}
+	if !validFocusActions[c.Behavior.OnFocusChange] {
+		return fmt.Errorf("invalid behavior.on_focus_change: %s (must be ignore, warn, or cancel)", c.Behavior.OnFocusChange)
+	}
+	if c.Behavior.MaxCommandLength == 0 {
+		c.Behavior.MaxCommandLength = bus.DefaultMaxCommandLength
+	}
+	if c.Behavior.MaxCommandLength < 64 {
+		return fmt.Errorf("invalid behavior.max_command_length: %d (must be at least 64 bytes)", c.Behavior.MaxCommandLength)
+	}
+	if c.Behavior.ToggleDebounce < 0 {
+		return fmt.Errorf("invalid behavior.toggle_debounce: %v", c.Behavior.ToggleDebounce)
+	}
+	if c.Behavior.MergeWindow < 0 {
+		return fmt.Errorf("invalid behavior.merge_window: %v (must not be negative)", c.Behavior.MergeWindow)
+	}
+	if c.Behavior.ConfirmTimeout == 0 {
+		c.Behavior.ConfirmTimeout = DefaultConfirmTimeout
+	}
+	if c.Behavior.ConfirmTimeout < 0 {
+		return fmt.Errorf("invalid behavior.confirm_timeout: %v (must not be negative)", c.Behavior.ConfirmTimeout)
+	}
 
 	// Processing (optional - defaults to "raw" if not set)
 	if c.Processing.Mode == "" {
@@ -251,14 +719,48 @@ func (c *Config) Validate() error {
 	if !validModes[c.Processing.Mode] {
 		return fmt.Errorf("invalid processing.mode: %s (must be raw or llm)", c.Processing.Mode)
 	}
+	if c.Processing.Pipeline == nil {
+		c.Processing.Pipeline = append([]string(nil), DefaultProcessingPipeline...)
+	}
+	seenStages := map[string]bool{}
+	for _, stage := range c.Processing.Pipeline {
+		switch stage {
+		case StageReplace, StageLLM, StageSnippets, StageCommand:
+		default:
+			return fmt.Errorf("invalid processing.pipeline stage: %s (must be replace, llm, snippets, or command)", stage)
+		}
+		if seenStages[stage] {
+			return fmt.Errorf("invalid processing.pipeline: stage %s listed more than once", stage)
+		}
+		seenStages[stage] = true
+	}
+	for from := range c.Processing.Replacements {
+		if strings.TrimSpace(from) == "" {
+			return fmt.Errorf("invalid processing.replacements: empty word")
+		}
+	}
+	for trigger := range c.Processing.Snippets {
+		if strings.TrimSpace(trigger) == "" {
+			return fmt.Errorf("invalid processing.snippets: empty trigger")
+		}
+	}
+	if c.Processing.CommandTimeout == 0 {
+		c.Processing.CommandTimeout = DefaultCommandTimeout
+	}
+	if c.Processing.CommandTimeout < 0 {
+		return fmt.Errorf("invalid processing.command_timeout: %v (must be positive)", c.Processing.CommandTimeout)
+	}
+	if c.Processing.Command != "" && !seenStages[StageCommand] {
+		log.Printf("Config: processing.command is set but processing.pipeline has no \"command\" stage, so it will not run")
+	}
 
 	// LLM config (only validate if mode is "llm")
 	if c.Processing.Mode == "llm" {
 		if c.LLM.Provider == "" {
 			c.LLM.Provider = "openai"
 		}
-		if c.LLM.Provider != "openai" {
-			return fmt.Errorf("invalid llm.provider: %s (must be openai)", c.LLM.Provider)
+		if c.LLM.Provider != "openai" && c.LLM.Provider != "azure-openai" {
+			return fmt.Errorf("invalid llm.provider: %s (must be openai or azure-openai)", c.LLM.Provider)
 		}
 		if c.LLM.Model == "" {
 			c.LLM.Model = "gpt-4o-mini"
@@ -267,28 +769,95 @@ func (c *Config) Validate() error {
 		if c.LLM.Level == "" {
 			c.LLM.Level = "moderate"
 		}
-		validLevels := map[string]bool{"minimal": true, "moderate": true, "thorough": true, "custom": true}
-		if !validLevels[c.LLM.Level] {
+		if !llm.IsValidLevel(c.LLM.Level) {
 			return fmt.Errorf("invalid llm.level: %s (must be minimal, moderate, thorough, or custom)", c.LLM.Level)
 		}
+		if err := c.validateLLMModel("llm.model", c.LLM.Model); err != nil {
+			return err
+		}
+		for level, model := range c.LLM.Models {
+			if !llm.IsValidLevel(level) {
+				return fmt.Errorf("invalid llm.models key: %s (must be minimal, moderate, thorough, or custom)", level)
+			}
+			if model == "" {
+				return fmt.Errorf("invalid llm.models.%s: empty model", level)
+			}
+			if err := c.validateLLMModel("llm.models."+level, model); err != nil {
+				return err
+			}
+		}
+		if c.LLM.MinOutputRatio < 0 || c.LLM.MinOutputRatio >= 1 {
+			return fmt.Errorf("invalid llm.min_output_ratio: %g (must be at least 0 and below 1)", c.LLM.MinOutputRatio)
+		}
 		// If level is custom, require a custom_prompt
 		if c.LLM.Level == "custom" && c.LLM.CustomPrompt == "" {
 			return fmt.Errorf("llm.custom_prompt is required when llm.level is 'custom'")
 		}
 		// Check for API key
+		keyEnv := "OPENAI_API_KEY"
+		if c.LLM.Provider == "azure-openai" {
+			keyEnv = "AZURE_OPENAI_API_KEY"
+			if azureEndpoint(c.LLM.Endpoint) == "" {
+				return fmt.Errorf("Azure OpenAI endpoint required when processing.mode is 'llm': not found in config (llm.endpoint) or environment variable (AZURE_OPENAI_ENDPOINT)")
+			}
+			if c.LLM.APIVersion == "" {
+				c.LLM.APIVersion = DefaultAzureAPIVersion
+			}
+		}
 		apiKey := c.LLM.APIKey
 		if apiKey == "" {
-			apiKey = os.Getenv("OPENAI_API_KEY")
+			apiKey = os.Getenv(keyEnv)
 		}
 		if apiKey == "" {
-			return fmt.Errorf("LLM API key required when processing.mode is 'llm': not found in config (llm.api_key) or environment variable (OPENAI_API_KEY)")
+			return fmt.Errorf("LLM API key required when processing.mode is 'llm': not found in config (llm.api_key) or environment variable (%s)", keyEnv)
 		}
 	}
 
 	return nil
 }
 
-func isValidLanguageCode(code string) bool {
+// allowUnknownModels is set by "serve --allow-unknown-model" and skips model list checks for every config load
+var allowUnknownModels atomic.Bool
+
+// AllowUnknownModels disables the known-model checks for custom or OpenAI-compatible endpoints
+func AllowUnknownModels(allow bool) {
+	allowUnknownModels.Store(allow)
+}
+
+// validateTranscriptionModel rejects model names the provider does not serve for the task,
+// catching typos at config time instead of as runtime 404s
+func (c *Config) validateTranscriptionModel() error {
+	// Azure deployments are named by the user, so there is no list to check against
+	if c.Transcription.AllowUnknownModel || allowUnknownModels.Load() || c.Transcription.Provider == "azure-openai" {
+		return nil
+	}
+
+	provider, task, model := c.Transcription.Provider, c.Transcription.Task, c.Transcription.Model
+	if transcriber.IsSupportedModel(provider, task, model) {
+		return nil
+	}
+
+	supported := strings.Join(transcriber.SupportedModels[provider][task], " or ")
+	if provider == "groq" && task == transcriber.TaskTranslate {
+		supported += ", turbo version not supported for translation"
+	}
+	taskName := "transcription"
+	if task == transcriber.TaskTranslate {
+		taskName = "translation"
+	}
+	return fmt.Errorf("invalid model for %s %s: %s (must be %s)", provider, taskName, model, supported)
+}
+
+func (c *Config) validateLLMModel(key, model string) error {
+	if c.LLM.AllowUnknownModel || allowUnknownModels.Load() || c.LLM.Provider == "azure-openai" || llm.IsKnownModel(c.LLM.Provider, model) {
+		return nil
+	}
+	return fmt.Errorf("invalid %s: %s (known models: %s; set llm.allow_unknown_model = true to use others)",
+		key, model, strings.Join(llm.KnownModels[c.LLM.Provider], ", "))
+}
+
+// IsValidLanguageCode reports whether code is an ISO-639-1 language code Whisper accepts
+func IsValidLanguageCode(code string) bool {
 	validCodes := map[string]bool{
 		"en": true, "es": true, "fr": true, "de": true, "it": true, "pt": true,
 		"ru": true, "ja": true, "ko": true, "zh": true, "ar": true, "hi": true,
@@ -349,9 +918,21 @@ func Load() (*Config, error) {
 
 	log.Printf("Config: loading configuration from %s", configPath)
 	var config Config
+	config.Transcription.RepetitionFilter = true
+	config.LLM.FallbackToRaw = true
+	config.Notifications.UpdateInPlace = true
+	config.Injection.TimeoutPerChar = injection.DefaultTimeoutPerChar // "0s" in the file turns scaling off
+	config.Injection.WindowRetries = injection.DefaultWindowRetries   // 0 in the file turns retries off
+	config.Recording.TimeoutWarning = DefaultTimeoutWarning           // 0 in the file turns the warning off
 	if _, err := toml.DecodeFile(configPath, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
 	}
+	if err := config.applyEnvOverrides(); err != nil {
+		return nil, err
+	}
+	if err := config.resolveKeyRefs(); err != nil {
+		return nil, err
+	}
 
 	// Migrate legacy mode-based config to backends
 	if len(config.Injection.Backends) == 0 {
@@ -360,6 +941,9 @@ func Load() (*Config, error) {
 		config.migrateInjectionMode(legacy.Injection.Mode)
 	}
 
+	// Migrate legacy groq-transcription/groq-translation providers to provider + task
+	config.migrateTranscriptionProvider()
+
 	log.Printf("Config: configuration loaded successfully")
 	return &config, nil
 }
@@ -392,6 +976,16 @@ func (c *Config) migrateInjectionMode(mode string) {
 	log.Printf("Config: legacy 'mode' config detected - please update your config.toml to use 'backends' instead")
 }
 
+// migrateTranscriptionProvider converts legacy provider names to the provider + task pair
+func (c *Config) migrateTranscriptionProvider() {
+	provider, task := transcriber.NormalizeProvider(c.Transcription.Provider, c.Transcription.Task)
+	if provider != c.Transcription.Provider {
+		log.Printf("Config: migrated transcription.provider='%s' to provider='%s', task='%s' - please update your config.toml", c.Transcription.Provider, provider, task)
+	}
+	c.Transcription.Provider = provider
+	c.Transcription.Task = task
+}
+
 func SaveDefaultConfig() error {
 	configPath, err := GetConfigPath()
 	if err != nil {
@@ -410,66 +1004,163 @@ func SaveDefaultConfig() error {
 
 # Audio Recording Configuration
 [recording]
+  backend = "pipewire"         # Audio capture: "pipewire" (pw-record), "pulse" (parec), or "auto" (PipeWire, falling back to PulseAudio)
   sample_rate = 16000          # Audio sample rate in Hz (16000 recommended for speech)
   channels = 1                 # Number of audio channels (1 = mono, 2 = stereo)
-  format = "s16"               # Audio format (s16 = 16-bit signed integers)
+  format = "s16"               # Capture format: s16 (default), s24, s32 or f32; others are converted to s16
   buffer_size = 8192           # Internal buffer size in bytes (larger = less CPU, more latency)
-  device = ""                  # PipeWire audio device (empty = use default microphone)
+  device = ""                  # Audio device / source name (empty = use default microphone)
+  device_fallback = []         # Devices tried in order, first one plugged in wins, e.g. ["alsa_input.usb-headset", "alsa_input.pci-builtin"] (falls back to device)
   channel_buffer_size = 30     # Audio frame buffer size (frames to buffer)
   timeout = "5m"               # Maximum recording duration (e.g., "30s", "2m", "5m")
+  start_delay = "0s"           # Discard audio captured right after toggling (e.g., "200ms" to drop keybind clicks)
+  trim_silence = false         # Cut leading and trailing silence before upload (faster, fewer hallucinations on quiet starts)
+  timeout_warning = 0.9        # Notify once this fraction of timeout has passed, e.g. 0.9 = 30s left of 5m (0 = no warning)
 
 # Speech Transcription Configuration
 [transcription]
-  provider = "openai"          # Transcription service: "openai", "groq-transcription", or "groq-translation"
+  provider = "openai"          # Transcription service: "openai", "groq", or "azure-openai"
+  task = "transcribe"          # "transcribe" (keep spoken language) or "translate" (output English)
   api_key = ""                 # API key (or set OPENAI_API_KEY/GROQ_API_KEY environment variable)
+  api_key_ref = ""             # Read the key from the system keyring instead, e.g. "keyring:hyprvoice/openai"
   language = ""                # Language code (empty for auto-detect, "en", "it", "es", "fr", etc.)
-  model = "whisper-1"          # Model: OpenAI="whisper-1", Groq="whisper-large-v3" or "whisper-large-v3-turbo"
+  model = "whisper-1"          # Model: OpenAI="whisper-1", "gpt-4o-transcribe" or "gpt-4o-mini-transcribe", Groq="whisper-large-v3" or "whisper-large-v3-turbo" (empty = provider default); for azure-openai, the deployment name
+  endpoint = ""                # azure-openai only: resource URL, e.g. "https://myresource.openai.azure.com" (or AZURE_OPENAI_ENDPOINT)
+  api_version = ""             # azure-openai only: api-version query parameter (empty = 2024-06-01)
+  realtime = false             # OpenAI transcribe only: stream audio while recording over the realtime websocket (batch upload if it fails)
+  start = "on_record"          # When the transcriber opens: "on_record" (while recording) or "on_inject" (only once recording stops)
+  finalize_timeout = "2m"      # Time for the upload, LLM cleanup and injection once recording stops, separate from recording.timeout
+  hallucination_phrases = ["thanks for watching", "thank you for watching", "please subscribe", "like and subscribe"]  # Results matching these exactly are discarded ([] to disable)
+  repetition_filter = true     # Collapse phrases Whisper loops on ("thank you thank you thank you ...") to one copy
+  max_repetitions = 3          # Back-to-back copies of a phrase kept as real speech before it counts as a loop
+  response_format = "text"     # "hyprvoice transcribe" output: "text", "json", "verbose_json", "srt", "vtt" (srt/vtt OpenAI only; dictation always uses text)
+  allow_unknown_model = false  # Accept models outside the provider's known list (custom or newer endpoints)
+  prompt = ""                  # Whisper prompt to bias vocabulary and spelling, e.g. "Kubernetes, Hyprland, gRPC"
+
+# Per-language prompts (the entry for the current language replaces prompt)
+[transcription.prompts]
+  # it = "Riunione su Kubernetes, Hyprland e gRPC."
 
 # Text Injection Configuration
 [injection]
   backends = ["ydotool", "wtype", "clipboard"]  # Ordered fallback chain (tries each until one succeeds)
+  strategy = "sequential"      # "sequential" (fallback chain) or "parallel" (prepare all at once, one writes at a time, first success wins)
+  retries_per_backend = 1      # Attempts per backend before falling through to the next (1 = no retry)
+  humanize = false             # Type character by character with random delays (ydotool/wtype) for apps that reject bulk input
+  humanize_min_delay = "30ms"  # Shortest delay between humanized keystrokes
+  humanize_max_delay = "120ms" # Longest delay between humanized keystrokes
   ydotool_timeout = "5s"       # Timeout for ydotool commands
   wtype_timeout = "5s"         # Timeout for wtype commands
   clipboard_timeout = "3s"     # Timeout for clipboard operations
+  timeout_per_char = "20ms"    # Extra ydotool/wtype time per character so long dictations don't time out ("0s" = fixed timeouts)
+  max_timeout = "2m"           # Upper bound for the scaled ydotool/wtype timeout
+  focus_window = true          # Refocus the window that was active when recording started (false = type/copy into the current window)
+  focus_before_type = false    # Also refocus it before ydotool/wtype type (by default only clipboard refocuses before pasting)
+  compositor = "auto"          # Window tracking: "auto" (detect), "hyprland" (hyprctl), "sway" (swaymsg), or "generic" (no window tracking)
+  focus_command = ""           # Shell command that focuses the recorded window, {addr} is its quoted address, e.g. "hyprctl dispatch focuswindow address:{addr}" (empty = built-in)
+  pre_inject_delay = "0s"      # Pause after focusing and before typing or pasting, for apps that drop the first characters ("0s" = none)
+  keyboard_layout = "us"       # Your XKB layout, e.g. "de" or "fr"; ydotool passes text it would mistype on to the next backend
+  window_retries = 2           # Ask the compositor again this many times when the active window comes back empty right after the keybind
+  window_retry_delay = "50ms"  # Pause between those attempts
+  deny_classes = []            # Never inject into these window classes, e.g. ["KeePassXC", "Bitwarden"] (Hyprland/Sway only)
+  clipboard_selection = "clipboard"  # Clipboard backend target: "clipboard" (Ctrl+V), "primary" (middle-click), or "both"
+  clipboard_mime = "text/plain;charset=utf-8"  # MIME type the text is copied as (wl-copy --type)
+  pre_keys = []                # Key combos pressed before the text, e.g. ["i"] (vim insert mode) or ["ctrl+l"]
+  post_keys = []               # Key combos pressed after the text, e.g. ["Escape"] or ["Return"]
+  prefix = ""                  # Text added before every dictation, e.g. "- " for bullet notes
+  suffix = ""                  # Text added after every dictation, e.g. "\n"
+  verify_clipboard = false     # Read the clipboard back with wl-paste before pasting; skip the paste if it doesn't match
+  file_path = ""               # File the "file" backend writes to for editors that watch it, e.g. "$XDG_RUNTIME_DIR/hyprvoice.txt"
+  file_mode = "append"         # File backend: "append" (one dictation per line) or "overwrite" (latest dictation only)
 
 # Desktop Notification Configuration
 [notifications]
   enabled = true               # Enable desktop notifications
   type = "desktop"             # Notification type ("desktop", "log", "none")
+  error_urgency = "critical"   # Urgency for errors: "low", "normal", or "critical" (critical usually stays until dismissed)
+  error_timeout = "0s"         # How long errors stay up, e.g. "10s" (0 = notification server default)
+  info_urgency = "normal"      # Urgency for status messages like "Recording Started"
+  info_timeout = "0s"          # How long status messages stay up, e.g. "2s" (0 = notification server default)
+  update_in_place = true       # Update one notification per dictation instead of stacking (needs libnotify 0.7.9+)
 
 # Post-Transcription Processing Configuration
 [processing]
   mode = "raw"                 # Processing mode: "raw" (direct transcription) or "llm" (AI cleanup)
+  continue_sentence = false    # Lowercase the first word so dictation fits mid-sentence (toggle with "hyprvoice continue")
+  pipeline = ["replace", "llm", "snippets", "command"]  # Post-processing stages in order; "llm" only runs when mode = "llm"
+  command = ""                 # Pipe the text through this shell command ("command" stage), e.g. "~/bin/fix-terms"
+  command_timeout = "5s"       # Give up on the command after this long and keep its input
+
+# Whole-word, case-insensitive substitutions applied by the "replace" stage
+[processing.replacements]
+  # "hyper voice" = "Hyprvoice"
+
+# Dictations that consist only of a trigger phrase are replaced by its expansion ("snippets" stage)
+[processing.snippets]
+  # "my email" = "jane@example.com"
 
 # LLM Configuration (used when processing.mode = "llm")
 [llm]
-  provider = "openai"          # LLM provider (currently only "openai" supported)
-  api_key = ""                 # API key (or use OPENAI_API_KEY environment variable)
+  provider = "openai"          # LLM provider: "openai" or "azure-openai"
+  api_key = ""                 # API key (or use OPENAI_API_KEY, or AZURE_OPENAI_API_KEY for azure-openai)
+  endpoint = ""                # azure-openai only: resource URL (or AZURE_OPENAI_ENDPOINT); model names the deployment
+  api_version = ""             # azure-openai only: api-version query parameter (empty = 2024-06-01)
   model = "gpt-4o-mini"        # Model to use for text cleanup
   level = "moderate"           # Intervention level: "minimal", "moderate", "thorough", or "custom"
   custom_prompt = ""           # Custom system prompt (used when level = "custom")
+  allow_unknown_model = false  # Accept models outside the known OpenAI chat model list
+  min_output_ratio = 0.0       # Keep the raw text when the LLM output is shorter than this fraction of it, e.g. 0.5 (0 = only reject empty output)
+  fallback_to_raw = true       # Inject the raw text when the LLM fails (false = copy it to the clipboard and notify instead)
+  strip_formatting = false     # Remove "Here is the cleaned text:" preambles, wrapping quotes, bullets and markdown the LLM adds anyway
+
+# Per-level model overrides (levels not listed use llm.model)
+[llm.models]
+  # minimal = "gpt-4o-mini"
+  # thorough = "gpt-4o"
+
+# Behavior Configuration
+[behavior]
+  confirm_before_inject = false  # Show the transcription and wait for "hyprvoice confirm" or "hyprvoice discard" before injecting
+  confirm_except_classes = []    # Window classes that skip the confirmation, e.g. ["obsidian"] (Hyprland/Sway only)
+  confirm_timeout = "2m"         # How long a transcription waits for "hyprvoice confirm" or "hyprvoice inject" before it is copied to the clipboard and failsafe_file
+  explicit_inject = false        # Toggle stops and transcribes, then the text waits for "hyprvoice inject" (or "hyprvoice discard")
+  state_file = ""                # Write the current status (idle, recording, ...) to this file for status bars (empty = disabled)
+  toggle_during_injection = "abort"  # Toggle while typing: "abort", "ignore" (let it finish), or "abort-and-clear-clipboard"
+  on_focus_change = "ignore"     # When focus leaves the window dictation started in: "ignore", "warn", or "cancel" (Hyprland/Sway only)
+  max_command_length = 65536     # Longest control socket command in bytes; longer lines get "ERR code=too_long"
+  rapid_mode = false             # Keep recording after each inject; toggle injects a snippet, "hyprvoice cancel" ends the session
+  failsafe_file = ""             # Append transcriptions that could not be injected to this file (empty = disabled)
+  toggle_debounce = "0s"         # Ignore a toggle this soon after the previous one, e.g. "200ms" for a bouncy keybind ("0s" = off)
+  autostart_recording = false    # Start recording as soon as the daemon launches, as if toggled (pair with rapid_mode for always-on dictation)
+  merge_window = "0s"            # Dictating into the same window this soon after the last injection continues that text instead of starting anew, e.g. "10s" ("0s" = off)
 
 # Backend explanations:
 # - "ydotool": Uses ydotool (requires ydotoold daemon running). Most compatible with Chromium/Electron apps.
 # - "wtype": Uses wtype for Wayland. May have issues with some Chromium-based apps.
-# - "clipboard": Copies text to clipboard only (most reliable, but requires manual paste).
+# - "clipboard": Copies text to clipboard, then refocuses the recorded window and pastes (copy-only if focus_window = false).
+# - "clipboard-copy": Copies text to clipboard and never focuses or pastes; you paste by hand.
+# - "file": Writes text to file_path so an editor plugin can insert it (no keystrokes).
 #
 # The backends are tried in order. First successful one wins.
 # Example configurations:
 #   backends = ["clipboard"]                      # Clipboard only (safest)
+#   backends = ["clipboard-copy"]                 # Transcribe and copy, paste manually
 #   backends = ["wtype", "clipboard"]             # wtype with clipboard fallback
 #   backends = ["ydotool", "wtype", "clipboard"]  # Full fallback chain (default)
 #
 # Provider explanations:
 # - "openai": OpenAI Whisper API (cloud-based, requires OPENAI_API_KEY)
-# - "groq-transcription": Groq Whisper API for transcription (fast, requires GROQ_API_KEY)
-#     Models: whisper-large-v3 or whisper-large-v3-turbo
-# - "groq-translation": Groq Whisper API for translation to English (always outputs English text)
-#     Models: whisper-large-v3 only (turbo not supported for translation)
+#     task = "translate" requires model "whisper-1"
+# - "groq": Groq Whisper API (fast, requires GROQ_API_KEY)
+#     task = "transcribe": whisper-large-v3 or whisper-large-v3-turbo
+#     task = "translate":  whisper-large-v3 only (turbo not supported for translation)
+# - "azure-openai": Azure OpenAI resource (requires AZURE_OPENAI_API_KEY and endpoint or AZURE_OPENAI_ENDPOINT)
+#     model is the deployment name; api_version defaults to 2024-06-01
+# Legacy provider names "groq-transcription" and "groq-translation" are still accepted.
 #
 # Language codes: Use empty string ("") for automatic detection, or specific codes like:
 # "en" (English), "it" (Italian), "es" (Spanish), "fr" (French), "de" (German), etc.
-# For groq-translation, the language field hints at the source audio language for better accuracy.
+# For task = "translate", the language field hints at the source audio language for better accuracy.
 #
 # Processing mode explanations:
 # - "raw": Direct transcription output without any post-processing
@@ -483,6 +1174,7 @@ func SaveDefaultConfig() error {
 #
 # LLM provider explanations:
 # - "openai": Uses OpenAI's API (requires OPENAI_API_KEY). Recommended model: gpt-4o-mini
+# - "azure-openai": Uses an Azure OpenAI chat deployment (requires AZURE_OPENAI_API_KEY and endpoint); model is the deployment name
 `
 
 	if _, err := file.WriteString(configContent); err != nil {
@@ -1,10 +1,17 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/leonardotrapani/hyprvoice/internal/injection"
+	"github.com/leonardotrapani/hyprvoice/internal/transcriber"
 )
 
 // createTestConfig returns a valid configuration for testing
@@ -680,16 +687,16 @@ func TestIsValidLanguageCode(t *testing.T) {
 
 	for _, code := range validCodes {
 		t.Run("valid_"+code, func(t *testing.T) {
-			if !isValidLanguageCode(code) {
-				t.Errorf("isValidLanguageCode(%s) = false, want true", code)
+			if !IsValidLanguageCode(code) {
+				t.Errorf("IsValidLanguageCode(%s) = false, want true", code)
 			}
 		})
 	}
 
 	for _, code := range invalidCodes {
 		t.Run("invalid_"+code, func(t *testing.T) {
-			if isValidLanguageCode(code) {
-				t.Errorf("isValidLanguageCode(%s) = true, want false", code)
+			if IsValidLanguageCode(code) {
+				t.Errorf("IsValidLanguageCode(%s) = true, want false", code)
 			}
 		})
 	}
@@ -1224,7 +1231,1354 @@ func TestConfig_Validate_GroqTranslation_RejectsTurbo(t *testing.T) {
 	if err == nil {
 		t.Error("Validate() should have rejected whisper-large-v3-turbo for groq-translation")
 	}
-	if err != nil && err.Error() != "invalid model for groq-translation: whisper-large-v3-turbo (must be whisper-large-v3, turbo version not supported for translation)" {
+	if err != nil && err.Error() != "invalid model for groq translation: whisper-large-v3-turbo (must be whisper-large-v3, turbo version not supported for translation)" {
 		t.Errorf("Unexpected error message: %v", err)
 	}
 }
+
+func TestConfig_Validate_HallucinationPhrasesDefault(t *testing.T) {
+	config := createTestConfig()
+
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if len(config.Transcription.HallucinationPhrases) == 0 {
+		t.Errorf("Validate() should default transcription.hallucination_phrases")
+	}
+
+	// An explicit empty list disables the filter and must be preserved
+	config = createTestConfig()
+	config.Transcription.HallucinationPhrases = []string{}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if len(config.Transcription.HallucinationPhrases) != 0 {
+		t.Errorf("Validate() overrode explicit empty hallucination_phrases: %v", config.Transcription.HallucinationPhrases)
+	}
+}
+
+func TestConfig_Validate_MigratesLegacyGroqProviders(t *testing.T) {
+	tests := []struct {
+		provider string
+		model    string
+		wantTask string
+	}{
+		{"groq-transcription", "whisper-large-v3-turbo", "transcribe"},
+		{"groq-translation", "whisper-large-v3", "translate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			config := createTestConfig()
+			config.Transcription.Provider = tt.provider
+			config.Transcription.APIKey = "gsk-test-key"
+			config.Transcription.Model = tt.model
+
+			if err := config.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if config.Transcription.Provider != "groq" {
+				t.Errorf("Provider = %s, want groq", config.Transcription.Provider)
+			}
+			if config.Transcription.Task != tt.wantTask {
+				t.Errorf("Task = %s, want %s", config.Transcription.Task, tt.wantTask)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_TranscriptionTask(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		task     string
+		model    string
+		wantErr  bool
+	}{
+		{"default task", "groq", "", "whisper-large-v3-turbo", false},
+		{"groq translate", "groq", "translate", "whisper-large-v3", false},
+		{"groq translate rejects turbo", "groq", "translate", "whisper-large-v3-turbo", true},
+		{"openai translate", "openai", "translate", "whisper-1", false},
+		{"openai translate rejects other models", "openai", "translate", "gpt-4o-transcribe", true},
+		{"openai gpt-4o-transcribe", "openai", "transcribe", "gpt-4o-transcribe", false},
+		{"openai gpt-4o-mini-transcribe", "openai", "transcribe", "gpt-4o-mini-transcribe", false},
+		{"unknown task", "groq", "summarize", "whisper-large-v3", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.Transcription.Provider = tt.provider
+			config.Transcription.Task = tt.task
+			config.Transcription.Model = tt.model
+
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// loadTestConfigFile writes content as the user config file and loads it
+func loadTestConfigFile(t *testing.T, content string) *Config {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "hyprvoice", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatalf("Failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	originalConfigDir := os.Getenv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", tempDir)
+	t.Cleanup(func() {
+		if originalConfigDir == "" {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		} else {
+			os.Setenv("XDG_CONFIG_HOME", originalConfigDir)
+		}
+	})
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return config
+}
+
+func TestConfig_Load_FocusWindow(t *testing.T) {
+	base := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[injection]
+backends = ["clipboard"]
+`
+
+	t.Run("defaults to true when absent", func(t *testing.T) {
+		config := loadTestConfigFile(t, base)
+		if !config.Injection.FocusWindowEnabled() {
+			t.Errorf("FocusWindowEnabled() = false, want true when focus_window is not set")
+		}
+	})
+
+	t.Run("defaults to true without Load", func(t *testing.T) {
+		if !createTestConfig().ToInjectionConfig().FocusWindow {
+			t.Errorf("ToInjectionConfig().FocusWindow = false, want true for a config built in code")
+		}
+	})
+
+	t.Run("respects explicit false", func(t *testing.T) {
+		config := loadTestConfigFile(t, base+"focus_window = false\n")
+		if config.Injection.FocusWindowEnabled() {
+			t.Errorf("FocusWindowEnabled() = true, want false")
+		}
+		if config.ToInjectionConfig().FocusWindow {
+			t.Errorf("ToInjectionConfig().FocusWindow = true, want false")
+		}
+	})
+
+	t.Run("focus_before_type defaults to false", func(t *testing.T) {
+		config := loadTestConfigFile(t, base)
+		if config.Injection.FocusBeforeType {
+			t.Errorf("FocusBeforeType = true, want false when focus_before_type is not set")
+		}
+	})
+
+	t.Run("focus_before_type", func(t *testing.T) {
+		config := loadTestConfigFile(t, base+"focus_before_type = true\n")
+		if !config.ToInjectionConfig().FocusBeforeType {
+			t.Errorf("ToInjectionConfig().FocusBeforeType = false, want true")
+		}
+	})
+}
+
+func TestConfig_Validate_Compositor(t *testing.T) {
+	tests := []struct {
+		name       string
+		compositor string
+		want       string
+		wantErr    bool
+	}{
+		{name: "empty defaults to auto", compositor: "", want: "auto"},
+		{name: "hyprland", compositor: "hyprland", want: "hyprland"},
+		{name: "sway", compositor: "sway", want: "sway"},
+		{name: "generic", compositor: "generic", want: "generic"},
+		{name: "unknown", compositor: "kwin", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.Injection.Compositor = tt.compositor
+
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && config.Injection.Compositor != tt.want {
+				t.Errorf("Compositor = %q, want %q", config.Injection.Compositor, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_StartDelay(t *testing.T) {
+	config := createTestConfig()
+	config.Recording.StartDelay = 200 * time.Millisecond
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil for positive start_delay", err)
+	}
+	if got := config.ToRecordingConfig().StartDelay; got != 200*time.Millisecond {
+		t.Errorf("ToRecordingConfig().StartDelay = %v, want 200ms", got)
+	}
+
+	config.Recording.StartDelay = -time.Second
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject negative start_delay")
+	}
+}
+
+func TestConfig_TimeoutWarning(t *testing.T) {
+	base := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+`
+	config := loadTestConfigFile(t, base)
+	if config.Recording.TimeoutWarning != DefaultTimeoutWarning {
+		t.Errorf("TimeoutWarning = %v, want default %v", config.Recording.TimeoutWarning, DefaultTimeoutWarning)
+	}
+
+	config = loadTestConfigFile(t, base+"\n[recording]\ntimeout_warning = 0\n")
+	if config.Recording.TimeoutWarning != 0 {
+		t.Errorf("TimeoutWarning = %v, want 0 to disable the warning", config.Recording.TimeoutWarning)
+	}
+
+	for _, value := range []float64{-0.1, 1} {
+		config := createTestConfig()
+		config.Recording.TimeoutWarning = value
+		if err := config.Validate(); err == nil {
+			t.Errorf("Validate() should reject timeout_warning = %v", value)
+		}
+	}
+}
+
+func TestConfig_Validate_ClipboardMIME(t *testing.T) {
+	config := createTestConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := config.ToInjectionConfig().ClipboardMIME; got != injection.DefaultClipboardMIME {
+		t.Errorf("ClipboardMIME = %q, want default %q", got, injection.DefaultClipboardMIME)
+	}
+
+	config.Injection.ClipboardMIME = "text/plain"
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil for text/plain", err)
+	}
+	for _, mime := range []string{"utf-8", "text/plain;;"} {
+		config.Injection.ClipboardMIME = mime
+		if err := config.Validate(); err == nil {
+			t.Errorf("Validate() should reject clipboard_mime %q", mime)
+		}
+	}
+}
+
+func TestConfig_Validate_RecordingFormat(t *testing.T) {
+	for _, format := range []string{"s16", "s24", "s32", "f32"} {
+		config := createTestConfig()
+		config.Recording.Format = format
+		if err := config.Validate(); err != nil {
+			t.Errorf("Validate() with format %q error = %v, want nil", format, err)
+		}
+	}
+
+	config := createTestConfig()
+	config.Recording.Format = "f64"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "recording.format") {
+		t.Errorf("Validate() error = %v, want an unsupported recording.format error", err)
+	}
+}
+
+func TestConfig_Validate_ProcessingCommand(t *testing.T) {
+	config := createTestConfig()
+	config.Processing.Command = "tr a-z A-Z"
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v, want nil", err)
+	}
+	if config.Processing.CommandTimeout != DefaultCommandTimeout {
+		t.Errorf("CommandTimeout = %v, want default %v", config.Processing.CommandTimeout, DefaultCommandTimeout)
+	}
+
+	config.Processing.CommandTimeout = -time.Second
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject a negative processing.command_timeout")
+	}
+}
+
+func TestConfig_Validate_DefaultModel(t *testing.T) {
+	tests := []struct {
+		provider string
+		task     string
+		want     string
+	}{
+		{"openai", transcriber.TaskTranscribe, "whisper-1"},
+		{"openai", transcriber.TaskTranslate, "whisper-1"},
+		{"groq", transcriber.TaskTranscribe, "whisper-large-v3-turbo"},
+		{"groq", transcriber.TaskTranslate, "whisper-large-v3"},
+		{"groq-transcription", "", "whisper-large-v3-turbo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.task, func(t *testing.T) {
+			config := createTestConfig()
+			config.Transcription.Provider = tt.provider
+			config.Transcription.Task = tt.task
+			config.Transcription.Model = ""
+			if got := config.ToTranscriberConfig().Model; got != tt.want {
+				t.Errorf("ToTranscriberConfig().Model = %q, want %q", got, tt.want)
+			}
+			if err := config.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v, want the default model", err)
+			}
+			if config.Transcription.Model != tt.want {
+				t.Errorf("Model = %q, want %q", config.Transcription.Model, tt.want)
+			}
+		})
+	}
+
+	config := createTestConfig()
+	config.Transcription.Model = "whisper-2"
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should still reject an unknown non-empty model")
+	}
+}
+
+func TestConfig_APIKeyRef(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	original := lookupSecret
+	t.Cleanup(func() { lookupSecret = original })
+
+	var gotService, gotAccount string
+	lookupSecret = func(ctx context.Context, service, account string) (string, error) {
+		gotService, gotAccount = service, account
+		return "sk-from-keyring", nil
+	}
+	config := loadTestConfigFile(t, `[transcription]
+provider = "openai"
+api_key_ref = "keyring:hyprvoice/openai"
+model = "whisper-1"
+`)
+	if gotService != "hyprvoice" || gotAccount != "openai" {
+		t.Errorf("lookup service=%q account=%q, want hyprvoice/openai", gotService, gotAccount)
+	}
+	if got := config.ToTranscriberConfig().APIKey; got != "sk-from-keyring" {
+		t.Errorf("ToTranscriberConfig().APIKey = %q, want the keyring secret", got)
+	}
+	if config.Transcription.APIKey != "" {
+		t.Error("the keyring secret must not be copied into api_key, or saving would write it to disk")
+	}
+	valid := createTestConfig()
+	valid.Transcription.APIKey = ""
+	valid.Transcription.keyringKey = "sk-from-keyring"
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() with a keyring key error = %v, want nil", err)
+	}
+
+	// An explicit api_key wins without touching the keyring
+	gotService = ""
+	config = loadTestConfigFile(t, `[transcription]
+provider = "openai"
+api_key = "test-key"
+api_key_ref = "keyring:hyprvoice/openai"
+model = "whisper-1"
+`)
+	if gotService != "" || config.ToTranscriberConfig().APIKey != "test-key" {
+		t.Errorf("api_key should take precedence over api_key_ref")
+	}
+
+	// No keyring: warn and fall back to the environment
+	lookupSecret = func(ctx context.Context, service, account string) (string, error) {
+		return "", errors.New("secret-tool not found")
+	}
+	t.Setenv("OPENAI_API_KEY", "sk-from-env")
+	config = loadTestConfigFile(t, `[transcription]
+provider = "openai"
+api_key_ref = "keyring:hyprvoice/openai"
+model = "whisper-1"
+`)
+	if got := config.ToTranscriberConfig().APIKey; got != "sk-from-env" {
+		t.Errorf("ToTranscriberConfig().APIKey = %q, want the OPENAI_API_KEY fallback", got)
+	}
+
+	for _, ref := range []string{"hyprvoice/openai", "keyring:hyprvoice", "keyring:/openai", "keyring:hyprvoice/"} {
+		config := createTestConfig()
+		config.Transcription.APIKey = ""
+		config.Transcription.APIKeyRef = ref
+		if err := config.resolveKeyRefs(); err == nil {
+			t.Errorf("resolveKeyRefs() should reject %q", ref)
+		}
+	}
+}
+
+func TestConfig_Validate_DeviceFallback(t *testing.T) {
+	config := createTestConfig()
+	config.Recording.DeviceFallback = []string{"alsa_input.usb-headset", "alsa_input.pci-builtin"}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+	if got := config.ToRecordingConfig().DeviceFallback; len(got) != 2 || got[0] != "alsa_input.usb-headset" {
+		t.Errorf("ToRecordingConfig().DeviceFallback = %v, want the configured order", got)
+	}
+
+	config.Recording.DeviceFallback = []string{"alsa_input.usb-headset", " "}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject an empty device name")
+	}
+}
+
+func TestConfig_Validate_InjectionStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		want     string
+		wantErr  bool
+	}{
+		{name: "empty defaults to sequential", strategy: "", want: "sequential"},
+		{name: "sequential", strategy: "sequential", want: "sequential"},
+		{name: "parallel", strategy: "parallel", want: "parallel"},
+		{name: "unknown", strategy: "random", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.Injection.Strategy = tt.strategy
+
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && config.ToInjectionConfig().Strategy != tt.want {
+				t.Errorf("Strategy = %q, want %q", config.ToInjectionConfig().Strategy, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_HumanizeDelays(t *testing.T) {
+	config := createTestConfig()
+	config.Injection.Humanize = true
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if config.Injection.HumanizeMinDelay != 30*time.Millisecond || config.Injection.HumanizeMaxDelay != 120*time.Millisecond {
+		t.Errorf("humanize delays = %v-%v, want 30ms-120ms defaults", config.Injection.HumanizeMinDelay, config.Injection.HumanizeMaxDelay)
+	}
+
+	config.Injection.HumanizeMinDelay = 200 * time.Millisecond
+	config.Injection.HumanizeMaxDelay = 100 * time.Millisecond
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject humanize_min_delay above humanize_max_delay")
+	}
+}
+
+func TestConfig_LLMModelsPerLevel(t *testing.T) {
+	config := createTestConfig()
+	config.Processing.Mode = "llm"
+	config.LLM.APIKey = "test-key"
+	config.LLM.Model = "gpt-4o-mini"
+	config.LLM.Models = map[string]string{"thorough": "gpt-4o"}
+
+	for level, want := range map[string]string{"thorough": "gpt-4o", "minimal": "gpt-4o-mini"} {
+		config.LLM.Level = level
+		if err := config.Validate(); err != nil {
+			t.Fatalf("Validate() error = %v", err)
+		}
+		if got := config.ToLLMConfig().ModelForLevel(); got != want {
+			t.Errorf("ModelForLevel() for %s = %q, want %q", level, got, want)
+		}
+	}
+
+	config.LLM.Models = map[string]string{"extreme": "gpt-4o"}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject unknown levels in llm.models")
+	}
+}
+
+func TestConfig_Load_LLMModelsTable(t *testing.T) {
+	config := loadTestConfigFile(t, `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[processing]
+mode = "llm"
+
+[llm]
+api_key = "test-key"
+level = "thorough"
+
+[llm.models]
+thorough = "gpt-4o"
+`)
+	if got := config.ToLLMConfig().ModelForLevel(); got != "gpt-4o" {
+		t.Errorf("ModelForLevel() = %q, want %q", got, "gpt-4o")
+	}
+}
+
+func TestConfig_Validate_RecordingBackend(t *testing.T) {
+	for _, backend := range []string{"", "pipewire", "pulse", "auto"} {
+		config := createTestConfig()
+		config.Recording.Backend = backend
+		if err := config.Validate(); err != nil {
+			t.Errorf("Validate() with backend %q error = %v", backend, err)
+		}
+		if backend == "" && config.Recording.Backend != "pipewire" {
+			t.Errorf("Backend = %q, want pipewire default", config.Recording.Backend)
+		}
+	}
+
+	config := createTestConfig()
+	config.Recording.Backend = "alsa"
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject unknown recording.backend")
+	}
+}
+
+func TestConfig_Validate_ProviderModels(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		model    string
+		allow    bool
+		wantErr  bool
+	}{
+		{"openai whisper-1", "openai", "whisper-1", false, false},
+		{"openai typo", "openai", "whisper-2", false, true},
+		{"openai groq model", "openai", "whisper-large-v3", false, true},
+		{"groq model on openai allowed", "openai", "whisper-large-v3", true, false},
+		{"groq turbo", "groq", "whisper-large-v3-turbo", false, false},
+		{"groq openai model", "groq", "whisper-1", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.Transcription.Provider = tt.provider
+			config.Transcription.Model = tt.model
+			config.Transcription.AllowUnknownModel = tt.allow
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_LLMUnknownModel(t *testing.T) {
+	config := createTestConfig()
+	config.Processing.Mode = "llm"
+	config.LLM.APIKey = "test-key"
+	config.LLM.Model = "gpt-4o-mnii"
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject unknown llm.model")
+	}
+
+	config.LLM.Model = "gpt-4o-mini"
+	config.LLM.Models = map[string]string{"thorough": "my-finetune"}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject unknown llm.models values")
+	}
+
+	config.LLM.AllowUnknownModel = true
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate() with allow_unknown_model error = %v", err)
+	}
+
+	config.LLM.AllowUnknownModel = false
+	AllowUnknownModels(true)
+	defer AllowUnknownModels(false)
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate() with --allow-unknown-model error = %v", err)
+	}
+}
+
+func TestConfig_Validate_ToggleDuringInjection(t *testing.T) {
+	for _, value := range []string{"", "abort", "ignore", "abort-and-clear-clipboard"} {
+		config := createTestConfig()
+		config.Behavior.ToggleDuringInjection = value
+		if err := config.Validate(); err != nil {
+			t.Errorf("Validate() with %q error = %v", value, err)
+		}
+		if value == "" && config.Behavior.ToggleDuringInjection != ToggleInjectionAbort {
+			t.Errorf("ToggleDuringInjection = %q, want abort default", config.Behavior.ToggleDuringInjection)
+		}
+	}
+
+	config := createTestConfig()
+	config.Behavior.ToggleDuringInjection = "pause"
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject unknown behavior.toggle_during_injection")
+	}
+}
+
+func TestConfig_Validate_OnFocusChange(t *testing.T) {
+	for _, value := range []string{"", "ignore", "warn", "cancel"} {
+		config := createTestConfig()
+		config.Behavior.OnFocusChange = value
+		if err := config.Validate(); err != nil {
+			t.Errorf("Validate() with %q error = %v", value, err)
+		}
+		if value == "" && config.Behavior.OnFocusChange != FocusChangeIgnore {
+			t.Errorf("OnFocusChange = %q, want ignore default", config.Behavior.OnFocusChange)
+		}
+	}
+
+	config := createTestConfig()
+	config.Behavior.OnFocusChange = "pause"
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject unknown behavior.on_focus_change")
+	}
+}
+
+func TestConfig_Validate_ClipboardSelection(t *testing.T) {
+	for _, value := range []string{"", "clipboard", "primary", "both"} {
+		config := createTestConfig()
+		config.Injection.ClipboardSelection = value
+		if err := config.Validate(); err != nil {
+			t.Errorf("Validate() with %q error = %v", value, err)
+		}
+		if value == "" && config.Injection.ClipboardSelection != "clipboard" {
+			t.Errorf("ClipboardSelection = %q, want clipboard default", config.Injection.ClipboardSelection)
+		}
+	}
+
+	config := createTestConfig()
+	config.Injection.ClipboardSelection = "secondary"
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject unknown injection.clipboard_selection")
+	}
+}
+
+func TestConfig_Validate_FileBackend(t *testing.T) {
+	config := createTestConfig()
+	config.Injection.Backends = []string{"file"}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should require injection.file_path for the file backend")
+	}
+
+	config.Injection.FilePath = "/tmp/hyprvoice.txt"
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if config.Injection.FileMode != "append" {
+		t.Errorf("FileMode = %q, want append default", config.Injection.FileMode)
+	}
+	if got := config.ToInjectionConfig().FilePath; got != "/tmp/hyprvoice.txt" {
+		t.Errorf("ToInjectionConfig().FilePath = %q, want /tmp/hyprvoice.txt", got)
+	}
+
+	config.Injection.FileMode = "prepend"
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject unknown injection.file_mode")
+	}
+}
+
+func TestConfig_RepetitionFilter(t *testing.T) {
+	base := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+`
+	config := loadTestConfigFile(t, base)
+	if !config.Transcription.RepetitionFilter {
+		t.Error("RepetitionFilter = false, want true when repetition_filter is not set")
+	}
+
+	defaults := createTestConfig()
+	if err := defaults.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if defaults.Transcription.MaxRepetitions != 3 {
+		t.Errorf("MaxRepetitions = %d, want default 3", defaults.Transcription.MaxRepetitions)
+	}
+
+	config = loadTestConfigFile(t, base+"repetition_filter = false\nmax_repetitions = 5\n")
+	if config.Transcription.RepetitionFilter || config.Transcription.MaxRepetitions != 5 {
+		t.Errorf("RepetitionFilter = %v, MaxRepetitions = %d, want false and 5", config.Transcription.RepetitionFilter, config.Transcription.MaxRepetitions)
+	}
+
+	invalid := createTestConfig()
+	invalid.Transcription.MaxRepetitions = -1
+	if err := invalid.Validate(); err == nil {
+		t.Error("Validate() should reject negative transcription.max_repetitions")
+	}
+}
+
+func TestConfig_Validate_PreAndPostKeys(t *testing.T) {
+	config := createTestConfig()
+	config.Injection.PreKeys = []string{"i"}
+	config.Injection.PostKeys = []string{"Escape", "ctrl+s"}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	injectionConfig := config.ToInjectionConfig()
+	if len(injectionConfig.PreKeys) != 1 || len(injectionConfig.PostKeys) != 2 {
+		t.Errorf("ToInjectionConfig() keys = %v / %v", injectionConfig.PreKeys, injectionConfig.PostKeys)
+	}
+
+	config.Injection.PostKeys = []string{"ctrl+"}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject key combos with empty parts")
+	}
+}
+
+func TestConfig_Validate_MaxCommandLength(t *testing.T) {
+	config := createTestConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if config.Behavior.MaxCommandLength != 65536 {
+		t.Errorf("MaxCommandLength = %d, want default 65536", config.Behavior.MaxCommandLength)
+	}
+
+	config = createTestConfig()
+	config.Behavior.MaxCommandLength = 10
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject max_command_length below 64")
+	}
+}
+
+func TestConfig_Validate_ToggleDebounce(t *testing.T) {
+	config := createTestConfig()
+	config.Behavior.ToggleDebounce = 200 * time.Millisecond
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil for positive toggle_debounce", err)
+	}
+
+	config.Behavior.ToggleDebounce = -time.Millisecond
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject negative toggle_debounce")
+	}
+}
+
+func TestConfig_Validate_ResponseFormat(t *testing.T) {
+	tests := []struct {
+		provider string
+		model    string // Empty uses the provider's first model
+		format   string
+		wantErr  bool
+	}{
+		{"openai", "", "", false},
+		{"openai", "", "srt", false},
+		{"openai", "", "vtt", false},
+		{"groq", "", "verbose_json", false},
+		{"groq", "", "srt", true},
+		{"openai", "", "docx", true},
+		{"openai", "gpt-4o-transcribe", "json", false},
+		{"openai", "gpt-4o-transcribe", "verbose_json", true},
+		{"openai", "gpt-4o-mini-transcribe", "srt", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.model+"/"+tt.format, func(t *testing.T) {
+			config := createTestConfig()
+			config.Transcription.Provider = tt.provider
+			config.Transcription.Model = tt.model
+			if tt.model == "" {
+				config.Transcription.Model = transcriber.SupportedModels[tt.provider][transcriber.TaskTranscribe][0]
+			}
+			config.Transcription.ResponseFormat = tt.format
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && config.ToTranscriberConfig().ResponseFormat != "" {
+				t.Error("ToTranscriberConfig() should leave ResponseFormat empty for dictation")
+			}
+		})
+	}
+}
+
+func TestConfig_RapidMode(t *testing.T) {
+	base := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+`
+	config := loadTestConfigFile(t, base)
+	if config.Behavior.RapidMode {
+		t.Error("RapidMode = true, want false when rapid_mode is not set")
+	}
+
+	config = loadTestConfigFile(t, base+"\n[behavior]\nrapid_mode = true\n")
+	if !config.Behavior.RapidMode {
+		t.Error("RapidMode = false, want true")
+	}
+}
+
+func TestConfig_AutostartRecording(t *testing.T) {
+	base := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+`
+	config := loadTestConfigFile(t, base)
+	if config.Behavior.AutostartRecording {
+		t.Error("AutostartRecording = true, want false when autostart_recording is not set")
+	}
+
+	config = loadTestConfigFile(t, base+"\n[behavior]\nautostart_recording = true\n")
+	if !config.Behavior.AutostartRecording {
+		t.Error("AutostartRecording = false, want true")
+	}
+}
+
+func TestConfig_Validate_NotificationUrgency(t *testing.T) {
+	config := createTestConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if config.Notifications.ErrorUrgency != "critical" || config.Notifications.InfoUrgency != "normal" {
+		t.Errorf("urgencies = %q / %q, want critical / normal defaults", config.Notifications.ErrorUrgency, config.Notifications.InfoUrgency)
+	}
+
+	config = createTestConfig()
+	config.Notifications.InfoUrgency = "urgent"
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject unknown notifications.info_urgency")
+	}
+
+	config = createTestConfig()
+	config.Notifications.ErrorTimeout = -time.Second
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject negative notifications.error_timeout")
+	}
+}
+
+func TestConfig_Validate_RetriesPerBackend(t *testing.T) {
+	config := createTestConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if config.Injection.RetriesPerBackend != 1 {
+		t.Errorf("RetriesPerBackend = %d, want default 1", config.Injection.RetriesPerBackend)
+	}
+
+	config = createTestConfig()
+	config.Injection.RetriesPerBackend = -2
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject negative injection.retries_per_backend")
+	}
+}
+
+func TestConfig_PrefixAndSuffix(t *testing.T) {
+	config := loadTestConfigFile(t, `[injection]
+prefix = "- "
+suffix = "\n"
+`)
+	if config.Injection.Prefix != "- " || config.Injection.Suffix != "\n" {
+		t.Errorf("Prefix = %q, Suffix = %q, want \"- \" and a newline", config.Injection.Prefix, config.Injection.Suffix)
+	}
+}
+
+func TestConfig_TranscriptionPrompts(t *testing.T) {
+	config := createTestConfig()
+	config.Transcription.Prompt = "Kubernetes, Hyprland"
+	config.Transcription.Prompts = map[string]string{"it": "Riunione su Kubernetes"}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"", "Kubernetes, Hyprland"},
+		{"en", "Kubernetes, Hyprland"},
+		{"it", "Riunione su Kubernetes"},
+	}
+	for _, tt := range tests {
+		config.Transcription.Language = tt.language
+		if got := config.ToTranscriberConfig().Prompt; got != tt.want {
+			t.Errorf("Prompt for language %q = %q, want %q", tt.language, got, tt.want)
+		}
+	}
+
+	config.Transcription.Language = ""
+	config.Transcription.Prompts = map[string]string{"italian": "Riunione"}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject transcription.prompts keys that are not language codes")
+	}
+}
+
+func TestConfig_ProcessingPipeline(t *testing.T) {
+	config := createTestConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if !reflect.DeepEqual(config.Processing.Pipeline, DefaultProcessingPipeline) {
+		t.Errorf("Pipeline = %v, want default %v", config.Processing.Pipeline, DefaultProcessingPipeline)
+	}
+
+	tests := []struct {
+		name     string
+		pipeline []string
+		wantErr  bool
+	}{
+		{"reordered", []string{"snippets", "replace"}, false},
+		{"empty", []string{}, false},
+		{"unknown stage", []string{"replace", "spellcheck"}, true},
+		{"duplicate stage", []string{"llm", "llm"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.Processing.Pipeline = tt.pipeline
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	config = createTestConfig()
+	config.Processing.Replacements = map[string]string{" ": "x"}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject an empty replacement word")
+	}
+}
+
+func TestConfig_ProcessingTables(t *testing.T) {
+	config := loadTestConfigFile(t, `[processing]
+pipeline = []
+
+[processing.replacements]
+"hyper voice" = "Hyprvoice"
+
+[processing.snippets]
+"my email" = "jane@example.com"
+`)
+	if config.Processing.Pipeline == nil || len(config.Processing.Pipeline) != 0 {
+		t.Errorf("Pipeline = %#v, want an explicit empty list", config.Processing.Pipeline)
+	}
+	if got := config.Processing.Replacements["hyper voice"]; got != "Hyprvoice" {
+		t.Errorf("Replacements[\"hyper voice\"] = %q, want %q", got, "Hyprvoice")
+	}
+	if got := config.Processing.Snippets["my email"]; got != "jane@example.com" {
+		t.Errorf("Snippets[\"my email\"] = %q, want %q", got, "jane@example.com")
+	}
+}
+
+func TestConfig_EnvOverrides(t *testing.T) {
+	t.Setenv("HYPRVOICE_TRANSCRIPTION_PROVIDER", "groq")
+	t.Setenv("HYPRVOICE_RECORDING_SAMPLE_RATE", "48000")
+	t.Setenv("HYPRVOICE_INJECTION_WTYPE_TIMEOUT", "2s")
+	t.Setenv("HYPRVOICE_INJECTION_FOCUS_WINDOW", "false")
+	t.Setenv("HYPRVOICE_INJECTION_BACKENDS", "wtype, clipboard")
+
+	config := loadTestConfigFile(t, `[transcription]
+provider = "openai"
+
+[recording]
+sample_rate = 16000
+
+[injection]
+backends = ["ydotool"]
+`)
+	if config.Transcription.Provider != "groq" {
+		t.Errorf("Provider = %q, want %q", config.Transcription.Provider, "groq")
+	}
+	if config.Recording.SampleRate != 48000 {
+		t.Errorf("SampleRate = %d, want 48000", config.Recording.SampleRate)
+	}
+	if config.Injection.WtypeTimeout != 2*time.Second {
+		t.Errorf("WtypeTimeout = %v, want 2s", config.Injection.WtypeTimeout)
+	}
+	if config.Injection.FocusWindowEnabled() {
+		t.Error("FocusWindowEnabled() = true, want false from the environment")
+	}
+	if !reflect.DeepEqual(config.Injection.Backends, []string{"wtype", "clipboard"}) {
+		t.Errorf("Backends = %v, want [wtype clipboard]", config.Injection.Backends)
+	}
+}
+
+func TestConfig_EnvOverridesInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"HYPRVOICE_RECORDING_SAMPLE_RATE", "fast"},
+		{"HYPRVOICE_INJECTION_FOCUS_WINDOW", "maybe"},
+		{"HYPRVOICE_RECORDING_TIMEOUT", "5 minutes"},
+		{"HYPRVOICE_LLM_MODELS", "thorough=gpt-4o"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+			var config Config
+			if err := config.applyEnvOverrides(); err == nil || !strings.Contains(err.Error(), tt.name) {
+				t.Errorf("applyEnvOverrides() error = %v, want one naming %s", err, tt.name)
+			}
+		})
+	}
+}
+
+func TestConfig_LLMMinOutputRatio(t *testing.T) {
+	for _, ratio := range []float64{0, 0.5} {
+		config := createTestConfig()
+		config.Processing.Mode = "llm"
+		config.LLM.APIKey = "test-key"
+		config.LLM.MinOutputRatio = ratio
+		if err := config.Validate(); err != nil {
+			t.Errorf("Validate() with min_output_ratio %g error = %v", ratio, err)
+		}
+	}
+	for _, ratio := range []float64{-0.1, 1} {
+		config := createTestConfig()
+		config.Processing.Mode = "llm"
+		config.LLM.APIKey = "test-key"
+		config.LLM.MinOutputRatio = ratio
+		if err := config.Validate(); err == nil {
+			t.Errorf("Validate() should reject min_output_ratio %g", ratio)
+		}
+	}
+
+	config := loadTestConfigFile(t, `[llm]
+min_output_ratio = 0.4
+`)
+	if config.LLM.MinOutputRatio != 0.4 {
+		t.Errorf("MinOutputRatio = %g, want 0.4", config.LLM.MinOutputRatio)
+	}
+}
+
+func TestConfig_InjectionTimeoutScaling(t *testing.T) {
+	config := loadTestConfigFile(t, `[injection]
+backends = ["ydotool"]
+`)
+	if config.Injection.TimeoutPerChar != injection.DefaultTimeoutPerChar {
+		t.Errorf("TimeoutPerChar = %v, want default %v", config.Injection.TimeoutPerChar, injection.DefaultTimeoutPerChar)
+	}
+
+	config = loadTestConfigFile(t, `[injection]
+timeout_per_char = "0s"
+`)
+	if config.Injection.TimeoutPerChar != 0 {
+		t.Errorf("TimeoutPerChar = %v, want 0 when disabled in the file", config.Injection.TimeoutPerChar)
+	}
+
+	config = createTestConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if config.Injection.MaxTimeout != injection.DefaultMaxTimeout {
+		t.Errorf("MaxTimeout = %v, want default %v", config.Injection.MaxTimeout, injection.DefaultMaxTimeout)
+	}
+
+	config = createTestConfig()
+	config.Injection.TimeoutPerChar = -time.Millisecond
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject a negative timeout_per_char")
+	}
+}
+
+func TestConfig_Validate_Realtime(t *testing.T) {
+	tests := []struct {
+		provider string
+		task     string
+		wantErr  bool
+	}{
+		{"openai", transcriber.TaskTranscribe, false},
+		{"openai", transcriber.TaskTranslate, true},
+		{"groq", transcriber.TaskTranscribe, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.task, func(t *testing.T) {
+			config := createTestConfig()
+			config.Transcription.Provider = tt.provider
+			config.Transcription.Task = tt.task
+			config.Transcription.Model = ""
+			config.Transcription.Realtime = true
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "transcription.realtime") {
+				t.Errorf("Validate() error = %v, want it to name transcription.realtime", err)
+			}
+		})
+	}
+}
+
+func TestConfig_Load_FallbackToRaw(t *testing.T) {
+	config := loadTestConfigFile(t, "[llm]\nmodel = \"gpt-4o-mini\"\n")
+	if !config.LLM.FallbackToRaw {
+		t.Error("FallbackToRaw = false, want true when llm.fallback_to_raw is not set")
+	}
+
+	config = loadTestConfigFile(t, "[llm]\nfallback_to_raw = false\n")
+	if config.LLM.FallbackToRaw {
+		t.Error("FallbackToRaw = true, want false from the file")
+	}
+}
+
+func TestConfig_WindowCapture(t *testing.T) {
+	config := loadTestConfigFile(t, "[injection]\ncompositor = \"hyprland\"\n")
+	if config.Injection.WindowRetries != injection.DefaultWindowRetries {
+		t.Errorf("WindowRetries = %d, want default %d", config.Injection.WindowRetries, injection.DefaultWindowRetries)
+	}
+	config = loadTestConfigFile(t, "[injection]\nwindow_retries = 0\n")
+	if config.Injection.WindowRetries != 0 {
+		t.Errorf("WindowRetries = %d, want 0 from the file", config.Injection.WindowRetries)
+	}
+
+	valid := createTestConfig()
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if valid.Injection.WindowRetryDelay != injection.DefaultWindowRetryDelay {
+		t.Errorf("WindowRetryDelay = %v, want default %v", valid.Injection.WindowRetryDelay, injection.DefaultWindowRetryDelay)
+	}
+
+	negative := createTestConfig()
+	negative.Injection.WindowRetries = -1
+	if err := negative.Validate(); err == nil || !strings.Contains(err.Error(), "injection.window_retries") {
+		t.Errorf("Validate() error = %v, want invalid injection.window_retries", err)
+	}
+	negative = createTestConfig()
+	negative.Injection.WindowRetryDelay = -time.Millisecond
+	if err := negative.Validate(); err == nil || !strings.Contains(err.Error(), "injection.window_retry_delay") {
+		t.Errorf("Validate() error = %v, want invalid injection.window_retry_delay", err)
+	}
+}
+
+func TestConfig_TranscriptionStart(t *testing.T) {
+	config := createTestConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if config.Transcription.Start != TranscriptionStartOnRecord {
+		t.Errorf("Start = %q, want default %q", config.Transcription.Start, TranscriptionStartOnRecord)
+	}
+	if config.ToTranscriberConfig().Deferred {
+		t.Error("Deferred = true, want false for on_record")
+	}
+
+	config.Transcription.Start = TranscriptionStartOnInject
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if !config.ToTranscriberConfig().Deferred {
+		t.Error("Deferred = false, want true for on_inject")
+	}
+
+	config.Transcription.Realtime = true
+	config.Transcription.Model = "gpt-4o-transcribe"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "transcription.start") {
+		t.Errorf("Validate() error = %v, want invalid transcription.start with realtime", err)
+	}
+
+	config = createTestConfig()
+	config.Transcription.Start = "later"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "transcription.start") {
+		t.Errorf("Validate() error = %v, want invalid transcription.start", err)
+	}
+}
+
+func TestConfig_AzureOpenAI(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_API_KEY", "")
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+
+	config := createTestConfig()
+	config.Transcription.Provider = "azure-openai"
+	config.Transcription.Model = "my-whisper"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "endpoint") {
+		t.Errorf("Validate() error = %v, want missing endpoint", err)
+	}
+
+	config.Transcription.Endpoint = "https://myresource.openai.azure.com"
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if config.Transcription.APIVersion != DefaultAzureAPIVersion {
+		t.Errorf("APIVersion = %q, want default %q", config.Transcription.APIVersion, DefaultAzureAPIVersion)
+	}
+	tc := config.ToTranscriberConfig()
+	if tc.Endpoint != config.Transcription.Endpoint || tc.APIVersion != DefaultAzureAPIVersion || tc.Model != "my-whisper" {
+		t.Errorf("ToTranscriberConfig() = %+v, want the Azure endpoint, version and deployment", tc)
+	}
+
+	// The endpoint and key can come from the environment instead
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "https://env.openai.azure.com")
+	t.Setenv("AZURE_OPENAI_API_KEY", "env-key")
+	config.Transcription.Endpoint = ""
+	config.Transcription.APIKey = ""
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() with env error = %v", err)
+	}
+	if tc := config.ToTranscriberConfig(); tc.Endpoint != "https://env.openai.azure.com" || tc.APIKey != "env-key" {
+		t.Errorf("ToTranscriberConfig() endpoint = %q, key = %q, want the environment values", tc.Endpoint, tc.APIKey)
+	}
+
+	config.Transcription.Model = ""
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "transcription.model") {
+		t.Errorf("Validate() error = %v, want invalid transcription.model", err)
+	}
+
+	llmConfig := createTestConfig()
+	llmConfig.Processing.Mode = "llm"
+	llmConfig.LLM.Provider = "azure-openai"
+	llmConfig.LLM.APIKey = "azure-key"
+	llmConfig.LLM.Model = "cleanup-deployment"
+	if err := llmConfig.Validate(); err != nil {
+		t.Fatalf("Validate() llm error = %v", err)
+	}
+	if c := llmConfig.ToLLMConfig(); c.Endpoint != "https://env.openai.azure.com" || c.APIVersion != DefaultAzureAPIVersion {
+		t.Errorf("ToLLMConfig() endpoint = %q, version = %q", c.Endpoint, c.APIVersion)
+	}
+}
+
+func TestConfig_FocusCommand(t *testing.T) {
+	config := createTestConfig()
+	config.Injection.FocusCommand = "hyprctl dispatch focuswindow address:{addr}"
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := config.ToInjectionConfig().FocusCommand; got != config.Injection.FocusCommand {
+		t.Errorf("ToInjectionConfig().FocusCommand = %q", got)
+	}
+
+	config.Injection.FocusCommand = "hyprctl dispatch focuswindow"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "injection.focus_command") {
+		t.Errorf("Validate() error = %v, want invalid injection.focus_command", err)
+	}
+
+	config.Injection.FocusCommand = "focus {addr}"
+	config.Injection.Compositor = "generic"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "generic") {
+		t.Errorf("Validate() error = %v, want focus_command rejected on the generic compositor", err)
+	}
+}
+
+func TestConfig_PreInjectDelay(t *testing.T) {
+	config := createTestConfig()
+	config.Injection.PreInjectDelay = 150 * time.Millisecond
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := config.ToInjectionConfig().PreInjectDelay; got != 150*time.Millisecond {
+		t.Errorf("ToInjectionConfig().PreInjectDelay = %v, want 150ms", got)
+	}
+
+	config.Injection.PreInjectDelay = -time.Millisecond
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "injection.pre_inject_delay") {
+		t.Errorf("Validate() error = %v, want invalid injection.pre_inject_delay", err)
+	}
+}
+
+func TestConfig_KeyboardLayout(t *testing.T) {
+	config := createTestConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if config.Injection.KeyboardLayout != "us" {
+		t.Errorf("KeyboardLayout = %q, want default \"us\"", config.Injection.KeyboardLayout)
+	}
+
+	config.Injection.KeyboardLayout = "de"
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := config.ToInjectionConfig().KeyboardLayout; got != "de" {
+		t.Errorf("ToInjectionConfig().KeyboardLayout = %q, want \"de\"", got)
+	}
+
+	config.Injection.KeyboardLayout = "German"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "injection.keyboard_layout") {
+		t.Errorf("Validate() error = %v, want invalid injection.keyboard_layout", err)
+	}
+}
+
+func TestConfig_WithTranscriptionOverride(t *testing.T) {
+	t.Setenv("GROQ_API_KEY", "")
+	t.Setenv("AZURE_OPENAI_API_KEY", "")
+	config := createTestConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if _, err := config.WithTranscriptionOverride("groq", ""); err == nil || !strings.Contains(err.Error(), "GROQ_API_KEY") {
+		t.Errorf("WithTranscriptionOverride(groq) without a key error = %v, want GROQ_API_KEY hint", err)
+	}
+
+	t.Setenv("GROQ_API_KEY", "groq-key")
+	overridden, err := config.WithTranscriptionOverride("groq", "")
+	if err != nil {
+		t.Fatalf("WithTranscriptionOverride(groq) error = %v", err)
+	}
+	tc := overridden.ToTranscriberConfig()
+	if tc.Provider != "groq" || tc.Model != "whisper-large-v3-turbo" || tc.APIKey != "groq-key" {
+		t.Errorf("ToTranscriberConfig() = %s %s key %q, want groq whisper-large-v3-turbo key from the environment", tc.Provider, tc.Model, tc.APIKey)
+	}
+	if config.Transcription.Provider != "openai" {
+		t.Errorf("WithTranscriptionOverride() changed the original provider to %s", config.Transcription.Provider)
+	}
+
+	if _, err := config.WithTranscriptionOverride("groq", "whisper-1"); err == nil {
+		t.Error("WithTranscriptionOverride() should reject a model the provider does not serve")
+	}
+	if _, err := config.WithTranscriptionOverride("anthropic", ""); err == nil {
+		t.Error("WithTranscriptionOverride() should reject an unknown provider")
+	}
+	if _, err := config.WithTranscriptionOverride("", "gpt-4o-transcribe"); err != nil {
+		t.Errorf("WithTranscriptionOverride(model only) error = %v", err)
+	}
+}
+
+func TestConfig_MergeWindow(t *testing.T) {
+	config := createTestConfig()
+	config.Behavior.MergeWindow = 10 * time.Second
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	config.Behavior.MergeWindow = -time.Second
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "behavior.merge_window") {
+		t.Errorf("Validate() error = %v, want invalid behavior.merge_window", err)
+	}
+}
+
+func TestConfig_ConfirmTimeout(t *testing.T) {
+	config := createTestConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if config.Behavior.ConfirmTimeout != DefaultConfirmTimeout {
+		t.Errorf("ConfirmTimeout = %v, want default %v", config.Behavior.ConfirmTimeout, DefaultConfirmTimeout)
+	}
+
+	config.Behavior.ConfirmTimeout = -time.Second
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "behavior.confirm_timeout") {
+		t.Errorf("Validate() error = %v, want invalid behavior.confirm_timeout", err)
+	}
+}
+
+func TestConfig_FinalizeTimeout(t *testing.T) {
+	config := createTestConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if config.Transcription.FinalizeTimeout != DefaultFinalizeTimeout {
+		t.Errorf("FinalizeTimeout = %v, want default %v", config.Transcription.FinalizeTimeout, DefaultFinalizeTimeout)
+	}
+
+	config.Transcription.FinalizeTimeout = -time.Second
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "transcription.finalize_timeout") {
+		t.Errorf("Validate() error = %v, want invalid transcription.finalize_timeout", err)
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix starts every config override variable, e.g. HYPRVOICE_TRANSCRIPTION_PROVIDER
+const EnvPrefix = "HYPRVOICE_"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// EnvName returns the override variable for a section and key, e.g. ("injection", "wtype_timeout")
+// becomes HYPRVOICE_INJECTION_WTYPE_TIMEOUT
+func EnvName(section, key string) string {
+	return EnvPrefix + strings.ToUpper(section+"_"+key)
+}
+
+// applyEnvOverrides sets every config value that has a HYPRVOICE_<SECTION>_<KEY> variable,
+// so the environment wins over the file. Lists are comma-separated; tables such as
+// [llm.models] can only be set in the file.
+func (c *Config) applyEnvOverrides() error {
+	sections := reflect.ValueOf(c).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Type().Field(i).Tag.Get("toml")
+		fields := sections.Field(i)
+		for j := 0; j < fields.NumField(); j++ {
+			key := fields.Type().Field(j).Tag.Get("toml")
+			if key == "" {
+				continue
+			}
+			name := EnvName(section, key)
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				continue
+			}
+			if err := setFromEnv(fields.Field(j), value); err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			log.Printf("Config: %s.%s overridden by %s", section, key, name)
+		}
+	}
+	return nil
+}
+
+func setFromEnv(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Pointer:
+		// Optional booleans such as focus_window, where nil means the default
+		if field.Type().Elem().Kind() != reflect.Bool {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(&b))
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", field.Type())
+		}
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("%s values can only be set in the config file", field.Kind())
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// KeyringPrefix marks an api_key_ref stored in the system keyring, e.g. "keyring:hyprvoice/openai"
+const KeyringPrefix = "keyring:"
+
+// lookupSecret reads a secret from the Secret Service keyring (GNOME Keyring, KWallet, KeePassXC)
+// via secret-tool; swapped out in tests
+var lookupSecret = func(ctx context.Context, service, account string) (string, error) {
+	lookupCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(lookupCtx, "secret-tool", "lookup", "service", service, "account", account).Output()
+	if err != nil {
+		return "", fmt.Errorf("secret-tool lookup failed: %w", err)
+	}
+	secret := strings.TrimSpace(string(output))
+	if secret == "" {
+		return "", fmt.Errorf("no secret stored for service=%s account=%s", service, account)
+	}
+	return secret, nil
+}
+
+// parseKeyringRef splits "keyring:<service>/<account>" into its parts
+func parseKeyringRef(ref string) (service, account string, err error) {
+	rest, ok := strings.CutPrefix(ref, KeyringPrefix)
+	if !ok {
+		return "", "", fmt.Errorf("%q must start with %q", ref, KeyringPrefix)
+	}
+	service, account, ok = strings.Cut(rest, "/")
+	if !ok || service == "" || account == "" {
+		return "", "", fmt.Errorf("%q must look like %s<service>/<account>", ref, KeyringPrefix)
+	}
+	return service, account, nil
+}
+
+// resolveKeyRefs fills the transcription key from api_key_ref when api_key is not set.
+// A missing keyring only logs a warning, so the environment variable fallback and the
+// "API key required" check in Validate still apply.
+func (c *Config) resolveKeyRefs() error {
+	t := &c.Transcription
+	if t.APIKeyRef == "" || t.APIKey != "" {
+		return nil
+	}
+	service, account, err := parseKeyringRef(t.APIKeyRef)
+	if err != nil {
+		return fmt.Errorf("invalid transcription.api_key_ref: %w", err)
+	}
+	secret, err := lookupSecret(context.Background(), service, account)
+	if err != nil {
+		log.Printf("Config: cannot read transcription.api_key_ref from keyring: %v", err)
+		return nil
+	}
+	t.keyringKey = secret
+	log.Printf("Config: transcription API key loaded from keyring (%s/%s)", service, account)
+	return nil
+}
+
+// apiKey returns the configured key, or the one resolved from the keyring. The keyring
+// secret is kept out of APIKey so saving the config never writes it to disk.
+func (t TranscriptionConfig) apiKey() string {
+	if t.APIKey != "" {
+		return t.APIKey
+	}
+	return t.keyringKey
+}
@@ -160,6 +160,11 @@ func (m *Manager) reloadConfig() {
 	log.Printf("Config manager: configuration successfully reloaded")
 }
 
+// Reload re-reads the config file right away, as if it had changed on disk
+func (m *Manager) Reload() {
+	m.reloadConfig()
+}
+
 func (m *Manager) SetOnConfigReload(onConfigReload func()) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -5,19 +5,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
-	"os/exec"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
+	"unicode"
 
 	"github.com/leonardotrapani/hyprvoice/internal/bus"
 	"github.com/leonardotrapani/hyprvoice/internal/config"
+	"github.com/leonardotrapani/hyprvoice/internal/injection"
+	"github.com/leonardotrapani/hyprvoice/internal/llm"
 	"github.com/leonardotrapani/hyprvoice/internal/notify"
 	"github.com/leonardotrapani/hyprvoice/internal/pipeline"
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
 )
 
 type Daemon struct {
@@ -32,18 +38,30 @@ type Daemon struct {
 
 	wg sync.WaitGroup
 
-	modeOverride string // Runtime mode override ("raw", "llm", or "" for config default)
+	startedAt  time.Time
+	lastToggle time.Time // When the last toggle was accepted, for behavior.toggle_debounce
+
+	lastTranscription time.Duration        // How long the last finished transcription took (0 = none yet)
+	lastError         pipeline.ErrorKind   // Kind of the last error in the latest dictation ("" = none)
+	merge             *pipeline.MergeState // Last injected block, continued by dictations within behavior.merge_window
+
+	modeOverride     string // Runtime mode override ("raw", "llm", or "" for config default)
+	configMode       string // processing.mode as of the last load, to spot edits on reload
+	continueOverride string // Runtime continue-sentence override ("on", "off", or "" for config default)
+	languageOverride string // Runtime language override ("auto", a language code, or "" for config default)
+	levelOverride    string // Runtime LLM level override ("minimal", "moderate", "thorough", "custom", or "" for config default)
+	providerOverride string // Runtime transcription provider override ("" for config default)
+	modelOverride    string // Runtime transcription model override ("" for the provider's configured or default model)
 }
 
 func New() (*Daemon, error) {
 	configMgr, err := config.NewManager()
-
-	conf := configMgr.GetConfig()
-
 	if err != nil {
 		return nil, fmt.Errorf("failed to create config manager: %w", err)
 	}
 
+	conf := configMgr.GetConfig()
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	n := notify.GetNotifierBasedOnConfig(conf)
@@ -53,6 +71,10 @@ func New() (*Daemon, error) {
 		configMgr: configMgr,
 		ctx:       ctx,
 		cancel:    cancel,
+		startedAt: time.Now(),
+		merge:     &pipeline.MergeState{},
+
+		configMode: conf.Processing.Mode,
 	}
 
 	return d, nil
@@ -64,8 +86,18 @@ func (d *Daemon) onConfigReload() {
 
 	d.notifier.Notify("Hyprvoice", "Config Reloaded")
 
+	cfg := d.configMgr.GetConfig()
 	d.mu.Lock()
-	d.notifier = notify.GetNotifierBasedOnConfig(d.configMgr.GetConfig())
+	d.notifier = notify.GetNotifierBasedOnConfig(cfg)
+	// Editing processing.mode is an explicit choice, so it replaces a session override.
+	// Any other edit leaves the override in place.
+	if cfg.Processing.Mode != d.configMode {
+		if d.modeOverride != "" {
+			log.Printf("Daemon: processing.mode changed to %s, clearing session mode %s", cfg.Processing.Mode, d.modeOverride)
+			d.modeOverride = ""
+		}
+		d.configMode = cfg.Processing.Mode
+	}
 	d.mu.Unlock()
 }
 
@@ -107,20 +139,25 @@ func (d *Daemon) Run() error {
 	}
 	defer bus.RemovePidFile()
 
+	// Surface a missing audio stack at startup instead of on the first toggle
+	_, audioErr := recording.ResolveBackend(d.ctx, d.configMgr.GetConfig().Recording.Backend)
+	if audioErr != nil {
+		log.Printf("Warning: %v", audioErr)
+		d.notifier.Error(audioErr.Error())
+	}
+
+	d.writeStateFile(pipeline.Idle)
+	defer d.removeStateFile()
+
 	if err := d.configMgr.StartWatching(d.ctx); err != nil {
 		log.Printf("Warning: failed to start config file watching: %v", err)
 	}
 	defer d.configMgr.Stop()
 
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP, syscall.SIGUSR1)
 	defer signal.Stop(sigCh)
-
-	go func() {
-		sig := <-sigCh
-		log.Printf("Received signal %v, shutting down gracefully", sig)
-		d.cancel()
-	}()
+	go d.handleSignals(sigCh)
 
 	go func() {
 		<-d.ctx.Done()
@@ -131,6 +168,16 @@ func (d *Daemon) Run() error {
 
 	log.Printf("Daemon started, listening on socket")
 
+	if d.configMgr.GetConfig().Behavior.AutostartRecording {
+		if audioErr != nil {
+			log.Printf("Daemon: Not auto-starting recording without an audio backend")
+		} else {
+			// Same path as a client toggle, so toggle, cancel and rapid_mode behave as usual
+			log.Printf("Daemon: Auto-starting recording")
+			d.toggle("")
+		}
+	}
+
 	for {
 		c, err := ln.Accept()
 		if err != nil {
@@ -151,82 +198,327 @@ func (d *Daemon) handle(c net.Conn) {
 	defer c.Close()
 	defer d.wg.Done()
 
-	line, err := bufio.NewReader(c).ReadString('\n')
+	// Bound the line so a client flooding bytes without a newline can't grow the buffer forever
+	maxLength := d.maxCommandLength()
+	line, err := bufio.NewReader(io.LimitReader(c, int64(maxLength))).ReadString('\n')
+	if err != nil && len(line) >= maxLength {
+		log.Printf("Client command exceeded %d bytes, rejecting", maxLength)
+		reply(c, bus.KindErr, "code", "too_long")
+		return
+	}
 	if err != nil {
 		log.Printf("Client read error: %v", err)
-		fmt.Fprintf(c, "ERR read_error: %v\n", err)
+		reply(c, bus.KindErr, "code", "read_error", "message", err.Error())
 		return
 	}
 	if len(line) == 0 {
-		fmt.Fprint(c, "ERR empty\n")
+		reply(c, bus.KindErr, "code", "empty")
 		return
 	}
 	cmd := line[0]
 
 	switch cmd {
 	case 't':
-		d.toggle()
-		fmt.Fprint(c, "OK toggled\n")
+		// Toggle command - format: "t\n" or "t:standup\n" to tag the dictation it starts
+		arg := strings.TrimSpace(line[1:])
+		if arg != "" && !strings.HasPrefix(arg, ":") {
+			reply(c, bus.KindErr, "code", "invalid_toggle_command")
+			return
+		}
+		tag := strings.TrimPrefix(arg, ":")
+		if !validTag(tag) {
+			reply(c, bus.KindErr, "code", "invalid_tag", "value", tag)
+			return
+		}
+		if d.toggle(tag) {
+			reply(c, bus.KindOK, "action", "toggled")
+		} else {
+			reply(c, bus.KindOK, "action", "debounced")
+		}
 	case 'c':
 		d.cancelPipeline()
-		fmt.Fprint(c, "OK cancelled\n")
+		reply(c, bus.KindOK, "action", "cancelled")
+	case 'y':
+		// Text held by behavior.explicit_inject is only released by 'j'
+		if !d.configMgr.GetConfig().Behavior.ExplicitInject && d.sendConfirmationAction(pipeline.Confirm) {
+			reply(c, bus.KindOK, "action", "confirmed")
+		} else {
+			reply(c, bus.KindErr, "code", "not_awaiting_confirmation")
+		}
+	case 'j':
+		if d.configMgr.GetConfig().Behavior.ExplicitInject && d.sendConfirmationAction(pipeline.Inject) {
+			reply(c, bus.KindOK, "action", "injecting")
+		} else {
+			reply(c, bus.KindErr, "code", "nothing_to_inject")
+		}
+	case 'n':
+		if d.sendConfirmationAction(pipeline.Discard) {
+			reply(c, bus.KindOK, "action", "discarded")
+		} else {
+			reply(c, bus.KindErr, "code", "not_awaiting_confirmation")
+		}
 	case 's':
-		status := d.status()
-		fmt.Fprintf(c, "STATUS status=%s\n", status)
+		if ms := d.lastTranscriptionMs(); ms > 0 {
+			reply(c, bus.KindStatus, "status", string(d.status()), "last_transcription_ms", strconv.FormatInt(ms, 10))
+		} else {
+			reply(c, bus.KindStatus, "status", string(d.status()))
+		}
+	case 'i':
+		data, err := json.Marshal(d.info())
+		if err != nil {
+			reply(c, bus.KindErr, "code", "info_error", "message", err.Error())
+			return
+		}
+		writeReply(c, fmt.Sprintf("%s %s\n", bus.KindInfo, data))
 	case 'q':
-		fmt.Fprint(c, "OK quitting\n")
+		reply(c, bus.KindOK, "action", "quitting")
 		d.cancel()
 	case 'm':
-		// Mode command - format: "m\n" (get) or "m:llm\n" (set)
+		// Mode command - format: "m\n" (get), "m:llm\n" (set) or "m:reset\n" (back to config)
 		modeArg := strings.TrimSpace(line[1:])
 		if modeArg == "" {
-			// Get current mode
-			mode := d.getEffectiveMode()
-			fmt.Fprintf(c, "MODE mode=%s\n", mode)
+			reply(c, bus.KindMode, "mode", d.getEffectiveMode())
 		} else if strings.HasPrefix(modeArg, ":") {
-			// Set mode
 			newMode := strings.TrimPrefix(modeArg, ":")
-			if newMode != "raw" && newMode != "llm" {
-				fmt.Fprintf(c, "ERR invalid_mode=%s\n", newMode)
+			if newMode == "reset" {
+				d.setModeOverride("")
+				log.Printf("Daemon: Processing mode reset to config (%s)", d.getEffectiveMode())
+				reply(c, bus.KindOK, "mode", d.getEffectiveMode())
+			} else if newMode != "raw" && newMode != "llm" {
+				reply(c, bus.KindErr, "code", "invalid_mode", "value", newMode)
 			} else {
 				d.setModeOverride(newMode)
 				log.Printf("Daemon: Processing mode changed to %s", newMode)
-				fmt.Fprintf(c, "OK mode=%s\n", newMode)
+				reply(c, bus.KindOK, "mode", newMode)
+			}
+		} else {
+			reply(c, bus.KindErr, "code", "invalid_mode_command")
+		}
+	case 'u':
+		// Continue-sentence command - format: "u\n" (get) or "u:on\n" / "u:off\n" (set)
+		arg := strings.TrimSpace(line[1:])
+		if arg == "" {
+			reply(c, bus.KindContinue, "continue", d.getEffectiveContinue())
+		} else if strings.HasPrefix(arg, ":") {
+			value := strings.TrimPrefix(arg, ":")
+			if value != "on" && value != "off" {
+				reply(c, bus.KindErr, "code", "invalid_continue", "value", value)
+			} else {
+				d.mu.Lock()
+				d.continueOverride = value
+				d.mu.Unlock()
+				log.Printf("Daemon: Continue-sentence changed to %s", value)
+				reply(c, bus.KindOK, "continue", value)
+			}
+		} else {
+			reply(c, bus.KindErr, "code", "invalid_continue_command")
+		}
+	case 'l':
+		// Language command - format: "l\n" (get) or "l:it\n" / "l:auto\n" (set)
+		arg := strings.TrimSpace(line[1:])
+		if arg == "" {
+			reply(c, bus.KindLanguage, "language", d.getEffectiveLanguage())
+		} else if strings.HasPrefix(arg, ":") {
+			value := strings.TrimPrefix(arg, ":")
+			if value != "auto" && !config.IsValidLanguageCode(value) {
+				reply(c, bus.KindErr, "code", "invalid_language", "value", value)
+			} else {
+				d.mu.Lock()
+				d.languageOverride = value
+				d.mu.Unlock()
+				log.Printf("Daemon: Transcription language changed to %s", value)
+				reply(c, bus.KindOK, "language", value)
+			}
+		} else {
+			reply(c, bus.KindErr, "code", "invalid_language_command")
+		}
+	case 'v':
+		// Level command - format: "v\n" (get) or "v:thorough\n" (set)
+		arg := strings.TrimSpace(line[1:])
+		if arg == "" {
+			reply(c, bus.KindLevel, "level", d.getEffectiveLevel())
+		} else if strings.HasPrefix(arg, ":") {
+			value := strings.TrimPrefix(arg, ":")
+			if !llm.IsValidLevel(value) {
+				reply(c, bus.KindErr, "code", "invalid_level", "value", value)
+			} else if value == "custom" && d.configMgr.GetConfig().LLM.CustomPrompt == "" {
+				reply(c, bus.KindErr, "code", "missing_custom_prompt")
+			} else {
+				d.mu.Lock()
+				d.levelOverride = value
+				d.mu.Unlock()
+				log.Printf("Daemon: LLM level changed to %s", value)
+				reply(c, bus.KindOK, "level", value)
 			}
 		} else {
-			fmt.Fprintf(c, "ERR invalid_mode_command\n")
+			reply(c, bus.KindErr, "code", "invalid_level_command")
+		}
+	case 'p':
+		// Provider command - format: "p\n" (get), "p:groq\n" (set) or "p:reset\n" (back to config)
+		arg := strings.TrimSpace(line[1:])
+		if arg == "" {
+			reply(c, bus.KindProvider, "provider", d.getConfigWithModeOverride().Transcription.Provider)
+		} else if strings.HasPrefix(arg, ":") {
+			value := strings.TrimPrefix(arg, ":")
+			if value == "reset" {
+				value = ""
+			}
+			// A new provider starts from its own model, so the model override goes with it
+			if _, err := d.configMgr.GetConfig().WithTranscriptionOverride(value, ""); err != nil {
+				reply(c, bus.KindErr, "code", "invalid_provider", "value", value, "message", err.Error())
+			} else {
+				d.mu.Lock()
+				d.providerOverride = value
+				d.modelOverride = ""
+				d.mu.Unlock()
+				provider := d.getConfigWithModeOverride().Transcription.Provider
+				log.Printf("Daemon: Transcription provider changed to %s", provider)
+				reply(c, bus.KindOK, "provider", provider)
+			}
+		} else {
+			reply(c, bus.KindErr, "code", "invalid_provider_command")
+		}
+	case 'o':
+		// Model command - format: "o\n" (get), "o:whisper-large-v3\n" (set) or "o:reset\n" (back to config)
+		arg := strings.TrimSpace(line[1:])
+		if arg == "" {
+			reply(c, bus.KindModel, "model", d.getConfigWithModeOverride().Transcription.Model)
+		} else if strings.HasPrefix(arg, ":") {
+			value := strings.TrimPrefix(arg, ":")
+			if value == "reset" {
+				value = ""
+			}
+			d.mu.RLock()
+			provider := d.providerOverride
+			d.mu.RUnlock()
+			if _, err := d.configMgr.GetConfig().WithTranscriptionOverride(provider, value); err != nil {
+				reply(c, bus.KindErr, "code", "invalid_model", "value", value, "message", err.Error())
+			} else {
+				d.mu.Lock()
+				d.modelOverride = value
+				d.mu.Unlock()
+				model := d.getConfigWithModeOverride().Transcription.Model
+				log.Printf("Daemon: Transcription model changed to %s", model)
+				reply(c, bus.KindOK, "model", model)
+			}
+		} else {
+			reply(c, bus.KindErr, "code", "invalid_model_command")
 		}
 	default:
 		log.Printf("Unknown command: %c", cmd)
-		fmt.Fprintf(c, "ERR unknown=%q\n", cmd)
+		reply(c, bus.KindErr, "code", "unknown_command", "command", string(cmd))
+	}
+}
+
+// replyTimeout bounds how long a reply may block on a client that stopped reading
+const replyTimeout = 2 * time.Second
+
+// reply writes one response line; see bus.FormatResponse for the grammar
+func reply(c net.Conn, kind string, pairs ...string) {
+	writeReply(c, bus.FormatResponse(kind, pairs...))
+}
+
+// writeReply sends a response line. A client that hung up or stopped reading only costs
+// a log line: the command has already run, and the daemon keeps the state it left.
+func writeReply(c net.Conn, line string) {
+	if err := c.SetWriteDeadline(time.Now().Add(replyTimeout)); err != nil {
+		log.Printf("Client write deadline error: %v", err)
+	}
+	if _, err := io.WriteString(c, line); err != nil {
+		log.Printf("Client write error: %v", err)
+	}
+}
+
+// handleSignals maps signals to daemon actions: SIGTERM/SIGINT shut down gracefully,
+// SIGHUP reloads the config and SIGUSR1 toggles recording like "hyprvoice toggle"
+func (d *Daemon) handleSignals(sigCh <-chan os.Signal) {
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case sig := <-sigCh:
+			switch sig {
+			case syscall.SIGHUP:
+				log.Printf("Received SIGHUP, reloading config")
+				d.configMgr.Reload()
+			case syscall.SIGUSR1:
+				log.Printf("Received SIGUSR1, toggling recording")
+				d.toggle("")
+			default:
+				log.Printf("Received signal %v, shutting down gracefully", sig)
+				d.cancel()
+				return
+			}
+		}
 	}
 }
 
-func (d *Daemon) toggle() {
+// maxTagLength bounds the label "hyprvoice toggle --tag" attaches to a dictation
+const maxTagLength = 64
+
+// validTag reports whether tag is usable as a dictation label: letters, digits, '-', '_' and '.'
+// only, so it reads unambiguously in log lines and failsafe entries ("" means untagged)
+func validTag(tag string) bool {
+	if len(tag) > maxTagLength {
+		return false
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// toggle advances the pipeline: idle starts recording, recording stops it, and so on.
+// A dictation it starts is labeled with tag ("" = untagged); other transitions ignore it.
+// It returns false when the toggle came within behavior.toggle_debounce of the previous one and was ignored.
+func (d *Daemon) toggle(tag string) bool {
+	debounce := d.configMgr.GetConfig().Behavior.ToggleDebounce
+	now := time.Now()
+	d.mu.Lock()
+	if debounce > 0 && now.Sub(d.lastToggle) < debounce {
+		d.mu.Unlock()
+		log.Printf("Daemon: Toggle ignored, %v since the last one (toggle_debounce = %v)", now.Sub(d.lastToggle).Round(time.Millisecond), debounce)
+		return false
+	}
+	d.lastToggle = now
+	d.mu.Unlock()
+
 	switch d.status() {
 	case pipeline.Idle:
-		config := d.getConfigWithModeOverride()
-		
+		cfg := d.getConfigWithModeOverride()
+
 		// Capture active window when recording starts
-		windowAddress := d.getActiveWindow()
+		windowAddress := d.getActiveWindow(cfg)
 		if windowAddress != "" {
 			log.Printf("Daemon: Captured active window address: %s", windowAddress)
 		} else {
 			log.Printf("Daemon: Failed to capture active window, continuing without window tracking")
 		}
-		
-		p := pipeline.New(config)
+
+		p := pipeline.New(cfg)
 		if windowAddress != "" {
 			p.SetWindowAddress(windowAddress)
 		}
+		p.SetMergeState(d.merge)
+		if tag != "" {
+			log.Printf("Daemon: Dictation tagged %q", tag)
+			p.SetTag(tag)
+		}
 		p.Run(d.ctx)
 
 		d.mu.Lock()
 		d.pipeline = p
+		d.lastError = ""
 		d.mu.Unlock()
 
+		d.notifier.StartSession()
 		go d.notifier.Notify("Hyprvoice", "Recording Started")
 		go d.monitorPipelineErrors(p)
+		if windowAddress != "" && (cfg.Behavior.OnFocusChange == config.FocusChangeWarn || cfg.Behavior.OnFocusChange == config.FocusChangeCancel) {
+			go d.watchFocus(p, injection.NewWindowManager(cfg.Injection.Compositor), windowAddress, cfg.Behavior.OnFocusChange)
+		}
 
 	case pipeline.Recording:
 		d.stopPipeline()
@@ -244,12 +536,63 @@ func (d *Daemon) toggle() {
 		}
 		go d.notifier.Notify("Hyprvoice", "Recording Ended... Transcribing")
 
+	case pipeline.Confirming:
+		log.Printf("Daemon: Toggle ignored while awaiting confirmation")
+		if d.configMgr.GetConfig().Behavior.ExplicitInject {
+			go d.notifier.Notify("Hyprvoice", "Transcription ready: run 'hyprvoice inject' or 'hyprvoice discard'")
+		} else {
+			go d.notifier.Notify("Hyprvoice", "Awaiting confirmation: run 'hyprvoice confirm' or 'hyprvoice discard'")
+		}
+
 	case pipeline.Injecting:
+		d.toggleDuringInjection()
+	}
+	return true
+}
+
+// toggleDuringInjection applies behavior.toggle_during_injection. Aborting stops the
+// backend mid-run, so text that was already typed stays in the target window.
+func (d *Daemon) toggleDuringInjection() {
+	switch d.configMgr.GetConfig().Behavior.ToggleDuringInjection {
+	case config.ToggleInjectionIgnore:
+		log.Printf("Daemon: Toggle ignored while injecting")
+		go d.notifier.Notify("Hyprvoice", "Injection in progress")
+	case config.ToggleInjectionAbortClearClipboard:
+		d.stopPipeline()
+		ctx, cancel := context.WithTimeout(d.ctx, 2*time.Second)
+		defer cancel()
+		if err := injection.ClearClipboard(ctx); err != nil {
+			log.Printf("Daemon: Failed to clear clipboard after aborted injection: %v", err)
+		}
+		go d.notifier.Error("Injection Aborted")
+	default:
 		d.stopPipeline()
 		go d.notifier.Error("Injection Aborted")
 	}
 }
 
+// sendConfirmationAction forwards a confirm, inject or discard action to a pipeline holding
+// its text. Returns false if no pipeline is waiting for one.
+func (d *Daemon) sendConfirmationAction(action pipeline.Action) bool {
+	d.mu.RLock()
+	p := d.pipeline
+	d.mu.RUnlock()
+
+	if p == nil || p.Status() != pipeline.Confirming {
+		log.Printf("Daemon: %s requested but no transcription is awaiting confirmation", action)
+		return false
+	}
+
+	log.Printf("Daemon: Sending %s action to pipeline", action)
+	select {
+	case p.GetActionCh() <- action:
+		return true
+	default:
+		log.Printf("Daemon: Pipeline action channel full, dropping %s", action)
+		return false
+	}
+}
+
 func (d *Daemon) cancelPipeline() {
 	switch d.status() {
 	case pipeline.Idle:
@@ -262,43 +605,176 @@ func (d *Daemon) cancelPipeline() {
 
 func (d *Daemon) monitorPipelineErrors(p pipeline.Pipeline) {
 	errorCh := p.GetErrorCh()
+	notifyCh := p.GetNotifyCh()
+	statusCh := p.GetStatusCh()
+	timingCh := p.GetTimingCh()
 	for {
 		select {
+		case status := <-statusCh:
+			d.writeStateFile(status)
+		case elapsed := <-timingCh:
+			d.mu.Lock()
+			d.lastTranscription = elapsed
+			d.mu.Unlock()
 		case pipelineErr := <-errorCh:
 			message := pipelineErr.Message
 
 			if pipelineErr.Err != nil {
 				message = fmt.Sprintf("%s: %v", message, pipelineErr.Err)
 			}
+			log.Printf("Daemon: Pipeline error (%s): %s", pipelineErr.Kind, message)
+			if hint := pipelineErr.Hint(); hint != "" {
+				log.Printf("Daemon: Hint: %s", hint)
+				message = fmt.Sprintf("%s\n\n%s", message, hint)
+			}
+			d.mu.Lock()
+			d.lastError = pipelineErr.Kind
+			d.mu.Unlock()
 
 			d.notifier.Error(message)
+		case notification := <-notifyCh:
+			d.notifier.Notify(notification.Title, notification.Message)
 		case <-d.ctx.Done():
 			return
 		}
 	}
 }
 
-// getActiveWindow retrieves the address of the currently active window using hyprctl
-func (d *Daemon) getActiveWindow() string {
-	cmd := exec.Command("hyprctl", "-j", "activewindow")
-	output, err := cmd.Output()
-	if err != nil {
-		log.Printf("Daemon: Failed to get active window: %v", err)
-		return ""
+// stateFilePath returns the configured status file path with environment variables expanded
+func (d *Daemon) stateFilePath() string {
+	return os.ExpandEnv(d.configMgr.GetConfig().Behavior.StateFile)
+}
+
+// writeStateFile atomically replaces the status file so watchers never read a partial write
+func (d *Daemon) writeStateFile(status pipeline.Status) {
+	path := d.stateFilePath()
+	if path == "" {
+		return
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(string(status)+"\n"), 0644); err != nil {
+		log.Printf("Daemon: Failed to write state file: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.Printf("Daemon: Failed to replace state file: %v", err)
+		os.Remove(tmp)
 	}
+}
 
-	var window struct {
-		Address string `json:"address"`
+func (d *Daemon) removeStateFile() {
+	path := d.stateFilePath()
+	if path == "" {
+		return
 	}
-	if err := json.Unmarshal(output, &window); err != nil {
-		log.Printf("Daemon: Failed to parse active window JSON: %v", err)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Daemon: Failed to remove state file: %v", err)
+	}
+}
+
+// getActiveWindow retrieves the address of the currently active window from the compositor.
+// Returns an empty address on compositors without window tracking so injection targets the focused window.
+func (d *Daemon) getActiveWindow(cfg *config.Config) string {
+	windows := injection.NewWindowManager(cfg.Injection.Compositor)
+	if windows == nil {
+		log.Printf("Daemon: Window tracking unavailable on this compositor, skipping window capture")
 		return ""
 	}
+	return d.captureActiveWindow(windows, cfg.Injection.WindowRetries, cfg.Injection.WindowRetryDelay)
+}
 
-	return window.Address
+// captureActiveWindow asks for the active window, trying again up to retries times when
+// the answer is empty or fails: right after a keybind the compositor may not have settled
+func (d *Daemon) captureActiveWindow(windows injection.WindowManager, retries int, delay time.Duration) string {
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(d.ctx, 2*time.Second)
+		address, err := windows.ActiveWindow(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("Daemon: Failed to get active window via %s: %v", windows.Name(), err)
+		} else if address != "" {
+			return address
+		}
+		if attempt >= retries {
+			return ""
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return ""
+		case <-time.After(delay):
+		}
+	}
+}
+
+// maxCommandLength returns the configured command line limit in bytes
+func (d *Daemon) maxCommandLength() int {
+	if length := d.configMgr.GetConfig().Behavior.MaxCommandLength; length > 0 {
+		return length
+	}
+	return bus.DefaultMaxCommandLength
 }
 
-// getEffectiveMode returns the current processing mode (runtime override or config default)
+// focusPollInterval is how often watchFocus checks the active window
+const focusPollInterval = 500 * time.Millisecond
+
+// watchFocus polls the active window while p records or transcribes and warns or cancels
+// once focus leaves the window dictation started in. It stops at injection or when p is replaced.
+func (d *Daemon) watchFocus(p pipeline.Pipeline, windows injection.WindowManager, address string, action string) {
+	if windows == nil {
+		return
+	}
+
+	ticker := time.NewTicker(focusPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		d.mu.RLock()
+		current := d.pipeline
+		d.mu.RUnlock()
+		if current != p {
+			return
+		}
+		if status := p.Status(); status != pipeline.Recording && status != pipeline.Transcribing {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(d.ctx, 2*time.Second)
+		active, err := windows.ActiveWindow(ctx)
+		cancel()
+		if err != nil || active == address {
+			continue
+		}
+
+		log.Printf("Daemon: Focus moved from %s to %s during dictation (%s)", address, active, action)
+		if action == config.FocusChangeCancel {
+			d.mu.Lock()
+			stillCurrent := d.pipeline == p
+			if stillCurrent {
+				d.pipeline = nil
+			}
+			d.mu.Unlock()
+			if !stillCurrent {
+				return
+			}
+			p.Stop()
+			d.notifier.Error("Dictation Cancelled: focus left the original window")
+		} else {
+			d.notifier.Notify("Hyprvoice", "Focus left the original window; text will still go there")
+		}
+		return
+	}
+}
+
+// getEffectiveMode returns the current processing mode. A session override set with
+// "hyprvoice mode" wins over processing.mode until it is reset or the config's mode is edited.
 func (d *Daemon) getEffectiveMode() string {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
@@ -308,26 +784,114 @@ func (d *Daemon) getEffectiveMode() string {
 	return d.configMgr.GetConfig().Processing.Mode
 }
 
-// setModeOverride sets a runtime mode override
+// setModeOverride sets a runtime mode override; "" reverts to processing.mode
 func (d *Daemon) setModeOverride(mode string) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	d.modeOverride = mode
 }
 
-// getConfigWithModeOverride returns a copy of the config with the mode override applied
+// getConfigWithModeOverride returns a copy of the config with the runtime overrides
+// (mode, continue, language, level, provider, model) applied
 func (d *Daemon) getConfigWithModeOverride() *config.Config {
 	cfg := d.configMgr.GetConfig()
 
 	d.mu.RLock()
 	modeOverride := d.modeOverride
+	continueOverride := d.continueOverride
+	languageOverride := d.languageOverride
+	levelOverride := d.levelOverride
+	providerOverride := d.providerOverride
+	modelOverride := d.modelOverride
 	d.mu.RUnlock()
 
-	if modeOverride != "" {
-		// Create a copy with the override applied
+	if providerOverride != "" || modelOverride != "" {
+		// Checked when set, but a config reload can still make the override unusable
+		overridden, err := cfg.WithTranscriptionOverride(providerOverride, modelOverride)
+		if err != nil {
+			log.Printf("Daemon: Ignoring transcription provider/model override: %v", err)
+		} else {
+			cfg = overridden
+		}
+	}
+
+	if modeOverride != "" || continueOverride != "" || languageOverride != "" || levelOverride != "" {
+		// Create a copy with the overrides applied
 		cfgCopy := *cfg
-		cfgCopy.Processing.Mode = modeOverride
+		if modeOverride != "" {
+			cfgCopy.Processing.Mode = modeOverride
+		}
+		if continueOverride != "" {
+			cfgCopy.Processing.ContinueSentence = continueOverride == "on"
+		}
+		if languageOverride == "auto" {
+			cfgCopy.Transcription.Language = ""
+		} else if languageOverride != "" {
+			cfgCopy.Transcription.Language = languageOverride
+		}
+		if levelOverride != "" {
+			cfgCopy.LLM.Level = levelOverride
+		}
 		return &cfgCopy
 	}
 	return cfg
 }
+
+// info collects the snapshot served by the 'i' command
+func (d *Daemon) info() bus.Info {
+	cfg := d.getConfigWithModeOverride()
+	info := bus.Info{
+		Status:        string(d.status()),
+		Proto:         bus.ProtoVersion,
+		Mode:          d.getEffectiveMode(),
+		Continue:      d.getEffectiveContinue(),
+		Task:          cfg.Transcription.Task,
+		Provider:      cfg.Transcription.Provider,
+		Model:         cfg.Transcription.Model,
+		Language:      cfg.Transcription.Language,
+		Level:         d.getEffectiveLevel(),
+		UptimeSeconds: int64(time.Since(d.startedAt).Seconds()),
+
+		LastTranscriptionMs: d.lastTranscriptionMs(),
+	}
+	d.mu.RLock()
+	info.LastError = string(d.lastError)
+	d.mu.RUnlock()
+	return info
+}
+
+// lastTranscriptionMs returns how long the last transcription took in milliseconds, 0 before the first
+func (d *Daemon) lastTranscriptionMs() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.lastTranscription.Milliseconds()
+}
+
+// getEffectiveLanguage returns the transcription language (runtime override or config default), "auto" when unset
+func (d *Daemon) getEffectiveLanguage() string {
+	if language := d.getConfigWithModeOverride().Transcription.Language; language != "" {
+		return language
+	}
+	return "auto"
+}
+
+// getEffectiveLevel returns the LLM level (runtime override or config default), "moderate" when unset
+func (d *Daemon) getEffectiveLevel() string {
+	if level := d.getConfigWithModeOverride().LLM.Level; level != "" {
+		return level
+	}
+	return "moderate"
+}
+
+// getEffectiveContinue returns "on" or "off" for continue-sentence (runtime override or config default)
+func (d *Daemon) getEffectiveContinue() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.continueOverride != "" {
+		return d.continueOverride
+	}
+	if d.configMgr.GetConfig().Processing.ContinueSentence {
+		return "on"
+	}
+	return "off"
+}
@@ -6,10 +6,14 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/leonardotrapani/hyprvoice/internal/bus"
 	"github.com/leonardotrapani/hyprvoice/internal/pipeline"
+	"github.com/leonardotrapani/hyprvoice/internal/testutil"
 )
 
 func TestNew(t *testing.T) {
@@ -72,6 +76,27 @@ type = "log"`
 	}
 }
 
+func TestNew_ConfigManagerError(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+
+	// Unparseable TOML makes config.NewManager fail
+	configPath := filepath.Join(tempDir, "hyprvoice", "config.toml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	os.WriteFile(configPath, []byte("[recording\nsample_rate = "), 0644)
+
+	daemon, err := New()
+	if err == nil {
+		t.Fatal("New() should fail when the config manager cannot be created")
+	}
+	if daemon != nil {
+		t.Errorf("New() returned a daemon alongside error %v", err)
+	}
+	if !strings.Contains(err.Error(), "failed to create config manager") {
+		t.Errorf("New() error = %v, want config manager error", err)
+	}
+}
+
 func TestDaemon_Status(t *testing.T) {
 	// Set up a temporary config directory
 	tempDir := t.TempDir()
@@ -168,12 +193,12 @@ type = "log"`
 	}
 
 	// Test toggle from idle to recording
-	daemon.toggle()
+	daemon.toggle("")
 	status := daemon.status()
 	t.Logf("Status after first toggle = %s", status)
 
 	// Test toggle from recording to idle (abort)
-	daemon.toggle()
+	daemon.toggle("")
 	status = daemon.status()
 	t.Logf("Status after second toggle = %s", status)
 }
@@ -241,6 +266,29 @@ type = "log"`
 			t.Errorf("handle() response = %q, want %q", response, "STATUS status=idle\n")
 		}
 	})
+
+	t.Run("status_command_with_timing", func(t *testing.T) {
+		daemon.mu.Lock()
+		daemon.lastTranscription = 1234 * time.Millisecond
+		daemon.mu.Unlock()
+		defer func() {
+			daemon.mu.Lock()
+			daemon.lastTranscription = 0
+			daemon.mu.Unlock()
+		}()
+
+		mockConn := &MockConn{readData: []byte("s\n")}
+		daemon.wg.Add(1)
+		daemon.handle(mockConn)
+
+		want := "STATUS status=idle last_transcription_ms=1234\n"
+		if response := string(mockConn.writeData); response != want {
+			t.Errorf("handle() response = %q, want %q", response, want)
+		}
+		if got := daemon.info().LastTranscriptionMs; got != 1234 {
+			t.Errorf("info().LastTranscriptionMs = %d, want 1234", got)
+		}
+	})
 }
 
 // MockConn implements net.Conn for testing
@@ -315,11 +363,21 @@ type = "log"`
 		t.Fatalf("Failed to create daemon: %v", err)
 	}
 
-	// Test onConfigReload method
+	// A reload that leaves processing.mode alone keeps the session mode
+	daemon.setModeOverride("llm")
 	daemon.onConfigReload()
+	if mode := daemon.getEffectiveMode(); mode != "llm" {
+		t.Errorf("mode after unrelated reload = %q, want session mode llm", mode)
+	}
 
-	// Verify that the method completes without panicking
-	// (We can't easily test the internal state changes without more complex mocking)
+	// Editing processing.mode in the config replaces the session mode
+	daemon.setModeOverride("raw")
+	os.WriteFile(configPath, []byte(configContent+"\n\n[processing]\nmode = \"llm\"\n\n[llm]\napi_key = \"test-key\"\n"), 0644)
+	daemon.configMgr.Reload()
+	daemon.onConfigReload()
+	if mode := daemon.getEffectiveMode(); mode != "llm" {
+		t.Errorf("mode after processing.mode edit = %q, want config mode llm", mode)
+	}
 }
 
 func TestDaemon_StopPipeline(t *testing.T) {
@@ -386,6 +444,8 @@ type = "log"`
 }
 
 func TestDaemon_Handle_Commands(t *testing.T) {
+	t.Setenv("GROQ_API_KEY", "test-groq-key")
+
 	// Set up a temporary config directory
 	tempDir := t.TempDir()
 	originalConfigDir := os.Getenv("XDG_CONFIG_HOME")
@@ -434,9 +494,50 @@ type = "log"`
 		command  string
 		expected string
 	}{
-		{"toggle_command", "t\n", "OK toggled\n"},
-		{"quit_command", "q\n", "OK quitting\n"},
-		{"unknown_command", "x\n", "ERR unknown="},
+		{"info_command", "i\n", `INFO {"status":"idle","proto":8,"mode":"raw","continue":"off","task":"transcribe","provider":"openai","model":"whisper-1","language":"","level":"moderate","uptime_seconds":0,"last_transcription_ms":0,"last_error":""}` + "\n"},
+		{"status_command", "s\n", "STATUS status=idle\n"},
+		{"mode_get_default", "m\n", "MODE mode=raw\n"},
+		{"mode_invalid", "m:shout\n", "ERR code=invalid_mode value=shout\n"},
+		{"toggle_invalid_tag", "t:stand up\n", "ERR code=invalid_tag value=\"stand up\"\n"},
+		{"toggle_invalid_command", "tx\n", "ERR code=invalid_toggle_command\n"},
+		{"mode_malformed", "mx\n", "ERR code=invalid_mode_command\n"},
+		{"mode_set", "m:llm\n", "OK mode=llm\n"},
+		{"mode_get_override", "m\n", "MODE mode=llm\n"},
+		{"mode_reset", "m:reset\n", "OK mode=raw\n"},
+		{"mode_get_after_reset", "m\n", "MODE mode=raw\n"},
+		{"blank_line", "\n", `ERR code=unknown_command command="\n"` + "\n"},
+		{"toggle_command", "t\n", "OK action=toggled\n"},
+		{"cancel_command", "c\n", "OK action=cancelled\n"},
+		{"confirm_command_idle", "y\n", "ERR code=not_awaiting_confirmation\n"},
+		{"inject_command_idle", "j\n", "ERR code=nothing_to_inject\n"},
+		{"discard_command_idle", "n\n", "ERR code=not_awaiting_confirmation\n"},
+		{"continue_get_default", "u\n", "CONTINUE continue=off\n"},
+		{"continue_set_on", "u:on\n", "OK continue=on\n"},
+		{"continue_get_override", "u\n", "CONTINUE continue=on\n"},
+		{"continue_invalid", "u:maybe\n", "ERR code=invalid_continue value=maybe\n"},
+		{"language_get_default", "l\n", "LANGUAGE language=auto\n"},
+		{"language_set", "l:it\n", "OK language=it\n"},
+		{"language_get_override", "l\n", "LANGUAGE language=it\n"},
+		{"language_invalid", "l:klingon\n", "ERR code=invalid_language value=klingon\n"},
+		{"level_get_default", "v\n", "LEVEL level=moderate\n"},
+		{"level_set", "v:thorough\n", "OK level=thorough\n"},
+		{"level_get_override", "v\n", "LEVEL level=thorough\n"},
+		{"level_invalid", "v:extreme\n", "ERR code=invalid_level value=extreme\n"},
+		{"level_custom_without_prompt", "v:custom\n", "ERR code=missing_custom_prompt\n"},
+		{"level_malformed", "vx\n", "ERR code=invalid_level_command\n"},
+		{"provider_get_default", "p\n", "PROVIDER provider=openai\n"},
+		{"model_get_default", "o\n", "MODEL model=whisper-1\n"},
+		{"provider_set", "p:groq\n", "OK provider=groq\n"},
+		{"model_get_provider_default", "o\n", "MODEL model=whisper-large-v3-turbo\n"},
+		{"model_invalid", "o:whisper-1\n", `ERR code=invalid_model value=whisper-1 message="invalid model for groq transcription: whisper-1 (must be whisper-large-v3 or whisper-large-v3-turbo)"` + "\n"},
+		{"model_set", "o:whisper-large-v3\n", "OK model=whisper-large-v3\n"},
+		{"model_get_override", "o\n", "MODEL model=whisper-large-v3\n"},
+		{"provider_reset", "p:reset\n", "OK provider=openai\n"},
+		{"model_get_after_reset", "o\n", "MODEL model=whisper-1\n"},
+		{"provider_invalid", "p:anthropic\n", `ERR code=invalid_provider value=anthropic message="unsupported transcription provider: anthropic (must be openai, groq, azure-openai)"` + "\n"},
+		{"provider_malformed", "px\n", "ERR code=invalid_provider_command\n"},
+		{"quit_command", "q\n", "OK action=quitting\n"},
+		{"unknown_command", "x\n", "ERR code=unknown_command command=x\n"},
 	}
 
 	for _, tt := range tests {
@@ -454,17 +555,88 @@ type = "log"`
 
 			// Check response
 			response := string(mockConn.writeData)
-			if tt.name == "unknown_command" {
-				if len(response) == 0 || !((len(response) >= 12 && response[:12] == "ERR unknown=") || (len(response) >= 13 && response[:13] == "ERR unknown=")) {
-					t.Errorf("handle() response = %q, want prefix %q", response, "ERR unknown=")
-				}
-			} else if response != tt.expected {
+			if response != tt.expected {
 				t.Errorf("handle() response = %q, want %q", response, tt.expected)
 			}
 		})
 	}
 }
 
+func TestDaemon_Handle_ConfirmAndInject(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+	configPath := filepath.Join(tempDir, "hyprvoice", "config.toml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	configContent := `[recording]
+sample_rate = 16000
+channels = 1
+format = "s16"
+buffer_size = 8192
+channel_buffer_size = 30
+timeout = "5m"
+
+[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[injection]
+mode = "fallback"
+wtype_timeout = "5s"
+clipboard_timeout = "3s"
+
+[notifications]
+type = "log"`
+	os.WriteFile(configPath, []byte(configContent), 0644)
+
+	daemon, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create daemon: %v", err)
+	}
+	held := &heldPipeline{actions: make(chan pipeline.Action, 1)}
+	daemon.pipeline = held
+
+	send := func(command, want string, wantAction pipeline.Action) {
+		t.Helper()
+		conn := &MockConn{readData: []byte(command)}
+		daemon.wg.Add(1)
+		daemon.handle(conn)
+		if got := string(conn.writeData); got != want {
+			t.Errorf("handle(%q) = %q, want %q", command, got, want)
+		}
+		select {
+		case action := <-held.actions:
+			if action != wantAction {
+				t.Errorf("handle(%q) sent %s, want %q", command, action, wantAction)
+			}
+		default:
+			if wantAction != "" {
+				t.Errorf("handle(%q) sent no action, want %s", command, wantAction)
+			}
+		}
+	}
+
+	// Review before inject: only confirm releases the text
+	send("j\n", "ERR code=nothing_to_inject\n", "")
+	send("y\n", "OK action=confirmed\n", pipeline.Confirm)
+
+	// behavior.explicit_inject: only inject releases the text
+	os.WriteFile(configPath, []byte(configContent+"\n\n[behavior]\nexplicit_inject = true"), 0644)
+	daemon.configMgr.Reload()
+	send("y\n", "ERR code=not_awaiting_confirmation\n", "")
+	send("j\n", "OK action=injecting\n", pipeline.Inject)
+	send("n\n", "OK action=discarded\n", pipeline.Discard)
+}
+
+// heldPipeline is a pipeline holding its text for confirm or inject
+type heldPipeline struct {
+	MockPipeline
+	actions chan pipeline.Action
+}
+
+func (h *heldPipeline) Status() pipeline.Status             { return pipeline.Confirming }
+func (h *heldPipeline) GetActionCh() chan<- pipeline.Action { return h.actions }
+
 // MockPipeline implements pipeline.Pipeline for testing
 type MockPipeline struct{}
 
@@ -474,4 +646,592 @@ func (m *MockPipeline) Status() pipeline.Status { return pipeline.Idle }
 func (m *MockPipeline) GetErrorCh() <-chan pipeline.PipelineError {
 	return make(chan pipeline.PipelineError)
 }
-func (m *MockPipeline) GetActionCh() chan<- pipeline.Action { return make(chan pipeline.Action) }
+func (m *MockPipeline) GetNotifyCh() <-chan pipeline.Notification {
+	return make(chan pipeline.Notification)
+}
+func (m *MockPipeline) GetStatusCh() <-chan pipeline.Status {
+	return make(chan pipeline.Status)
+}
+func (m *MockPipeline) GetTimingCh() <-chan time.Duration {
+	return make(chan time.Duration)
+}
+func (m *MockPipeline) GetActionCh() chan<- pipeline.Action      { return make(chan pipeline.Action) }
+func (m *MockPipeline) SetWindowAddress(address string)          {}
+func (m *MockPipeline) GetWindowAddress() string                 { return "" }
+func (m *MockPipeline) SetMergeState(merge *pipeline.MergeState) {}
+func (m *MockPipeline) SetTag(tag string)                        {}
+
+func TestDaemon_StateFile(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+	t.Setenv("HYPRVOICE_TEST_STATE_DIR", tempDir)
+
+	configPath := filepath.Join(tempDir, "hyprvoice", "config.toml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	configContent := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[injection]
+backends = ["clipboard"]
+
+[notifications]
+type = "log"
+
+[behavior]
+state_file = "$HYPRVOICE_TEST_STATE_DIR/hyprvoice.state"`
+	os.WriteFile(configPath, []byte(configContent), 0644)
+
+	daemon, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create daemon: %v", err)
+	}
+
+	statePath := filepath.Join(tempDir, "hyprvoice.state")
+	for _, status := range []pipeline.Status{pipeline.Idle, pipeline.Recording, pipeline.Transcribing} {
+		daemon.writeStateFile(status)
+
+		data, err := os.ReadFile(statePath)
+		if err != nil {
+			t.Fatalf("Failed to read state file: %v", err)
+		}
+		if string(data) != string(status)+"\n" {
+			t.Errorf("state file = %q, want %q", data, string(status)+"\n")
+		}
+	}
+
+	daemon.removeStateFile()
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("state file should be removed, stat error = %v", err)
+	}
+}
+
+// failingPipeline is a MockPipeline whose errors come from errorCh
+type failingPipeline struct {
+	MockPipeline
+	errorCh chan pipeline.PipelineError
+}
+
+func (m *failingPipeline) GetErrorCh() <-chan pipeline.PipelineError { return m.errorCh }
+
+func TestDaemon_LastError(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+
+	configPath := filepath.Join(tempDir, "hyprvoice", "config.toml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	configContent := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[injection]
+backends = ["clipboard"]
+
+[notifications]
+type = "log"`
+	os.WriteFile(configPath, []byte(configContent), 0644)
+
+	daemon, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create daemon: %v", err)
+	}
+	defer daemon.cancel()
+
+	if got := daemon.info().LastError; got != "" {
+		t.Fatalf("info().LastError = %q before any dictation, want empty", got)
+	}
+
+	p := &failingPipeline{errorCh: make(chan pipeline.PipelineError)}
+	go daemon.monitorPipelineErrors(p)
+	p.errorCh <- pipeline.PipelineError{Kind: pipeline.ErrorKindInjection, Message: "Failed to inject text"}
+
+	deadline := time.Now().Add(time.Second)
+	for daemon.info().LastError != string(pipeline.ErrorKindInjection) {
+		if time.Now().After(deadline) {
+			t.Fatalf("info().LastError = %q, want %q", daemon.info().LastError, pipeline.ErrorKindInjection)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// recordingPipeline is a MockPipeline that reports it is still recording
+type recordingPipeline struct {
+	MockPipeline
+	stopped bool
+}
+
+func (m *recordingPipeline) Status() pipeline.Status { return pipeline.Recording }
+func (m *recordingPipeline) Stop()                   { m.stopped = true }
+
+// fakeWindows reports a fixed active window
+type fakeWindows struct {
+	active string
+}
+
+func (f *fakeWindows) Name() string                                     { return "fake" }
+func (f *fakeWindows) ActiveWindow(ctx context.Context) (string, error) { return f.active, nil }
+func (f *fakeWindows) FocusWindow(ctx context.Context, address string) error {
+	return nil
+}
+func (f *fakeWindows) WindowClass(ctx context.Context, address string) (string, error) {
+	return "", nil
+}
+
+// settlingWindows reports no active window for the first empty calls
+type settlingWindows struct {
+	fakeWindows
+	empty int
+	calls int
+}
+
+func (s *settlingWindows) ActiveWindow(ctx context.Context) (string, error) {
+	s.calls++
+	if s.calls <= s.empty {
+		return "", nil
+	}
+	return s.active, nil
+}
+
+func TestDaemon_CaptureActiveWindow_Retries(t *testing.T) {
+	d := &Daemon{ctx: context.Background()}
+
+	tests := []struct {
+		name      string
+		empty     int
+		retries   int
+		want      string
+		wantCalls int
+	}{
+		{"first try", 0, 2, "0xa", 1},
+		{"settles on retry", 2, 2, "0xa", 3},
+		{"gives up", 5, 2, "", 3},
+		{"retries off", 1, 0, "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows := &settlingWindows{fakeWindows: fakeWindows{active: "0xa"}, empty: tt.empty}
+			if got := d.captureActiveWindow(windows, tt.retries, time.Millisecond); got != tt.want {
+				t.Errorf("captureActiveWindow() = %q, want %q", got, tt.want)
+			}
+			if windows.calls != tt.wantCalls {
+				t.Errorf("ActiveWindow called %d times, want %d", windows.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDaemon_WatchFocus_Cancel(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+
+	configPath := filepath.Join(tempDir, "hyprvoice", "config.toml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	configContent := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[notifications]
+type = "log"`
+	os.WriteFile(configPath, []byte(configContent), 0644)
+
+	daemon, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create daemon: %v", err)
+	}
+
+	p := &recordingPipeline{}
+	daemon.pipeline = p
+
+	// Focus stays on the captured window: watchFocus keeps polling until the daemon stops
+	go func() {
+		time.Sleep(2 * focusPollInterval)
+		daemon.cancel()
+	}()
+	daemon.watchFocus(p, &fakeWindows{active: "0xa"}, "0xa", "cancel")
+	if p.stopped {
+		t.Fatal("watchFocus() stopped the pipeline while focus was unchanged")
+	}
+
+	daemon.ctx, daemon.cancel = context.WithCancel(context.Background())
+	defer daemon.cancel()
+	daemon.watchFocus(p, &fakeWindows{active: "0xb"}, "0xa", "cancel")
+	if !p.stopped {
+		t.Error("watchFocus() should stop the pipeline when focus moves")
+	}
+	if daemon.pipeline != nil {
+		t.Error("watchFocus() should clear the daemon pipeline on cancel")
+	}
+}
+
+func TestDaemon_ToggleDebounce(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+
+	configPath := filepath.Join(tempDir, "hyprvoice", "config.toml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	configContent := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[notifications]
+type = "log"
+
+[behavior]
+toggle_debounce = "1h"`
+	os.WriteFile(configPath, []byte(configContent), 0644)
+
+	daemon, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create daemon: %v", err)
+	}
+
+	first := &recordingPipeline{}
+	daemon.pipeline = first
+	if !daemon.toggle("") {
+		t.Fatal("first toggle should be accepted")
+	}
+	if !first.stopped {
+		t.Error("first toggle should abort the recording")
+	}
+
+	second := &recordingPipeline{}
+	daemon.pipeline = second
+	if daemon.toggle("") {
+		t.Error("toggle within toggle_debounce should be ignored")
+	}
+	if second.stopped {
+		t.Error("debounced toggle should leave the pipeline alone")
+	}
+
+	conn := &MockConn{readData: []byte("t\n")}
+	daemon.wg.Add(1)
+	daemon.handle(conn)
+	if got := string(conn.writeData); got != "OK action=debounced\n" {
+		t.Errorf("reply = %q, want OK action=debounced", got)
+	}
+}
+
+// chunkedConn delivers its data one byte per Read to exercise partial reads
+type chunkedConn struct {
+	MockConn
+}
+
+func (m *chunkedConn) Read(b []byte) (n int, err error) {
+	if len(b) > 1 {
+		b = b[:1]
+	}
+	return m.MockConn.Read(b)
+}
+
+func FuzzDaemon_Handle(f *testing.F) {
+	tempDir := f.TempDir()
+	f.Setenv("XDG_CONFIG_HOME", tempDir)
+
+	configPath := filepath.Join(tempDir, "hyprvoice", "config.toml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	configContent := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[notifications]
+type = "log"`
+	os.WriteFile(configPath, []byte(configContent), 0644)
+
+	daemon, err := New()
+	if err != nil {
+		f.Fatalf("Failed to create daemon: %v", err)
+	}
+
+	for _, seed := range []string{
+		"s\n", "i\n", "m\n", "m:llm\n", "m:bogus\n", "mx\n", "u:on\n", "u:\n", "l\n", "l:it\n", "l:auto\n", "y\n", "j\n", "n\n", "c\n",
+		"", "\n", "s", "\x00\xff\n", "m:\r\n", strings.Repeat("m", bus.DefaultMaxCommandLength+10),
+	} {
+		f.Add([]byte(seed), false)
+		f.Add([]byte(seed), true)
+	}
+
+	validPrefixes := []string{"OK ", "ERR ", "STATUS ", "MODE ", "CONTINUE ", "LANGUAGE ", "LEVEL ", "INFO "}
+
+	f.Fuzz(func(t *testing.T, data []byte, chunked bool) {
+		// Toggle starts a real recording and quit cancels the daemon; both are covered elsewhere
+		if len(data) > 0 && (data[0] == 't' || data[0] == 'q') {
+			t.Skip()
+		}
+
+		var conn net.Conn
+		mock := &MockConn{readData: data}
+		conn = mock
+		if chunked {
+			chunkedMock := &chunkedConn{MockConn: MockConn{readData: data}}
+			mock = &chunkedMock.MockConn
+			conn = chunkedMock
+		}
+
+		daemon.wg.Add(1)
+		daemon.handle(conn)
+
+		response := string(mock.writeData)
+		if !strings.HasSuffix(response, "\n") || strings.Count(response, "\n") != 1 {
+			t.Fatalf("handle(%q) response %q is not a single line", data, response)
+		}
+		valid := false
+		for _, prefix := range validPrefixes {
+			if strings.HasPrefix(response, prefix) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			t.Fatalf("handle(%q) response %q has no known prefix", data, response)
+		}
+	})
+}
+
+func TestDaemon_Handle_TooLong(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+
+	configPath := filepath.Join(tempDir, "hyprvoice", "config.toml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	configContent := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[notifications]
+type = "log"
+
+[behavior]
+max_command_length = 64`
+	os.WriteFile(configPath, []byte(configContent), 0644)
+
+	daemon, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create daemon: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		command  string
+		expected string
+	}{
+		{"within limit", "m:" + strings.Repeat("x", 40) + "\n", "ERR code=invalid_mode value=" + strings.Repeat("x", 40) + "\n"},
+		{"flood without newline", strings.Repeat("m", 1000), "ERR code=too_long\n"},
+		{"newline past limit", "m:" + strings.Repeat("x", 100) + "\n", "ERR code=too_long\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConn := &MockConn{readData: []byte(tt.command)}
+			daemon.wg.Add(1)
+			daemon.handle(mockConn)
+			if response := string(mockConn.writeData); response != tt.expected {
+				t.Errorf("handle() response = %q, want %q", response, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDaemon_HandleSignals(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+
+	configPath := filepath.Join(tempDir, "hyprvoice", "config.toml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	configContent := `[recording]
+sample_rate = 16000
+channels = 1
+format = "s16"
+buffer_size = 8192
+channel_buffer_size = 30
+timeout = "5m"
+
+[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[injection]
+backends = ["clipboard"]
+ydotool_timeout = "5s"
+wtype_timeout = "5s"
+clipboard_timeout = "3s"
+
+[notifications]
+type = "log"
+`
+	os.WriteFile(configPath, []byte(configContent), 0644)
+
+	daemon, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create daemon: %v", err)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	go func() {
+		daemon.handleSignals(sigCh)
+		close(done)
+	}()
+
+	// SIGHUP picks up config changes without waiting for the file watcher
+	os.WriteFile(configPath, []byte(strings.Replace(configContent, `model = "whisper-1"`, "model = \"whisper-1\"\nlanguage = \"it\"", 1)), 0644)
+	sigCh <- syscall.SIGHUP
+	deadline := time.Now().Add(2 * time.Second)
+	for daemon.configMgr.GetConfig().Transcription.Language != "it" {
+		if time.Now().After(deadline) {
+			t.Fatalf("language = %q after SIGHUP, want reloaded value", daemon.configMgr.GetConfig().Transcription.Language)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sigCh <- syscall.SIGTERM
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handleSignals() did not return after SIGTERM")
+	}
+	if daemon.ctx.Err() == nil {
+		t.Error("SIGTERM should cancel the daemon context")
+	}
+}
+
+func TestDaemon_Run_MemTransport(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	configPath := filepath.Join(configDir, "hyprvoice", "config.toml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	configContent := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[processing]
+mode = "raw"
+
+[notifications]
+enabled = true
+type = "log"`
+	os.WriteFile(configPath, []byte(configContent), 0644)
+
+	restore := bus.SetTransport(testutil.NewMemTransport())
+	defer restore()
+
+	d, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- d.Run() }()
+
+	// Run listens asynchronously, so retry the first command until the accept loop is up
+	var resp string
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err = bus.SendCommand('s')
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("status command failed: %v", err)
+	}
+
+	steps := []struct {
+		name string
+		send func() (string, error)
+		want string
+	}{
+		{"status", func() (string, error) { return bus.SendCommand('s') }, "STATUS status=idle"},
+		{"get mode", func() (string, error) { return bus.SendModeCommand("") }, "MODE mode=raw"},
+		{"set mode", func() (string, error) { return bus.SendModeCommand("llm") }, "OK mode=llm"},
+		{"mode override", func() (string, error) { return bus.SendModeCommand("") }, "MODE mode=llm"},
+		{"reset mode", func() (string, error) { return bus.SendModeCommand("reset") }, "OK mode=raw"},
+		{"invalid mode", func() (string, error) { return bus.SendModeCommand("bogus") }, "ERR code=invalid_mode"},
+		{"cancel", func() (string, error) { return bus.SendCommand('c') }, "OK action=cancelled"},
+		{"quit", func() (string, error) { return bus.SendCommand('q') }, "OK action=quitting"},
+	}
+
+	for _, step := range steps {
+		resp, err = step.send()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if !strings.HasPrefix(resp, step.want) {
+			t.Errorf("%s: response = %q, want prefix %q", step.name, resp, step.want)
+		}
+	}
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not return after quit")
+	}
+
+	if _, err := bus.SendCommand('s'); err == nil {
+		t.Error("expected dialing a stopped daemon to fail")
+	}
+}
+
+func TestDaemon_Handle_ClientHangsUp(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+
+	configPath := filepath.Join(tempDir, "hyprvoice", "config.toml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	configContent := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[notifications]
+type = "log"`
+	os.WriteFile(configPath, []byte(configContent), 0644)
+
+	d, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create daemon: %v", err)
+	}
+
+	server, client := net.Pipe()
+	go func() {
+		client.Write([]byte("m:llm\n"))
+		client.Close()
+	}()
+
+	d.wg.Add(1)
+	done := make(chan struct{})
+	go func() {
+		d.handle(server)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handle() blocked on a client that hung up")
+	}
+
+	// The command still ran, and the daemon keeps serving other clients
+	if got := d.getEffectiveMode(); got != "llm" {
+		t.Errorf("mode after hang-up = %s, want llm", got)
+	}
+	mockConn := &MockConn{readData: []byte("s\n")}
+	d.wg.Add(1)
+	d.handle(mockConn)
+	if got := string(mockConn.writeData); got != "STATUS status=idle\n" {
+		t.Errorf("status after hang-up = %q", got)
+	}
+}
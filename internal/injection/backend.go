@@ -2,6 +2,8 @@ package injection
 
 import (
 	"context"
+	"os/exec"
+	"strings"
 	"time"
 )
 
@@ -11,3 +13,29 @@ type Backend interface {
 	Available() error
 	Inject(ctx context.Context, text string, timeout time.Duration, windowAddress string) error
 }
+
+// commandRunner abstracts external command execution so backends can be tested without the real binaries
+type commandRunner interface {
+	LookPath(file string) (string, error)
+	Run(ctx context.Context, stdin string, name string, args ...string) error
+	Output(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// execRunner runs commands with os/exec
+type execRunner struct{}
+
+func (execRunner) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+func (execRunner) Run(ctx context.Context, stdin string, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	return cmd.Run()
+}
+
+func (execRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output()
+}
@@ -2,28 +2,72 @@ package injection
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
-	"strings"
 	"time"
 )
 
-type clipboardBackend struct{}
+// Clipboard selections the clipboard backend copies into
+const (
+	SelectionClipboard = "clipboard" // Regular Ctrl+V clipboard
+	SelectionPrimary   = "primary"   // PRIMARY selection used by middle-click paste
+	SelectionBoth      = "both"
+)
+
+// BackendClipboardCopy copies into the clipboard and stops there: no focusing, no paste, no keys
+const BackendClipboardCopy = "clipboard-copy"
+
+// DefaultClipboardMIME is the type wl-copy offers the text as; some apps ignore bare text/plain
+const DefaultClipboardMIME = "text/plain;charset=utf-8"
+
+// errClipboardMismatch means wl-paste returned something other than the text just copied
+var errClipboardMismatch = errors.New("clipboard content does not match the copied text")
+
+// verifyCopyAttempts is how often the copy is tried when verification keeps failing
+const verifyCopyAttempts = 2
+
+type clipboardBackend struct {
+	runner    commandRunner
+	windows   WindowManager // nil when the compositor has no window tracking
+	selection string        // SelectionClipboard (default), SelectionPrimary, or SelectionBoth
+	keys      *keyWrap      // Pressed around the automatic paste; nil presses none
+	verify    bool          // Read the clipboard back with wl-paste before pasting
+	mime      string        // Passed to wl-copy --type; empty lets wl-copy guess
+	copyOnly  bool          // Never focus or paste, even when a window address is given
+	delay     time.Duration // Pause between focusing the window and pasting
+}
 
 func NewClipboardBackend() Backend {
-	return &clipboardBackend{}
+	return newClipboardBackend(NewWindowManager(CompositorAuto), SelectionClipboard, nil, false, DefaultClipboardMIME, 0)
+}
+
+func newClipboardBackend(windows WindowManager, selection string, keys *keyWrap, verify bool, mime string, delay time.Duration) *clipboardBackend {
+	return &clipboardBackend{runner: execRunner{}, windows: windows, selection: selection, keys: keys, verify: verify, mime: mime, delay: delay}
+}
+
+// newClipboardCopyBackend returns the clipboard-copy backend, which leaves pasting to the user
+func newClipboardCopyBackend(selection string, verify bool, mime string) *clipboardBackend {
+	return &clipboardBackend{runner: execRunner{}, selection: selection, verify: verify, mime: mime, copyOnly: true}
 }
 
 func (c *clipboardBackend) Name() string {
+	if c.copyOnly {
+		return BackendClipboardCopy
+	}
 	return "clipboard"
 }
 
 func (c *clipboardBackend) Available() error {
-	if _, err := exec.LookPath("wl-copy"); err != nil {
+	if _, err := c.runner.LookPath("wl-copy"); err != nil {
 		return fmt.Errorf("wl-copy not found: %w (install wl-clipboard)", err)
 	}
+	if c.verify {
+		if _, err := c.runner.LookPath("wl-paste"); err != nil {
+			return fmt.Errorf("wl-paste not found: %w (install wl-clipboard or disable verify_clipboard)", err)
+		}
+	}
 
 	if os.Getenv("WAYLAND_DISPLAY") == "" {
 		return fmt.Errorf("WAYLAND_DISPLAY not set - clipboard operations require Wayland session")
@@ -37,30 +81,50 @@ func (c *clipboardBackend) Available() error {
 }
 
 func (c *clipboardBackend) Inject(ctx context.Context, text string, timeout time.Duration, windowAddress string) error {
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout+c.delay)
 	defer cancel()
 
 	if err := c.Available(); err != nil {
 		return err
 	}
+	// Copying already hands the text over, so the turn is claimed before the first wl-copy
+	if err := claimWrite(ctx); err != nil {
+		return err
+	}
 
-	// Copy text to clipboard
-	cmd := exec.CommandContext(ctx, "wl-copy")
-	cmd.Stdin = strings.NewReader(text)
+	if c.selection == SelectionPrimary || c.selection == SelectionBoth {
+		if err := c.runner.Run(ctx, text, "wl-copy", c.copyArgs("--primary")...); err != nil {
+			return fmt.Errorf("wl-copy --primary failed: %w", err)
+		}
+	}
+	if c.selection == SelectionPrimary {
+		// Ctrl+Shift+V pastes CLIPBOARD, so leave PRIMARY for the user's middle-click
+		return nil
+	}
+
+	if err := c.copyToClipboard(ctx, text); err != nil {
+		return err
+	}
+	if c.copyOnly {
+		return nil
+	}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("wl-copy failed: %w", err)
+	if windowAddress == "" && c.keys != nil {
+		log.Printf("Clipboard: copy-only injection, skipping pre/post keys")
 	}
 
 	// If window address is provided, focus the window and paste
 	if windowAddress != "" {
-		if err := c.focusWindow(ctx, windowAddress); err != nil {
+		if err := focusWindow(ctx, c.windows, windowAddress); err != nil {
 			log.Printf("Clipboard: Failed to focus window %s: %v, continuing with clipboard copy only", windowAddress, err)
 			// Don't fail the injection if focusing fails - clipboard copy succeeded
 		} else {
-			// Small delay to ensure window is focused before pasting
-			time.Sleep(100 * time.Millisecond)
-			if err := c.pasteFromClipboard(ctx); err != nil {
+			paste := func() error { return c.pasteFromClipboard(ctx) }
+			err := waitBeforeInject(ctx, c.delay)
+			if err == nil {
+				err = c.keys.wrap(func(combo string) error { return c.pressKey(ctx, combo) }, paste)
+			}
+			if err != nil {
 				log.Printf("Clipboard: Failed to paste: %v, text is still in clipboard", err)
 				// Don't fail the injection if paste fails - clipboard copy succeeded
 			} else {
@@ -72,23 +136,97 @@ func (c *clipboardBackend) Inject(ctx context.Context, text string, timeout time
 	return nil
 }
 
-// focusWindow focuses the specified window using hyprctl
-func (c *clipboardBackend) focusWindow(ctx context.Context, windowAddress string) error {
-	cmd := exec.CommandContext(ctx, "hyprctl", "dispatch", "focuswindow", windowAddress)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("hyprctl focuswindow failed: %w", err)
+// copyToClipboard runs wl-copy. With verify set it reads the clipboard back and copies
+// again on a mismatch, failing rather than letting a paste insert stale content.
+func (c *clipboardBackend) copyToClipboard(ctx context.Context, text string) error {
+	for attempt := 1; ; attempt++ {
+		if err := c.runner.Run(ctx, text, "wl-copy", c.copyArgs()...); err != nil {
+			return fmt.Errorf("wl-copy failed: %w", err)
+		}
+		if !c.verify {
+			return nil
+		}
+
+		err := c.checkClipboard(ctx, text)
+		if err == nil {
+			return nil
+		}
+		if attempt >= verifyCopyAttempts {
+			return fmt.Errorf("clipboard verification failed: %w", err)
+		}
+		log.Printf("Clipboard: %v, copying again", err)
+	}
+}
+
+// copyArgs returns the wl-copy arguments, adding --type when a MIME type is configured
+func (c *clipboardBackend) copyArgs(args ...string) []string {
+	if c.mime != "" {
+		args = append(args, "--type", c.mime)
+	}
+	return args
+}
+
+// checkClipboard compares the clipboard content with text
+func (c *clipboardBackend) checkClipboard(ctx context.Context, text string) error {
+	out, err := c.runner.Output(ctx, "wl-paste", "--no-newline")
+	if err != nil {
+		return fmt.Errorf("wl-paste failed: %w", err)
+	}
+	if string(out) != text {
+		return errClipboardMismatch
+	}
+	return nil
+}
+
+// CopyToClipboard copies text with wl-copy without pasting it anywhere
+func CopyToClipboard(ctx context.Context, text, mime string) error {
+	c := &clipboardBackend{runner: execRunner{}, mime: mime}
+	return c.copyToClipboard(ctx, text)
+}
+
+// CopyOnly reports whether every backend in the chain only copies to the clipboard,
+// so a successful injection means the text is waiting to be pasted by hand
+func CopyOnly(backends []string) bool {
+	for _, name := range backends {
+		if name != BackendClipboardCopy {
+			return false
+		}
+	}
+	return len(backends) > 0
+}
+
+// ClearClipboard empties the clipboard, e.g. so an aborted injection doesn't leave dictated text behind
+func ClearClipboard(ctx context.Context) error {
+	return clearClipboard(ctx, execRunner{})
+}
+
+func clearClipboard(ctx context.Context, runner commandRunner) error {
+	if err := runner.Run(ctx, "", "wl-copy", "--clear"); err != nil {
+		return fmt.Errorf("wl-copy --clear failed: %w", err)
 	}
 	return nil
 }
 
+// pressKey presses a key combo such as "ctrl+l" with wtype, falling back to ydotool
+func (c *clipboardBackend) pressKey(ctx context.Context, combo string) error {
+	if wtypePath, err := c.runner.LookPath("wtype"); err == nil {
+		if err := c.runner.Run(ctx, "", wtypePath, wtypeKeyArgs(combo)...); err == nil {
+			return nil
+		}
+	}
+	if _, err := c.runner.LookPath("ydotool"); err == nil {
+		return c.runner.Run(ctx, "", "ydotool", "key", combo)
+	}
+	return fmt.Errorf("neither wtype nor ydotool could press %q", combo)
+}
+
 // pasteFromClipboard simulates Ctrl+Shift+V to paste from clipboard
 // Uses Ctrl+Shift+V which works in terminals (Ghostty, etc.) and most GUI apps
 func (c *clipboardBackend) pasteFromClipboard(ctx context.Context) error {
 	// Try wtype first (Wayland native)
-	if wtypePath, err := exec.LookPath("wtype"); err == nil {
+	if wtypePath, err := c.runner.LookPath("wtype"); err == nil {
 		// Use Ctrl+Shift+V - works in terminals and most GUI apps
-		cmd := exec.CommandContext(ctx, wtypePath, "-M", "ctrl", "-M", "shift", "v", "-m", "shift", "-m", "ctrl")
-		if err := cmd.Run(); err != nil {
+		if err := c.runner.Run(ctx, "", wtypePath, "-M", "ctrl", "-M", "shift", "v", "-m", "shift", "-m", "ctrl"); err != nil {
 			log.Printf("Clipboard: wtype paste failed: %v, trying ydotool", err)
 		} else {
 			return nil
@@ -96,9 +234,8 @@ func (c *clipboardBackend) pasteFromClipboard(ctx context.Context) error {
 	}
 
 	// Fallback to ydotool
-	if _, err := exec.LookPath("ydotool"); err == nil {
-		cmd := exec.CommandContext(ctx, "ydotool", "key", "ctrl+shift+v")
-		if err := cmd.Run(); err != nil {
+	if _, err := c.runner.LookPath("ydotool"); err == nil {
+		if err := c.runner.Run(ctx, "", "ydotool", "key", "ctrl+shift+v"); err != nil {
 			return fmt.Errorf("ydotool paste failed: %w", err)
 		}
 		return nil
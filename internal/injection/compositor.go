@@ -0,0 +1,53 @@
+package injection
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// Compositor settings for window capture and focus
+const (
+	CompositorAuto     = "auto"     // Detect at runtime
+	CompositorHyprland = "hyprland" // Capture and refocus windows via hyprctl
+	CompositorSway     = "sway"     // Capture and refocus windows via swaymsg
+	CompositorGeneric  = "generic"  // No window tracking; inject into the focused window
+)
+
+var (
+	detectOnce         sync.Once
+	detectedCompositor string
+)
+
+// ResolveCompositor turns a compositor setting into the concrete compositor to use.
+// "auto" (or empty) is detected once per process and cached.
+func ResolveCompositor(setting string) string {
+	if setting != "" && setting != CompositorAuto {
+		return setting
+	}
+
+	detectOnce.Do(func() {
+		detectedCompositor = detectCompositor()
+		log.Printf("Injection: detected compositor %q", detectedCompositor)
+	})
+	return detectedCompositor
+}
+
+func detectCompositor() string {
+	if os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "" {
+		if _, err := exec.LookPath("hyprctl"); err != nil {
+			log.Printf("Injection: Hyprland session detected but hyprctl not found: %v", err)
+		} else {
+			return CompositorHyprland
+		}
+	}
+	if os.Getenv("SWAYSOCK") != "" {
+		if _, err := exec.LookPath("swaymsg"); err != nil {
+			log.Printf("Injection: Sway session detected but swaymsg not found: %v", err)
+		} else {
+			return CompositorSway
+		}
+	}
+	return CompositorGeneric
+}
@@ -0,0 +1,85 @@
+package injection
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File backend write modes
+const (
+	FileModeAppend    = "append"    // Add each dictation as a new line (default)
+	FileModeOverwrite = "overwrite" // Replace the file with the latest dictation
+)
+
+// fileBackend writes the text to a file for editors that watch it, instead of simulating keystrokes.
+// Every write replaces the file atomically, so a watcher never sees a half-written dictation.
+type fileBackend struct {
+	path string // Already environment-expanded
+	mode string // FileModeAppend (default) or FileModeOverwrite
+}
+
+func newFileBackend(path, mode string) *fileBackend {
+	return &fileBackend{path: os.ExpandEnv(path), mode: mode}
+}
+
+func (f *fileBackend) Name() string {
+	return "file"
+}
+
+func (f *fileBackend) Available() error {
+	if f.path == "" {
+		return fmt.Errorf("no file configured (set injection.file_path)")
+	}
+	return nil
+}
+
+func (f *fileBackend) Inject(ctx context.Context, text string, timeout time.Duration, windowAddress string) error {
+	if err := f.Available(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := claimWrite(ctx); err != nil {
+		return err
+	}
+
+	content := []byte(text)
+	if f.mode != FileModeOverwrite {
+		existing, err := os.ReadFile(f.path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("read %s: %w", f.path, err)
+		}
+		content = append(existing, text+"\n"...)
+	}
+	return writeFileAtomic(f.path, content)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename into place: %w", err)
+	}
+	return nil
+}
@@ -2,38 +2,95 @@ package injection
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
+// Injection strategies
+const (
+	StrategySequential = "sequential" // Try backends in order until one succeeds
+	StrategyParallel   = "parallel"   // Prepare all backends at once; they take turns writing until one succeeds
+)
+
+// retryDelay is the pause before attempting the same backend again
+const retryDelay = 200 * time.Millisecond
+
+// Defaults for scaling the typing backends' timeouts with text length
+const (
+	DefaultTimeoutPerChar = 20 * time.Millisecond
+	DefaultMaxTimeout     = 2 * time.Minute
+)
+
+// ErrDeniedWindow is returned when the target window's class is on the deny list
+var ErrDeniedWindow = errors.New("injection denied for window class")
+
 type Injector interface {
 	Inject(ctx context.Context, text string, windowAddress string) error
 }
 
 type Config struct {
-	Backends         []string      // Ordered list: "ydotool", "wtype", "clipboard"
-	YdotoolTimeout   time.Duration // Timeout for ydotool commands
-	WtypeTimeout     time.Duration // Timeout for wtype commands
-	ClipboardTimeout time.Duration // Timeout for clipboard operations
+	Backends           []string      // Ordered list: "ydotool", "wtype", "clipboard", "clipboard-copy", "file"
+	YdotoolTimeout     time.Duration // Timeout for ydotool commands
+	WtypeTimeout       time.Duration // Timeout for wtype commands
+	ClipboardTimeout   time.Duration // Timeout for clipboard operations
+	TimeoutPerChar     time.Duration // Added to the ydotool/wtype timeout per character of text (0 = fixed timeout)
+	MaxTimeout         time.Duration // Cap on the scaled ydotool/wtype timeout (0 = no cap)
+	FocusWindow        bool          // Refocus the recorded window before injecting; false injects into the current window
+	FocusBeforeType    bool          // Let ydotool/wtype refocus the recorded window too, not just clipboard
+	Compositor         string        // "auto", "hyprland", "sway", or "generic" (no window focusing)
+	FocusCommand       string        // Shell command focusing the window in {addr}, replacing hyprctl/swaymsg focus
+	PreInjectDelay     time.Duration // Pause before the first key, typed text or paste (ydotool/wtype/clipboard)
+	KeyboardLayout     string        // XKB layout of the keyboard; ydotool passes on text it would mistype (empty = LayoutUS)
+	Strategy           string        // StrategySequential (default) or StrategyParallel
+	RetriesPerBackend  int           // Attempts per backend before falling through to the next (0 or 1 = no retry)
+	Humanize           bool          // Type character by character with random delays (ydotool/wtype)
+	HumanizeMinDelay   time.Duration // Shortest delay between humanized keystrokes
+	HumanizeMaxDelay   time.Duration // Longest delay between humanized keystrokes
+	DenyClasses        []string      // Window classes that never receive injected text (e.g. password managers)
+	ClipboardSelection string        // SelectionClipboard (default), SelectionPrimary, or SelectionBoth
+	ClipboardMIME      string        // MIME type for wl-copy --type (empty = let wl-copy guess)
+	PreKeys            []string      // Key combos pressed before the text, e.g. "i" or "ctrl+l"
+	PostKeys           []string      // Key combos pressed after the text, e.g. "Escape" or "Return"
+	VerifyClipboard    bool          // Read the clipboard back with wl-paste and refuse to paste on a mismatch
+	FilePath           string        // File the "file" backend writes to ($VARS are expanded)
+	FileMode           string        // FileModeAppend (default) or FileModeOverwrite
 }
 
 type injector struct {
 	config   Config
 	backends []Backend
+	windows  WindowManager // nil when the compositor has no window tracking
 }
 
 func NewInjector(config Config) Injector {
 	// Build backend chain from config
 	backends := make([]Backend, 0, len(config.Backends))
+	jitter := newTypingJitter(config)
+	keys := newKeyWrap(config)
+	windows := withFocusCommand(NewWindowManager(config.Compositor), config.FocusCommand, execRunner{})
+	// Typing backends only refocus the recorded window when focus_before_type is set
+	var typeWindows WindowManager
+	if config.FocusBeforeType {
+		typeWindows = windows
+	}
 	for _, name := range config.Backends {
 		switch name {
 		case "ydotool":
-			backends = append(backends, NewYdotoolBackend())
+			backends = append(backends, &ydotoolBackend{runner: execRunner{}, jitter: jitter, keys: keys, windows: typeWindows, delay: config.PreInjectDelay, layout: config.KeyboardLayout})
 		case "wtype":
-			backends = append(backends, NewWtypeBackend())
+			backends = append(backends, &wtypeBackend{runner: execRunner{}, jitter: jitter, keys: keys, windows: typeWindows, delay: config.PreInjectDelay})
 		case "clipboard":
-			backends = append(backends, NewClipboardBackend())
+			backends = append(backends, newClipboardBackend(windows, config.ClipboardSelection, keys, config.VerifyClipboard, config.ClipboardMIME, config.PreInjectDelay))
+		case BackendClipboardCopy:
+			backends = append(backends, newClipboardCopyBackend(config.ClipboardSelection, config.VerifyClipboard, config.ClipboardMIME))
+		case "file":
+			backends = append(backends, newFileBackend(config.FilePath, config.FileMode))
 		default:
 			log.Printf("Injection: unknown backend %q, skipping", name)
 		}
@@ -42,9 +99,30 @@ func NewInjector(config Config) Injector {
 	// Default to clipboard if no valid backends
 	if len(backends) == 0 {
 		log.Printf("Injection: no valid backends configured, defaulting to clipboard")
-		backends = append(backends, NewClipboardBackend())
+		backends = append(backends, newClipboardBackend(windows, config.ClipboardSelection, keys, config.VerifyClipboard, config.ClipboardMIME, config.PreInjectDelay))
+	}
+
+	injector := newInjectorWithBackends(config, backends)
+	injector.windows = windows
+	return injector
+}
+
+// waitBeforeInject sleeps for delay so a window that just got focus is ready for input.
+// It returns the context's error if the injection is canceled or times out meanwhile.
+func waitBeforeInject(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
+// newInjectorWithBackends creates an injector with an explicit backend chain
+func newInjectorWithBackends(config Config, backends []Backend) *injector {
 	return &injector{
 		config:   config,
 		backends: backends,
@@ -56,31 +134,202 @@ func (i *injector) Inject(ctx context.Context, text string, windowAddress string
 		return fmt.Errorf("cannot inject empty text")
 	}
 
+	// Without window focusing, backends inject into whatever window currently has focus
+	if windowAddress != "" {
+		if !i.config.FocusWindow {
+			log.Printf("Injection: window focusing disabled, injecting into the currently focused window")
+			windowAddress = ""
+		} else if ResolveCompositor(i.config.Compositor) == CompositorGeneric {
+			log.Printf("Injection: window focusing unsupported on generic compositor, injecting into the currently focused window")
+			windowAddress = ""
+		}
+	}
+
+	if err := i.checkDeniedWindow(ctx, windowAddress); err != nil {
+		return err
+	}
+
+	if i.config.Strategy == StrategyParallel {
+		return i.injectParallel(ctx, text, windowAddress)
+	}
+
 	// Try each backend in order
-	var lastErr error
+	var errs []error
 	for _, backend := range i.backends {
-		timeout := i.getTimeout(backend.Name())
-		err := backend.Inject(ctx, text, timeout, windowAddress)
+		err := i.injectWithRetries(ctx, backend, text, windowAddress)
 		if err == nil {
 			log.Printf("Injection: success via %s", backend.Name())
 			return nil
 		}
 		log.Printf("Injection: %s failed: %v, trying next backend", backend.Name(), err)
-		lastErr = err
+		errs = append(errs, fmt.Errorf("%s: %w", backend.Name(), err))
+	}
+
+	return fmt.Errorf("all injection backends failed: %w", errors.Join(errs...))
+}
+
+// checkDeniedWindow refuses injection when the target window's class is on the deny list.
+// The target is the recorded window when it will be refocused, otherwise the focused window.
+func (i *injector) checkDeniedWindow(ctx context.Context, windowAddress string) error {
+	if len(i.config.DenyClasses) == 0 || CopyOnly(i.config.Backends) {
+		return nil // Copying alone never reaches the window
 	}
+	if i.windows == nil {
+		log.Printf("Injection: window classes unavailable on this compositor, deny_classes not enforced")
+		return nil
+	}
+
+	class, err := i.windows.WindowClass(ctx, windowAddress)
+	if err != nil {
+		// Fail closed: the deny list exists to protect sensitive windows
+		return fmt.Errorf("%w: could not determine target window class: %v", ErrDeniedWindow, err)
+	}
+	for _, denied := range i.config.DenyClasses {
+		if strings.EqualFold(class, denied) {
+			log.Printf("Injection: refusing to inject into window class %q", class)
+			return fmt.Errorf("%w %q", ErrDeniedWindow, class)
+		}
+	}
+	return nil
+}
 
-	return fmt.Errorf("all injection backends failed, last error: %w", lastErr)
+// errAlreadyInjected stops a backend that got its turn to write after another one succeeded
+var errAlreadyInjected = errors.New("text already injected by another backend")
+
+// writeGate lets the backends of a parallel injection write one at a time, and none after
+// one of them succeeded, so racing backends never type or paste the text twice
+type writeGate struct {
+	turn chan struct{} // Holds a token while a backend writes
+	done atomic.Bool   // A backend injected the text
 }
 
-func (i *injector) getTimeout(backendName string) time.Duration {
+// writeClaim is one backend's handle on the gate, carried in its context
+type writeClaim struct {
+	gate *writeGate
+	held bool
+}
+
+type writeClaimKey struct{}
+
+// claimWrite must be called by a backend right before its first keystroke, paste, copy or
+// write. Outside a parallel injection it always succeeds. In one it waits for the backend's
+// turn and fails once another backend has injected the text; the turn is kept until the
+// injector releases it, so retries of the same backend don't queue again.
+func claimWrite(ctx context.Context) error {
+	claim, ok := ctx.Value(writeClaimKey{}).(*writeClaim)
+	if !ok || claim.held {
+		return nil
+	}
+	select {
+	case claim.gate.turn <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if claim.gate.done.Load() {
+		<-claim.gate.turn
+		return errAlreadyInjected
+	}
+	claim.held = true
+	return nil
+}
+
+// release ends the backend's turn, closing the gate for good when it injected the text
+func (c *writeClaim) release(injected bool) {
+	if !c.held {
+		return
+	}
+	if injected {
+		c.gate.done.Store(true)
+	}
+	c.held = false
+	<-c.gate.turn
+}
+
+// injectParallel prepares every backend concurrently (availability checks, focusing, the
+// pre-inject delay) and returns on the first success. Writing goes through a writeGate:
+// backends wait for their turn before the first keystroke or paste and give up once one
+// has succeeded, and the rest are canceled then.
+func (i *injector) injectParallel(ctx context.Context, text string, windowAddress string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	gate := &writeGate{turn: make(chan struct{}, 1)}
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, len(i.backends))
+
+	for _, backend := range i.backends {
+		go func(backend Backend) {
+			claim := &writeClaim{gate: gate}
+			// A panicking backend counts as a failed one instead of crashing the daemon
+			defer func() {
+				if r := recover(); r != nil {
+					claim.release(false)
+					log.Printf("Injection: %s panicked: %v\n%s", backend.Name(), r, debug.Stack())
+					results <- result{name: backend.Name(), err: fmt.Errorf("panic: %v", r)}
+				}
+			}()
+			err := i.injectWithRetries(context.WithValue(ctx, writeClaimKey{}, claim), backend, text, windowAddress)
+			claim.release(err == nil)
+			results <- result{name: backend.Name(), err: err}
+		}(backend)
+	}
+
+	var errs []error
+	for range i.backends {
+		res := <-results
+		if res.err == nil {
+			cancel()
+			log.Printf("Injection: success via %s, canceling remaining backends", res.name)
+			return nil
+		}
+		log.Printf("Injection: %s failed: %v", res.name, res.err)
+		errs = append(errs, fmt.Errorf("%s: %w", res.name, res.err))
+	}
+
+	return fmt.Errorf("all injection backends failed: %w", errors.Join(errs...))
+}
+
+// injectWithRetries attempts backend up to RetriesPerBackend times. It gives up early when the
+// context ends, the backend is not available at all or can't type the text, since retrying can't help.
+func (i *injector) injectWithRetries(ctx context.Context, backend Backend, text string, windowAddress string) error {
+	attempts := max(i.config.RetriesPerBackend, 1)
+	for attempt := 1; ; attempt++ {
+		err := backend.Inject(ctx, text, i.getTimeout(backend.Name(), text), windowAddress)
+		if err == nil || attempt >= attempts || ctx.Err() != nil || errors.Is(err, errLayoutMismatch) || errors.Is(err, errAlreadyInjected) || backend.Available() != nil {
+			return err
+		}
+		log.Printf("Injection: %s attempt %d/%d failed: %v, retrying", backend.Name(), attempt, attempts, err)
+
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// getTimeout returns the backend's timeout for text. Typing backends get TimeoutPerChar
+// extra per character, capped at MaxTimeout but never below their configured timeout;
+// a clipboard copy takes the same time whatever the length.
+func (i *injector) getTimeout(backendName string, text string) time.Duration {
+	var base time.Duration
 	switch backendName {
 	case "ydotool":
-		return i.config.YdotoolTimeout
+		base = i.config.YdotoolTimeout
 	case "wtype":
-		return i.config.WtypeTimeout
-	case "clipboard":
+		base = i.config.WtypeTimeout
+	case "clipboard", BackendClipboardCopy:
 		return i.config.ClipboardTimeout
 	default:
 		return 5 * time.Second
 	}
+
+	timeout := base + i.config.TimeoutPerChar*time.Duration(utf8.RuneCountInString(text))
+	if i.config.MaxTimeout > 0 && timeout > i.config.MaxTimeout {
+		timeout = max(i.config.MaxTimeout, base)
+	}
+	return timeout
 }
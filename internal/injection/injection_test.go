@@ -2,7 +2,12 @@ package injection
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -23,7 +28,7 @@ func TestNewInjector(t *testing.T) {
 
 	// Test that the injector works with the expected config
 	ctx := context.Background()
-	err := injector.Inject(ctx, "test")
+	err := injector.Inject(ctx, "test", "")
 	// We expect this to fail due to missing external tools, but it should be the right type of error
 	if err != nil {
 		t.Logf("Injector created successfully (failed as expected due to missing tools): %v", err)
@@ -44,7 +49,7 @@ func TestNewInjector_DefaultsToClipboard(t *testing.T) {
 
 	// Should default to clipboard backend - just test it works
 	ctx := context.Background()
-	err := injector.Inject(ctx, "test")
+	err := injector.Inject(ctx, "test", "")
 	// Will fail if no clipboard tools, but that's ok
 	if err != nil {
 		t.Logf("Injection failed (expected without tools): %v", err)
@@ -122,7 +127,7 @@ func TestInjector_Inject(t *testing.T) {
 			injector := NewInjector(tt.config)
 			ctx := context.Background()
 
-			err := injector.Inject(ctx, tt.text)
+			err := injector.Inject(ctx, tt.text, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Inject() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -213,7 +218,7 @@ func TestInjector_ClipboardMode(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	err := injector.Inject(ctx, "test clipboard text")
+	err := injector.Inject(ctx, "test clipboard text", "")
 	if err != nil {
 		t.Logf("Clipboard injection failed (expected if clipboard tools not available): %v", err)
 		return
@@ -233,7 +238,7 @@ func TestInjector_WtypeMode(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	err := injector.Inject(ctx, "test typing text")
+	err := injector.Inject(ctx, "test typing text", "")
 	if err != nil {
 		t.Logf("Wtype injection failed (expected if wtype not available): %v", err)
 		return
@@ -255,7 +260,7 @@ func TestInjector_FallbackChain(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	err := injector.Inject(ctx, "test fallback text")
+	err := injector.Inject(ctx, "test fallback text", "")
 	if err != nil {
 		t.Logf("Fallback injection failed (expected if all tools not available): %v", err)
 		return
@@ -274,7 +279,7 @@ func TestInjector_EmptyText(t *testing.T) {
 	injector := NewInjector(config)
 	ctx := context.Background()
 
-	err := injector.Inject(ctx, "")
+	err := injector.Inject(ctx, "", "")
 	if err == nil {
 		t.Errorf("Inject() should fail with empty text")
 		return
@@ -284,3 +289,1147 @@ func TestInjector_EmptyText(t *testing.T) {
 		t.Errorf("Inject() error message = %q, want %q", err.Error(), "cannot inject empty text")
 	}
 }
+
+// fakeBackend is a configurable Backend that records how it was called
+type fakeBackend struct {
+	name        string
+	err         error
+	flaky       int   // when > 0, only the first flaky calls return err
+	unavailable error // returned by Available
+
+	calls      int
+	gotText    string
+	gotTimeout time.Duration
+	gotWindow  string
+}
+
+func (f *fakeBackend) Name() string     { return f.name }
+func (f *fakeBackend) Available() error { return f.unavailable }
+
+func (f *fakeBackend) Inject(ctx context.Context, text string, timeout time.Duration, windowAddress string) error {
+	f.calls++
+	f.gotText = text
+	f.gotTimeout = timeout
+	f.gotWindow = windowAddress
+	if f.flaky > 0 && f.calls > f.flaky {
+		return nil
+	}
+	return f.err
+}
+
+// blockingBackend hangs until its context is canceled, like ydotool on a stale socket
+type blockingBackend struct {
+	name     string
+	canceled chan struct{}
+}
+
+func (b *blockingBackend) Name() string     { return b.name }
+func (b *blockingBackend) Available() error { return nil }
+
+func (b *blockingBackend) Inject(ctx context.Context, text string, timeout time.Duration, windowAddress string) error {
+	<-ctx.Done()
+	close(b.canceled)
+	return ctx.Err()
+}
+
+// fakeWindowManager reports a fixed window class
+type fakeWindowManager struct {
+	class    string
+	err      error
+	gotClass string // address passed to WindowClass
+}
+
+func (f *fakeWindowManager) Name() string { return "fake" }
+func (f *fakeWindowManager) ActiveWindow(ctx context.Context) (string, error) {
+	return "0xabc", nil
+}
+func (f *fakeWindowManager) FocusWindow(ctx context.Context, address string) error { return nil }
+func (f *fakeWindowManager) WindowClass(ctx context.Context, address string) (string, error) {
+	f.gotClass = address
+	return f.class, f.err
+}
+
+// fakeRunner is a commandRunner that records commands instead of executing them
+type fakeRunner struct {
+	missing  map[string]bool   // binaries LookPath reports as missing
+	failures map[string]error  // command name -> error returned by Run
+	outputs  map[string][]byte // command name -> stdout returned by Output
+	commands []string
+	stdin    []string
+}
+
+func (f *fakeRunner) LookPath(file string) (string, error) {
+	if f.missing[file] {
+		return "", fmt.Errorf("exec: %q: executable file not found in $PATH", file)
+	}
+	return file, nil
+}
+
+func (f *fakeRunner) Run(ctx context.Context, stdin string, name string, args ...string) error {
+	f.commands = append(f.commands, strings.Join(append([]string{name}, args...), " "))
+	f.stdin = append(f.stdin, stdin)
+	return f.failures[name]
+}
+
+func (f *fakeRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	f.commands = append(f.commands, strings.Join(append([]string{name}, args...), " "))
+	return f.outputs[name], f.failures[name]
+}
+
+// setWaylandEnv makes the session environment checks in Available() pass
+func setWaylandEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("WAYLAND_DISPLAY", "wayland-test")
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+}
+
+func testInjectionConfig() Config {
+	return Config{
+		YdotoolTimeout:   1 * time.Second,
+		WtypeTimeout:     2 * time.Second,
+		ClipboardTimeout: 3 * time.Second,
+		FocusWindow:      true,
+		Compositor:       CompositorHyprland,
+	}
+}
+
+func TestInjector_FirstBackendSucceeds(t *testing.T) {
+	first := &fakeBackend{name: "ydotool"}
+	second := &fakeBackend{name: "wtype"}
+	injector := newInjectorWithBackends(testInjectionConfig(), []Backend{first, second})
+
+	if err := injector.Inject(context.Background(), "hello", "0xabc"); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+
+	if first.calls != 1 || first.gotText != "hello" {
+		t.Errorf("first backend calls = %d, text = %q, want 1 call with %q", first.calls, first.gotText, "hello")
+	}
+	if second.calls != 0 {
+		t.Errorf("second backend should not be called when the first succeeds, got %d calls", second.calls)
+	}
+}
+
+func TestInjector_FallsBackToNextBackend(t *testing.T) {
+	first := &fakeBackend{name: "ydotool", err: errors.New("socket missing")}
+	second := &fakeBackend{name: "wtype"}
+	injector := newInjectorWithBackends(testInjectionConfig(), []Backend{first, second})
+
+	if err := injector.Inject(context.Background(), "hello", ""); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+
+	if first.calls != 1 || second.calls != 1 {
+		t.Errorf("calls = (%d, %d), want (1, 1)", first.calls, second.calls)
+	}
+}
+
+func TestInjector_RetriesPerBackend(t *testing.T) {
+	config := testInjectionConfig()
+	config.RetriesPerBackend = 2
+
+	first := &fakeBackend{name: "ydotool", err: errors.New("socket busy"), flaky: 1}
+	second := &fakeBackend{name: "wtype"}
+	injector := newInjectorWithBackends(config, []Backend{first, second})
+	if err := injector.Inject(context.Background(), "hello", ""); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+	if first.calls != 2 || second.calls != 0 {
+		t.Errorf("calls = (%d, %d), want a retry of the first backend and no fallback", first.calls, second.calls)
+	}
+
+	// A backend that isn't available is not retried
+	first = &fakeBackend{name: "ydotool", err: errors.New("socket missing"), unavailable: errors.New("ydotoold not running")}
+	second = &fakeBackend{name: "wtype"}
+	injector = newInjectorWithBackends(config, []Backend{first, second})
+	if err := injector.Inject(context.Background(), "hello", ""); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+	if first.calls != 1 || second.calls != 1 {
+		t.Errorf("calls = (%d, %d), want (1, 1)", first.calls, second.calls)
+	}
+}
+
+func TestInjector_AllBackendsFail(t *testing.T) {
+	errYdotool := errors.New("socket missing")
+	errWtype := errors.New("compositor rejected virtual keyboard")
+	injector := newInjectorWithBackends(testInjectionConfig(), []Backend{
+		&fakeBackend{name: "ydotool", err: errYdotool},
+		&fakeBackend{name: "wtype", err: errWtype},
+	})
+
+	err := injector.Inject(context.Background(), "hello", "")
+	if err == nil {
+		t.Fatal("Inject() should fail when every backend fails")
+	}
+
+	if !errors.Is(err, errYdotool) || !errors.Is(err, errWtype) {
+		t.Errorf("Inject() error should wrap every backend error, got %v", err)
+	}
+	for _, want := range []string{"ydotool: socket missing", "wtype: compositor rejected virtual keyboard"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Inject() error %q should contain %q", err.Error(), want)
+		}
+	}
+}
+
+func TestInjector_RejectsEmptyTextWithoutCallingBackends(t *testing.T) {
+	backend := &fakeBackend{name: "clipboard"}
+	injector := newInjectorWithBackends(testInjectionConfig(), []Backend{backend})
+
+	if err := injector.Inject(context.Background(), "", ""); err == nil {
+		t.Error("Inject() should reject empty text")
+	}
+	if backend.calls != 0 {
+		t.Errorf("backend called %d times for empty text, want 0", backend.calls)
+	}
+}
+
+func TestInjector_PerBackendTimeouts(t *testing.T) {
+	config := testInjectionConfig()
+	tests := []struct {
+		name string
+		want time.Duration
+	}{
+		{"ydotool", config.YdotoolTimeout},
+		{"wtype", config.WtypeTimeout},
+		{"clipboard", config.ClipboardTimeout},
+		{"unknown", 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{name: tt.name}
+			injector := newInjectorWithBackends(config, []Backend{backend})
+
+			if err := injector.Inject(context.Background(), "hello", ""); err != nil {
+				t.Fatalf("Inject() error = %v", err)
+			}
+			if backend.gotTimeout != tt.want {
+				t.Errorf("timeout = %v, want %v", backend.gotTimeout, tt.want)
+			}
+		})
+	}
+}
+
+func TestInjector_TimeoutScalesWithText(t *testing.T) {
+	config := testInjectionConfig()
+	config.YdotoolTimeout = 5 * time.Second
+	config.ClipboardTimeout = 3 * time.Second
+	config.TimeoutPerChar = 10 * time.Millisecond
+	config.MaxTimeout = 20 * time.Second
+
+	tests := []struct {
+		name    string
+		backend string
+		text    string
+		want    time.Duration
+	}{
+		{"short text", "ydotool", "hello", 5*time.Second + 50*time.Millisecond},
+		{"counts characters not bytes", "ydotool", "héllo", 5*time.Second + 50*time.Millisecond},
+		{"long text", "ydotool", strings.Repeat("a", 1000), 15 * time.Second},
+		{"capped", "ydotool", strings.Repeat("a", 5000), 20 * time.Second},
+		{"clipboard stays fixed", "clipboard", strings.Repeat("a", 1000), 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{name: tt.backend}
+			injector := newInjectorWithBackends(config, []Backend{backend})
+
+			if err := injector.Inject(context.Background(), tt.text, ""); err != nil {
+				t.Fatalf("Inject() error = %v", err)
+			}
+			if backend.gotTimeout != tt.want {
+				t.Errorf("timeout = %v, want %v", backend.gotTimeout, tt.want)
+			}
+		})
+	}
+
+	// The cap never shortens a backend's own timeout
+	config.MaxTimeout = time.Second
+	injector := &injector{config: config}
+	if got := injector.getTimeout("ydotool", "hi"); got != 5*time.Second {
+		t.Errorf("getTimeout() with cap below base = %v, want 5s", got)
+	}
+}
+
+func TestInjector_FocusWindowDisabled(t *testing.T) {
+	backend := &fakeBackend{name: "clipboard"}
+	config := testInjectionConfig()
+	config.FocusWindow = false
+	injector := newInjectorWithBackends(config, []Backend{backend})
+
+	if err := injector.Inject(context.Background(), "hello", "0xabc"); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+	if backend.gotWindow != "" {
+		t.Errorf("window address = %q, want empty when focus_window is disabled", backend.gotWindow)
+	}
+}
+
+func TestInjector_ParallelFirstSuccessCancelsOthers(t *testing.T) {
+	slow := &blockingBackend{name: "ydotool", canceled: make(chan struct{})}
+	fast := &fakeBackend{name: "wtype"}
+	config := testInjectionConfig()
+	config.Strategy = StrategyParallel
+	injector := newInjectorWithBackends(config, []Backend{slow, fast})
+
+	if err := injector.Inject(context.Background(), "hello", ""); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+
+	select {
+	case <-slow.canceled:
+	case <-time.After(time.Second):
+		t.Fatal("slow backend was not canceled after another backend succeeded")
+	}
+	if fast.calls != 1 {
+		t.Errorf("fast backend calls = %d, want 1", fast.calls)
+	}
+}
+
+// writingBackend claims its turn like the real backends and records the text it "typed"
+type writingBackend struct {
+	name   string
+	mu     *sync.Mutex
+	output *[]string
+}
+
+func (w *writingBackend) Name() string     { return w.name }
+func (w *writingBackend) Available() error { return nil }
+func (w *writingBackend) Inject(ctx context.Context, text string, timeout time.Duration, windowAddress string) error {
+	if err := claimWrite(ctx); err != nil {
+		return err
+	}
+	time.Sleep(10 * time.Millisecond) // Typing takes a while, so both backends are ready by now
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	*w.output = append(*w.output, w.name+": "+text)
+	return nil
+}
+
+func TestInjector_ParallelWritesOnce(t *testing.T) {
+	var mu sync.Mutex
+	var output []string
+	config := testInjectionConfig()
+	config.Strategy = StrategyParallel
+	injector := newInjectorWithBackends(config, []Backend{
+		&writingBackend{name: "ydotool", mu: &mu, output: &output},
+		&writingBackend{name: "wtype", mu: &mu, output: &output},
+		&writingBackend{name: "clipboard", mu: &mu, output: &output},
+	})
+
+	for range 20 {
+		output = nil
+		if err := injector.Inject(context.Background(), "hello", ""); err != nil {
+			t.Fatalf("Inject() error = %v", err)
+		}
+		time.Sleep(30 * time.Millisecond) // Let the losers finish so a late write would show up
+		mu.Lock()
+		got := len(output)
+		mu.Unlock()
+		if got != 1 {
+			t.Fatalf("text written %d times (%v), want exactly once", got, output)
+		}
+	}
+}
+
+// panickingBackend simulates a bug in backend code
+type panickingBackend struct{ name string }
+
+func (p *panickingBackend) Name() string     { return p.name }
+func (p *panickingBackend) Available() error { return nil }
+func (p *panickingBackend) Inject(ctx context.Context, text string, timeout time.Duration, windowAddress string) error {
+	panic("backend bug")
+}
+
+func TestInjector_ParallelRecoversPanic(t *testing.T) {
+	config := testInjectionConfig()
+	config.Strategy = StrategyParallel
+	fallback := &fakeBackend{name: "clipboard", err: errors.New("no wl-copy")}
+	injector := newInjectorWithBackends(config, []Backend{&panickingBackend{name: "wtype"}, fallback})
+
+	err := injector.Inject(context.Background(), "hello", "")
+	if err == nil || !strings.Contains(err.Error(), "panic: backend bug") {
+		t.Errorf("Inject() error = %v, want the panic reported as a backend failure", err)
+	}
+}
+
+func TestInjector_ParallelAllFail(t *testing.T) {
+	errYdotool := errors.New("socket missing")
+	errWtype := errors.New("compositor rejected virtual keyboard")
+	config := testInjectionConfig()
+	config.Strategy = StrategyParallel
+	injector := newInjectorWithBackends(config, []Backend{
+		&fakeBackend{name: "ydotool", err: errYdotool},
+		&fakeBackend{name: "wtype", err: errWtype},
+	})
+
+	err := injector.Inject(context.Background(), "hello", "")
+	if err == nil {
+		t.Fatal("Inject() should fail when every backend fails")
+	}
+	if !errors.Is(err, errYdotool) || !errors.Is(err, errWtype) {
+		t.Errorf("Inject() error = %v, want both backend errors joined", err)
+	}
+}
+
+func TestInjector_DenyClasses(t *testing.T) {
+	tests := []struct {
+		name       string
+		class      string
+		classErr   error
+		wantDenied bool
+	}{
+		{name: "allowed class", class: "kitty"},
+		{name: "denied class", class: "org.keepassxc.KeePassXC", wantDenied: true},
+		{name: "denied class ignores case", class: "BITWARDEN", wantDenied: true},
+		{name: "unknown class fails closed", classErr: errors.New("hyprctl not responding"), wantDenied: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{name: "wtype"}
+			config := testInjectionConfig()
+			config.DenyClasses = []string{"org.keepassxc.KeePassXC", "Bitwarden"}
+			injector := newInjectorWithBackends(config, []Backend{backend})
+			windows := &fakeWindowManager{class: tt.class, err: tt.classErr}
+			injector.windows = windows
+
+			err := injector.Inject(context.Background(), "hunter2", "0xabc")
+			if got := errors.Is(err, ErrDeniedWindow); got != tt.wantDenied {
+				t.Fatalf("Inject() error = %v, want denied = %v", err, tt.wantDenied)
+			}
+			if tt.wantDenied && backend.calls != 0 {
+				t.Errorf("backend called %d times, want no injection into a denied window", backend.calls)
+			}
+			if windows.gotClass != "0xabc" {
+				t.Errorf("class looked up for %q, want the recorded window", windows.gotClass)
+			}
+		})
+	}
+}
+
+func TestInjector_DenyClassesWithoutWindowManager(t *testing.T) {
+	backend := &fakeBackend{name: "wtype"}
+	config := testInjectionConfig()
+	config.DenyClasses = []string{"Bitwarden"}
+	injector := newInjectorWithBackends(config, []Backend{backend})
+
+	if err := injector.Inject(context.Background(), "hello", ""); err != nil {
+		t.Fatalf("Inject() error = %v, want deny list skipped without window tracking", err)
+	}
+}
+
+func TestInjector_GenericCompositorSkipsFocus(t *testing.T) {
+	backend := &fakeBackend{name: "clipboard"}
+	config := testInjectionConfig()
+	config.Compositor = CompositorGeneric
+	injector := newInjectorWithBackends(config, []Backend{backend})
+
+	if err := injector.Inject(context.Background(), "hello", "0xabc"); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+	if backend.gotWindow != "" {
+		t.Errorf("window address = %q, want empty on a generic compositor", backend.gotWindow)
+	}
+}
+
+func TestResolveCompositor_ExplicitSetting(t *testing.T) {
+	for _, setting := range []string{CompositorHyprland, CompositorGeneric} {
+		if got := ResolveCompositor(setting); got != setting {
+			t.Errorf("ResolveCompositor(%q) = %q, want %q", setting, got, setting)
+		}
+	}
+}
+
+func TestDetectCompositor(t *testing.T) {
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "")
+	t.Setenv("SWAYSOCK", "")
+	if got := detectCompositor(); got != CompositorGeneric {
+		t.Errorf("detectCompositor() without Hyprland = %q, want %q", got, CompositorGeneric)
+	}
+
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "test")
+	t.Setenv("PATH", t.TempDir())
+	if got := detectCompositor(); got != CompositorGeneric {
+		t.Errorf("detectCompositor() without hyprctl = %q, want %q", got, CompositorGeneric)
+	}
+}
+
+func TestWtypeBackend_WithFakeRunner(t *testing.T) {
+	setWaylandEnv(t)
+	runner := &fakeRunner{}
+	backend := &wtypeBackend{runner: runner}
+
+	if err := backend.Inject(context.Background(), "hello world", time.Second, ""); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+
+	want := []string{"wtype -- hello world"}
+	if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+		t.Errorf("commands = %v, want %v", runner.commands, want)
+	}
+}
+
+func TestWtypeBackend_MissingBinary(t *testing.T) {
+	setWaylandEnv(t)
+	runner := &fakeRunner{missing: map[string]bool{"wtype": true}}
+	backend := &wtypeBackend{runner: runner}
+
+	if err := backend.Inject(context.Background(), "hello", time.Second, ""); err == nil {
+		t.Error("Inject() should fail when wtype is missing")
+	}
+	if len(runner.commands) != 0 {
+		t.Errorf("no command should run when wtype is missing, got %v", runner.commands)
+	}
+}
+
+func TestWtypeBackend_Humanized(t *testing.T) {
+	setWaylandEnv(t)
+	runner := &fakeRunner{}
+	backend := &wtypeBackend{runner: runner, jitter: &typingJitter{minDelay: time.Millisecond, maxDelay: 2 * time.Millisecond}}
+
+	if err := backend.Inject(context.Background(), "hé!", time.Second, ""); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+
+	want := []string{"wtype -- h", "wtype -- é", "wtype -- !"}
+	if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+		t.Errorf("commands = %v, want %v", runner.commands, want)
+	}
+}
+
+func TestWtypeBackend_PreInjectDelay(t *testing.T) {
+	setWaylandEnv(t)
+	runner := &fakeRunner{}
+	backend := &wtypeBackend{runner: runner, delay: 20 * time.Millisecond}
+
+	start := time.Now()
+	if err := backend.Inject(context.Background(), "hello", time.Second, ""); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < backend.delay {
+		t.Errorf("Inject() returned after %v, want at least the %v pre-inject delay", elapsed, backend.delay)
+	}
+
+	// A canceled injection stops waiting and never types
+	runner.commands = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	backend.delay = time.Minute
+	if err := backend.Inject(ctx, "hello", time.Second, ""); err == nil {
+		t.Error("Inject() should fail when canceled during the pre-inject delay")
+	}
+	if len(runner.commands) != 0 {
+		t.Errorf("no command should run after cancellation, got %v", runner.commands)
+	}
+}
+
+func TestTypingJitter_Delay(t *testing.T) {
+	jitter := &typingJitter{minDelay: 10 * time.Millisecond, maxDelay: 20 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		if d := jitter.delay(); d < jitter.minDelay || d > jitter.maxDelay {
+			t.Fatalf("delay() = %v, want within [%v, %v]", d, jitter.minDelay, jitter.maxDelay)
+		}
+	}
+
+	if got := jitter.maxDuration("abc"); got != 60*time.Millisecond {
+		t.Errorf("maxDuration() = %v, want 60ms", got)
+	}
+}
+
+func TestTypingJitter_StopsWhenCanceled(t *testing.T) {
+	jitter := &typingJitter{minDelay: time.Hour, maxDelay: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var typed []string
+	err := jitter.typeText(ctx, "abc", func(char string) error {
+		typed = append(typed, char)
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("typeText() error = %v, want %v", err, context.Canceled)
+	}
+	if len(typed) != 1 {
+		t.Errorf("typed = %v, want only the first character before canceling", typed)
+	}
+}
+
+func TestNewTypingJitter_Disabled(t *testing.T) {
+	if jitter := newTypingJitter(testInjectionConfig()); jitter != nil {
+		t.Errorf("newTypingJitter() = %+v, want nil when humanize is off", jitter)
+	}
+}
+
+func TestYdotoolBackend_WithFakeRunner(t *testing.T) {
+	socket := t.TempDir() + "/ydotool_socket"
+	if err := os.WriteFile(socket, nil, 0600); err != nil {
+		t.Fatalf("failed to create fake socket: %v", err)
+	}
+	t.Setenv("YDOTOOL_SOCKET", socket)
+
+	runner := &fakeRunner{}
+	backend := &ydotoolBackend{runner: runner}
+
+	if err := backend.Inject(context.Background(), "hello", time.Second, ""); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+
+	want := []string{"ydotool type -- hello"}
+	if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+		t.Errorf("commands = %v, want %v", runner.commands, want)
+	}
+}
+
+func TestYdotoolBackend_KeyboardLayout(t *testing.T) {
+	socket := t.TempDir() + "/ydotool_socket"
+	if err := os.WriteFile(socket, nil, 0600); err != nil {
+		t.Fatalf("failed to create fake socket: %v", err)
+	}
+	t.Setenv("YDOTOOL_SOCKET", socket)
+
+	tests := []struct {
+		layout   string
+		text     string
+		wantType bool
+	}{
+		{layout: "us", text: "user@example.com: done!", wantType: true},
+		{layout: "de", text: "Hallo Welt, alles klar.", wantType: true},
+		{layout: "de", text: "Zeit", wantType: false},
+		{layout: "de", text: "a/b", wantType: false},
+		{layout: "fr", text: "bonjour", wantType: true},
+		{layout: "fr", text: "merci", wantType: false},
+		{layout: "gb", text: "Hello world 42.", wantType: true},
+		{layout: "gb", text: "50%", wantType: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout+"/"+tt.text, func(t *testing.T) {
+			runner := &fakeRunner{}
+			backend := &ydotoolBackend{runner: runner, layout: tt.layout}
+			err := backend.Inject(context.Background(), tt.text, time.Second, "")
+			if tt.wantType {
+				if err != nil {
+					t.Fatalf("Inject() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, errLayoutMismatch) {
+				t.Errorf("Inject() error = %v, want errLayoutMismatch", err)
+			}
+			if len(runner.commands) != 0 {
+				t.Errorf("nothing should be typed, got %v", runner.commands)
+			}
+		})
+	}
+}
+
+func TestTypingBackends_FocusBeforeType(t *testing.T) {
+	t.Run("wtype", func(t *testing.T) {
+		setWaylandEnv(t)
+		runner := &fakeRunner{}
+		backend := &wtypeBackend{runner: runner, windows: &hyprlandWindowManager{runner: runner}}
+		if err := backend.Inject(context.Background(), "hello", time.Second, "0xabc"); err != nil {
+			t.Fatalf("Inject() error = %v", err)
+		}
+		want := []string{"hyprctl dispatch focuswindow 0xabc", "wtype -- hello"}
+		if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+			t.Errorf("commands = %v, want %v", runner.commands, want)
+		}
+	})
+
+	t.Run("ydotool", func(t *testing.T) {
+		socket := t.TempDir() + "/ydotool_socket"
+		if err := os.WriteFile(socket, nil, 0600); err != nil {
+			t.Fatalf("failed to create fake socket: %v", err)
+		}
+		t.Setenv("YDOTOOL_SOCKET", socket)
+		runner := &fakeRunner{}
+		backend := &ydotoolBackend{runner: runner, windows: &swayWindowManager{runner: runner}}
+		if err := backend.Inject(context.Background(), "hello", time.Second, "42"); err != nil {
+			t.Fatalf("Inject() error = %v", err)
+		}
+		want := []string{"swaymsg [con_id=42] focus", "ydotool type -- hello"}
+		if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+			t.Errorf("commands = %v, want %v", runner.commands, want)
+		}
+	})
+
+	t.Run("focus failure still types", func(t *testing.T) {
+		setWaylandEnv(t)
+		runner := &fakeRunner{failures: map[string]error{"hyprctl": errors.New("no such window")}}
+		backend := &wtypeBackend{runner: runner, windows: &hyprlandWindowManager{runner: runner}}
+		if err := backend.Inject(context.Background(), "hello", time.Second, "0xabc"); err != nil {
+			t.Fatalf("Inject() error = %v", err)
+		}
+		if last := runner.commands[len(runner.commands)-1]; last != "wtype -- hello" {
+			t.Errorf("last command = %q, want typing after the failed focus", last)
+		}
+	})
+
+	t.Run("disabled ignores window address", func(t *testing.T) {
+		setWaylandEnv(t)
+		runner := &fakeRunner{}
+		backend := &wtypeBackend{runner: runner}
+		if err := backend.Inject(context.Background(), "hello", time.Second, "0xabc"); err != nil {
+			t.Fatalf("Inject() error = %v", err)
+		}
+		want := []string{"wtype -- hello"}
+		if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+			t.Errorf("commands = %v, want %v", runner.commands, want)
+		}
+	})
+}
+
+func TestFileBackend(t *testing.T) {
+	t.Run("append", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nested", "dictation.txt")
+		backend := newFileBackend(path, FileModeAppend)
+		for _, text := range []string{"first", "second"} {
+			if err := backend.Inject(context.Background(), text, time.Second, ""); err != nil {
+				t.Fatalf("Inject() error = %v", err)
+			}
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if string(got) != "first\nsecond\n" {
+			t.Errorf("file = %q, want one dictation per line", got)
+		}
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "dictation.txt")
+		backend := newFileBackend(path, FileModeOverwrite)
+		for _, text := range []string{"first", "second"} {
+			if err := backend.Inject(context.Background(), text, time.Second, ""); err != nil {
+				t.Fatalf("Inject() error = %v", err)
+			}
+		}
+		got, _ := os.ReadFile(path)
+		if string(got) != "second" {
+			t.Errorf("file = %q, want only the latest dictation", got)
+		}
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		if len(entries) != 1 {
+			t.Errorf("directory has %d entries, want no leftover temp files", len(entries))
+		}
+	})
+
+	t.Run("expands env", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("HYPRVOICE_TEST_DIR", dir)
+		backend := newFileBackend("$HYPRVOICE_TEST_DIR/out.txt", FileModeOverwrite)
+		if err := backend.Inject(context.Background(), "hi", time.Second, ""); err != nil {
+			t.Fatalf("Inject() error = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "out.txt")); err != nil {
+			t.Errorf("expanded path not written: %v", err)
+		}
+	})
+
+	t.Run("no path", func(t *testing.T) {
+		if err := newFileBackend("", FileModeAppend).Available(); err == nil {
+			t.Error("Available() should fail without a file path")
+		}
+	})
+}
+
+func TestClipboardBackend_CopyOnly(t *testing.T) {
+	setWaylandEnv(t)
+	runner := &fakeRunner{}
+	backend := &clipboardBackend{runner: runner}
+
+	if err := backend.Inject(context.Background(), "copied text", time.Second, ""); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+
+	if fmt.Sprint(runner.commands) != fmt.Sprint([]string{"wl-copy"}) {
+		t.Errorf("commands = %v, want only wl-copy", runner.commands)
+	}
+	if runner.stdin[0] != "copied text" {
+		t.Errorf("wl-copy stdin = %q, want %q", runner.stdin[0], "copied text")
+	}
+}
+
+func TestClipboardBackend_Selection(t *testing.T) {
+	tests := []struct {
+		selection string
+		want      []string
+	}{
+		{"", []string{"wl-copy"}},
+		{SelectionClipboard, []string{"wl-copy"}},
+		{SelectionPrimary, []string{"wl-copy --primary"}},
+		{SelectionBoth, []string{"wl-copy --primary", "wl-copy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selection, func(t *testing.T) {
+			setWaylandEnv(t)
+			runner := &fakeRunner{}
+			backend := &clipboardBackend{runner: runner, selection: tt.selection}
+
+			if err := backend.Inject(context.Background(), "copied text", time.Second, ""); err != nil {
+				t.Fatalf("Inject() error = %v", err)
+			}
+			if fmt.Sprint(runner.commands) != fmt.Sprint(tt.want) {
+				t.Errorf("commands = %v, want %v", runner.commands, tt.want)
+			}
+		})
+	}
+}
+
+func TestClipboardBackend_MIME(t *testing.T) {
+	setWaylandEnv(t)
+	runner := &fakeRunner{}
+	backend := &clipboardBackend{runner: runner, selection: SelectionBoth, mime: DefaultClipboardMIME}
+
+	if err := backend.Inject(context.Background(), "copied text", time.Second, ""); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+	want := []string{"wl-copy --primary --type text/plain;charset=utf-8", "wl-copy --type text/plain;charset=utf-8"}
+	if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+		t.Errorf("commands = %v, want %v", runner.commands, want)
+	}
+}
+
+func TestClipboardCopyBackend(t *testing.T) {
+	setWaylandEnv(t)
+	runner := &fakeRunner{}
+	backend := newClipboardCopyBackend(SelectionClipboard, false, DefaultClipboardMIME)
+	backend.runner = runner
+
+	// A recorded window is ignored: nothing is focused, pasted or pressed
+	if err := backend.Inject(context.Background(), "copied text", time.Second, "0xabc"); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+	want := []string{"wl-copy --type text/plain;charset=utf-8"}
+	if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+		t.Errorf("commands = %v, want %v", runner.commands, want)
+	}
+	if backend.Name() != BackendClipboardCopy {
+		t.Errorf("Name() = %q, want %q", backend.Name(), BackendClipboardCopy)
+	}
+}
+
+func TestCopyOnly(t *testing.T) {
+	tests := []struct {
+		backends []string
+		want     bool
+	}{
+		{[]string{"clipboard-copy"}, true},
+		{[]string{"wtype", "clipboard-copy"}, false},
+		{[]string{"clipboard"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := CopyOnly(tt.backends); got != tt.want {
+			t.Errorf("CopyOnly(%v) = %v, want %v", tt.backends, got, tt.want)
+		}
+	}
+}
+
+func TestClipboardBackend_VerifyClipboard(t *testing.T) {
+	setWaylandEnv(t)
+	runner := &fakeRunner{outputs: map[string][]byte{"wl-paste": []byte("copied text")}}
+	backend := &clipboardBackend{runner: runner, verify: true}
+
+	if err := backend.Inject(context.Background(), "copied text", time.Second, ""); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+	want := []string{"wl-copy", "wl-paste --no-newline"}
+	if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+		t.Errorf("commands = %v, want %v", runner.commands, want)
+	}
+
+	// Stale content is copied again, then the paste is skipped and the backend fails
+	runner = &fakeRunner{outputs: map[string][]byte{"wl-paste": []byte("old text")}}
+	backend = &clipboardBackend{runner: runner, windows: &hyprlandWindowManager{runner: runner}, verify: true}
+	err := backend.Inject(context.Background(), "copied text", time.Second, "0xabc")
+	if !errors.Is(err, errClipboardMismatch) {
+		t.Fatalf("Inject() error = %v, want %v", err, errClipboardMismatch)
+	}
+	want = []string{"wl-copy", "wl-paste --no-newline", "wl-copy", "wl-paste --no-newline"}
+	if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+		t.Errorf("commands = %v, want %v", runner.commands, want)
+	}
+
+	// wl-paste is required when verifying
+	backend = &clipboardBackend{runner: &fakeRunner{missing: map[string]bool{"wl-paste": true}}, verify: true}
+	if err := backend.Available(); err == nil {
+		t.Error("Available() should fail without wl-paste when verify_clipboard is set")
+	}
+}
+
+func TestClipboardBackend_FocusAndPaste(t *testing.T) {
+	setWaylandEnv(t)
+	runner := &fakeRunner{}
+	backend := &clipboardBackend{runner: runner, windows: &hyprlandWindowManager{runner: runner}}
+
+	if err := backend.Inject(context.Background(), "pasted text", time.Second, "0xabc"); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+
+	want := []string{
+		"wl-copy",
+		"hyprctl dispatch focuswindow 0xabc",
+		"wtype -M ctrl -M shift v -m shift -m ctrl",
+	}
+	if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+		t.Errorf("commands = %v, want %v", runner.commands, want)
+	}
+}
+
+func TestBackends_PreAndPostKeys(t *testing.T) {
+	keys := &keyWrap{pre: []string{"i"}, post: []string{"Escape", "ctrl+s"}}
+
+	t.Run("wtype", func(t *testing.T) {
+		setWaylandEnv(t)
+		runner := &fakeRunner{}
+		backend := &wtypeBackend{runner: runner, keys: keys}
+		if err := backend.Inject(context.Background(), "hello", time.Second, ""); err != nil {
+			t.Fatalf("Inject() error = %v", err)
+		}
+		want := []string{"wtype -k i", "wtype -- hello", "wtype -k Escape", "wtype -M ctrl -k s -m ctrl"}
+		if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+			t.Errorf("commands = %v, want %v", runner.commands, want)
+		}
+	})
+
+	t.Run("ydotool", func(t *testing.T) {
+		socket := t.TempDir() + "/ydotool_socket"
+		if err := os.WriteFile(socket, nil, 0600); err != nil {
+			t.Fatalf("failed to create fake socket: %v", err)
+		}
+		t.Setenv("YDOTOOL_SOCKET", socket)
+
+		runner := &fakeRunner{}
+		backend := &ydotoolBackend{runner: runner, keys: keys}
+		if err := backend.Inject(context.Background(), "hello", time.Second, ""); err != nil {
+			t.Fatalf("Inject() error = %v", err)
+		}
+		want := []string{"ydotool key i", "ydotool type -- hello", "ydotool key Escape", "ydotool key ctrl+s"}
+		if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+			t.Errorf("commands = %v, want %v", runner.commands, want)
+		}
+	})
+
+	t.Run("clipboard", func(t *testing.T) {
+		setWaylandEnv(t)
+		runner := &fakeRunner{}
+		backend := &clipboardBackend{runner: runner, windows: &hyprlandWindowManager{runner: runner}, keys: keys}
+		if err := backend.Inject(context.Background(), "hello", time.Second, "0xabc"); err != nil {
+			t.Fatalf("Inject() error = %v", err)
+		}
+		want := []string{
+			"wl-copy",
+			"hyprctl dispatch focuswindow 0xabc",
+			"wtype -k i",
+			"wtype -M ctrl -M shift v -m shift -m ctrl",
+			"wtype -k Escape",
+			"wtype -M ctrl -k s -m ctrl",
+		}
+		if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+			t.Errorf("commands = %v, want %v", runner.commands, want)
+		}
+	})
+}
+
+func TestValidKeyCombo(t *testing.T) {
+	for combo, want := range map[string]bool{"i": true, "ctrl+shift+v": true, "Return": true, "": false, "ctrl+": false, "+v": false} {
+		if got := ValidKeyCombo(combo); got != want {
+			t.Errorf("ValidKeyCombo(%q) = %v, want %v", combo, got, want)
+		}
+	}
+}
+
+func TestClipboardBackend_SwayFocusAndPaste(t *testing.T) {
+	setWaylandEnv(t)
+	runner := &fakeRunner{}
+	backend := &clipboardBackend{runner: runner, windows: &swayWindowManager{runner: runner}}
+
+	if err := backend.Inject(context.Background(), "pasted text", time.Second, "42"); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+
+	want := []string{
+		"wl-copy",
+		"swaymsg [con_id=42] focus",
+		"wtype -M ctrl -M shift v -m shift -m ctrl",
+	}
+	if fmt.Sprint(runner.commands) != fmt.Sprint(want) {
+		t.Errorf("commands = %v, want %v", runner.commands, want)
+	}
+}
+
+func TestClipboardBackend_NoWindowManager(t *testing.T) {
+	setWaylandEnv(t)
+	runner := &fakeRunner{}
+	backend := &clipboardBackend{runner: runner}
+
+	if err := backend.Inject(context.Background(), "text", time.Second, "0xabc"); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+	if fmt.Sprint(runner.commands) != fmt.Sprint([]string{"wl-copy"}) {
+		t.Errorf("commands = %v, want only wl-copy without a window manager", runner.commands)
+	}
+}
+
+func TestHyprlandWindowManager_ActiveWindow(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string][]byte{"hyprctl": []byte(`{"address":"0x55d1","class":"kitty"}`)}}
+	windows := &hyprlandWindowManager{runner: runner}
+
+	address, err := windows.ActiveWindow(context.Background())
+	if err != nil {
+		t.Fatalf("ActiveWindow() error = %v", err)
+	}
+	if address != "0x55d1" {
+		t.Errorf("ActiveWindow() = %q, want %q", address, "0x55d1")
+	}
+}
+
+func TestSwayWindowManager_ActiveWindow(t *testing.T) {
+	tree := `{"id":1,"focused":false,"nodes":[
+		{"id":2,"focused":false,"nodes":[{"id":7,"focused":false,"nodes":[]}],"floating_nodes":[
+			{"id":9,"focused":true,"nodes":[]}
+		]}
+	]}`
+	runner := &fakeRunner{outputs: map[string][]byte{"swaymsg": []byte(tree)}}
+	windows := &swayWindowManager{runner: runner}
+
+	address, err := windows.ActiveWindow(context.Background())
+	if err != nil {
+		t.Fatalf("ActiveWindow() error = %v", err)
+	}
+	if address != "9" {
+		t.Errorf("ActiveWindow() = %q, want %q", address, "9")
+	}
+	if runner.commands[0] != "swaymsg -t get_tree" {
+		t.Errorf("command = %q, want %q", runner.commands[0], "swaymsg -t get_tree")
+	}
+}
+
+func TestHyprlandWindowManager_WindowClass(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string][]byte{
+		"hyprctl": []byte(`[{"address":"0x1","class":"kitty"},{"address":"0x2","class":"Bitwarden"}]`),
+	}}
+	windows := &hyprlandWindowManager{runner: runner}
+
+	class, err := windows.WindowClass(context.Background(), "0x2")
+	if err != nil {
+		t.Fatalf("WindowClass() error = %v", err)
+	}
+	if class != "Bitwarden" {
+		t.Errorf("WindowClass() = %q, want %q", class, "Bitwarden")
+	}
+	if runner.commands[0] != "hyprctl -j clients" {
+		t.Errorf("command = %q, want %q", runner.commands[0], "hyprctl -j clients")
+	}
+
+	if _, err := windows.WindowClass(context.Background(), "0x3"); err == nil {
+		t.Error("WindowClass() should fail for an unknown address")
+	}
+}
+
+func TestSwayWindowManager_WindowClass(t *testing.T) {
+	tree := `{"id":1,"focused":false,"nodes":[
+		{"id":4,"focused":true,"app_id":"foot","nodes":[]},
+		{"id":5,"focused":false,"app_id":null,"window_properties":{"class":"KeePassXC"},"nodes":[]}
+	]}`
+	runner := &fakeRunner{outputs: map[string][]byte{"swaymsg": []byte(tree)}}
+	windows := &swayWindowManager{runner: runner}
+
+	for address, want := range map[string]string{"": "foot", "5": "KeePassXC"} {
+		class, err := windows.WindowClass(context.Background(), address)
+		if err != nil {
+			t.Fatalf("WindowClass(%q) error = %v", address, err)
+		}
+		if class != want {
+			t.Errorf("WindowClass(%q) = %q, want %q", address, class, want)
+		}
+	}
+}
+
+func TestSwayWindowManager_NoFocusedWindow(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string][]byte{"swaymsg": []byte(`{"id":1,"focused":false,"nodes":[]}`)}}
+	windows := &swayWindowManager{runner: runner}
+
+	if _, err := windows.ActiveWindow(context.Background()); err == nil {
+		t.Error("ActiveWindow() should fail when no node is focused")
+	}
+}
+
+func TestSwayWindowManager_RejectsInvalidAddress(t *testing.T) {
+	runner := &fakeRunner{}
+	windows := &swayWindowManager{runner: runner}
+
+	if err := windows.FocusWindow(context.Background(), "0xabc"); err == nil {
+		t.Error("FocusWindow() should reject a non-numeric container id")
+	}
+	if len(runner.commands) != 0 {
+		t.Errorf("no command should run for an invalid id, got %v", runner.commands)
+	}
+}
+
+func TestClipboardBackend_CopyFailure(t *testing.T) {
+	setWaylandEnv(t)
+	runner := &fakeRunner{failures: map[string]error{"wl-copy": errors.New("no compositor")}}
+	backend := &clipboardBackend{runner: runner}
+
+	if err := backend.Inject(context.Background(), "text", time.Second, "0xabc"); err == nil {
+		t.Error("Inject() should fail when wl-copy fails")
+	}
+	if len(runner.commands) != 1 {
+		t.Errorf("focus/paste should be skipped after a failed copy, got %v", runner.commands)
+	}
+}
+
+func TestClearClipboard(t *testing.T) {
+	runner := &fakeRunner{}
+	if err := clearClipboard(context.Background(), runner); err != nil {
+		t.Fatalf("clearClipboard() error = %v", err)
+	}
+	if len(runner.commands) != 1 || runner.commands[0] != "wl-copy --clear" {
+		t.Errorf("commands = %v, want [wl-copy --clear]", runner.commands)
+	}
+
+	runner = &fakeRunner{failures: map[string]error{"wl-copy": fmt.Errorf("no display")}}
+	if err := clearClipboard(context.Background(), runner); err == nil {
+		t.Error("clearClipboard() should return wl-copy errors")
+	}
+}
+
+func TestFocusCommandWindowManager(t *testing.T) {
+	runner := &fakeRunner{}
+	windows := withFocusCommand(&hyprlandWindowManager{runner: runner}, "hyprctl dispatch focuswindow address:{addr}", runner)
+
+	if err := windows.FocusWindow(context.Background(), "0x5a1f"); err != nil {
+		t.Fatalf("FocusWindow() error = %v", err)
+	}
+	if want := "sh -c hyprctl dispatch focuswindow address:'0x5a1f'"; len(runner.commands) != 1 || runner.commands[0] != want {
+		t.Errorf("commands = %q, want [%q]", runner.commands, want)
+	}
+
+	runner.commands = nil
+	windows.FocusWindow(context.Background(), "x'; rm -rf ~'")
+	if want := `sh -c hyprctl dispatch focuswindow address:'x'\''; rm -rf ~'\'''`; runner.commands[0] != want {
+		t.Errorf("command = %q, want the address quoted as %q", runner.commands[0], want)
+	}
+
+	runner.failures = map[string]error{"sh": errors.New("exit status 1")}
+	if err := windows.FocusWindow(context.Background(), "0x5a1f"); err == nil {
+		t.Error("FocusWindow() with a failing command succeeded, want error")
+	}
+
+	// Capture still goes to the compositor
+	if windows.Name() != CompositorHyprland {
+		t.Errorf("Name() = %q, want %q", windows.Name(), CompositorHyprland)
+	}
+	if withFocusCommand(nil, "focus {addr}", runner) != nil {
+		t.Error("withFocusCommand(nil) should stay nil without window tracking")
+	}
+}
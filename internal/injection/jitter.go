@@ -0,0 +1,57 @@
+package injection
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Default delay range between humanized keystrokes
+const (
+	DefaultHumanizeMinDelay = 30 * time.Millisecond
+	DefaultHumanizeMaxDelay = 120 * time.Millisecond
+)
+
+// typingJitter types text one character at a time with a random delay between characters,
+// for apps that reject pasted or bulk-typed input
+type typingJitter struct {
+	minDelay time.Duration
+	maxDelay time.Duration
+}
+
+// newTypingJitter returns nil when humanized typing is disabled
+func newTypingJitter(config Config) *typingJitter {
+	if !config.Humanize {
+		return nil
+	}
+	return &typingJitter{minDelay: config.HumanizeMinDelay, maxDelay: config.HumanizeMaxDelay}
+}
+
+func (j *typingJitter) delay() time.Duration {
+	if j.maxDelay <= j.minDelay {
+		return j.minDelay
+	}
+	return j.minDelay + time.Duration(rand.Int63n(int64(j.maxDelay-j.minDelay)+1))
+}
+
+// maxDuration is the longest the jitter delays can add when typing text
+func (j *typingJitter) maxDuration(text string) time.Duration {
+	return time.Duration(len([]rune(text))) * j.maxDelay
+}
+
+// typeText calls typeChar for every character of text, sleeping a random delay in between
+func (j *typingJitter) typeText(ctx context.Context, text string, typeChar func(char string) error) error {
+	for i, r := range []rune(text) {
+		if i > 0 {
+			select {
+			case <-time.After(j.delay()):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err := typeChar(string(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
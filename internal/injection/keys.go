@@ -0,0 +1,70 @@
+package injection
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keyWrap presses key combos before and after the injected text, e.g. "i" to enter
+// vim insert mode first and "Escape" to leave it afterwards
+type keyWrap struct {
+	pre  []string
+	post []string
+}
+
+// newKeyWrap returns nil when no pre or post keys are configured
+func newKeyWrap(config Config) *keyWrap {
+	if len(config.PreKeys) == 0 && len(config.PostKeys) == 0 {
+		return nil
+	}
+	return &keyWrap{pre: config.PreKeys, post: config.PostKeys}
+}
+
+// wrap presses the pre keys, runs inject, then presses the post keys.
+// A nil keyWrap only runs inject.
+func (k *keyWrap) wrap(press func(combo string) error, inject func() error) error {
+	if k == nil {
+		return inject()
+	}
+	for _, combo := range k.pre {
+		if err := press(combo); err != nil {
+			return fmt.Errorf("pre key %q: %w", combo, err)
+		}
+	}
+	if err := inject(); err != nil {
+		return err
+	}
+	for _, combo := range k.post {
+		if err := press(combo); err != nil {
+			return fmt.Errorf("post key %q: %w", combo, err)
+		}
+	}
+	return nil
+}
+
+// ValidKeyCombo reports whether combo looks like "key" or "mod+...+key" with no empty parts
+func ValidKeyCombo(combo string) bool {
+	for _, part := range strings.Split(combo, "+") {
+		if strings.TrimSpace(part) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// wtypeKeyArgs converts a combo like "ctrl+shift+Return" into wtype arguments that
+// hold the modifiers, press the key, and release the modifiers in reverse order
+func wtypeKeyArgs(combo string) []string {
+	parts := strings.Split(combo, "+")
+	mods, key := parts[:len(parts)-1], parts[len(parts)-1]
+
+	args := make([]string, 0, 4*len(mods)+2)
+	for _, mod := range mods {
+		args = append(args, "-M", mod)
+	}
+	args = append(args, "-k", key)
+	for i := len(mods) - 1; i >= 0; i-- {
+		args = append(args, "-m", mods[i])
+	}
+	return args
+}
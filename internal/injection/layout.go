@@ -0,0 +1,69 @@
+package injection
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// LayoutUS is the keyboard layout ydotool's key positions are taken from
+const LayoutUS = "us"
+
+// errLayoutMismatch means ydotool would type the text wrong on the configured keyboard layout
+var errLayoutMismatch = errors.New("text would be mistyped on this keyboard layout")
+
+// Layouts that move letters around compared to US QWERTY. Anything else is treated as
+// a QWERTY variant, where letters, digits, comma and period stay where they are.
+var (
+	qwertzLayouts = map[string]bool{"de": true, "at": true, "ch": true, "cz": true, "sk": true, "hu": true, "si": true, "hr": true, "rs": true, "ba": true}
+	azertyLayouts = map[string]bool{"fr": true, "be": true}
+)
+
+// ValidKeyboardLayout reports whether layout looks like an XKB layout name such as "us", "de" or "fr"
+func ValidKeyboardLayout(layout string) bool {
+	if layout == "" {
+		return false
+	}
+	for _, r := range layout {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkLayout returns errLayoutMismatch when text contains a character ydotool, which sends
+// US key positions, would turn into a different one on layout
+func checkLayout(layout string, text string) error {
+	if layout == "" || layout == LayoutUS {
+		return nil
+	}
+	for _, r := range text {
+		if !layoutSafe(layout, r) {
+			return fmt.Errorf("%w %q: %q", errLayoutMismatch, layout, r)
+		}
+	}
+	return nil
+}
+
+// layoutSafe reports whether the US key for r produces r on layout as well
+func layoutSafe(layout string, r rune) bool {
+	switch r {
+	case ' ', '\n', '\t':
+		return true
+	}
+	lower := r | 0x20 // Folds ASCII upper case letters to lower case
+	if lower >= 'a' && lower <= 'z' {
+		switch {
+		case qwertzLayouts[layout]:
+			return !strings.ContainsRune("yz", lower)
+		case azertyLayouts[layout]:
+			return !strings.ContainsRune("aqzwm", lower)
+		}
+		return true
+	}
+	if azertyLayouts[layout] {
+		return false // Digits need shift and the punctuation moved
+	}
+	return (r >= '0' && r <= '9') || r == ',' || r == '.'
+}
@@ -0,0 +1,262 @@
+package injection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// focusSettleDelay gives the compositor time to move focus before keys are sent
+const focusSettleDelay = 100 * time.Millisecond
+
+// Defaults for retrying an empty active window right after a keybind, before the
+// compositor has settled
+const (
+	DefaultWindowRetries    = 2
+	DefaultWindowRetryDelay = 50 * time.Millisecond
+)
+
+// WindowManager captures and refocuses windows on a specific compositor.
+// Window addresses are opaque strings that are only meaningful to the manager that produced them.
+type WindowManager interface {
+	Name() string
+	ActiveWindow(ctx context.Context) (string, error)
+	FocusWindow(ctx context.Context, address string) error
+	WindowClass(ctx context.Context, address string) (string, error) // Empty address means the focused window
+}
+
+// NewWindowManager returns the window manager for a compositor setting, or nil when
+// the compositor has no window tracking support
+func NewWindowManager(compositor string) WindowManager {
+	return newWindowManager(ResolveCompositor(compositor), execRunner{})
+}
+
+// focusWindow focuses the specified window through the compositor's window manager and
+// waits for focus to settle so pasted or typed text lands in it
+func focusWindow(ctx context.Context, windows WindowManager, windowAddress string) error {
+	if windows == nil {
+		return fmt.Errorf("window focusing not supported on this compositor")
+	}
+	if err := windows.FocusWindow(ctx, windowAddress); err != nil {
+		return err
+	}
+	time.Sleep(focusSettleDelay)
+	return nil
+}
+
+// FocusCommandPlaceholder is replaced by the captured window address in injection.focus_command
+const FocusCommandPlaceholder = "{addr}"
+
+// commandFocusWindowManager focuses windows with a user command instead of the
+// compositor's own call; capture and class lookups still go to the wrapped manager
+type commandFocusWindowManager struct {
+	WindowManager
+	command string
+	runner  commandRunner
+}
+
+// withFocusCommand wraps windows so FocusWindow runs command, or returns windows unchanged
+// when no command is set or the compositor has no window tracking to capture an address
+func withFocusCommand(windows WindowManager, command string, runner commandRunner) WindowManager {
+	if command == "" {
+		return windows
+	}
+	if windows == nil {
+		log.Printf("Injection: focus_command ignored, this compositor has no window tracking to capture an address")
+		return nil
+	}
+	return &commandFocusWindowManager{WindowManager: windows, command: command, runner: runner}
+}
+
+func (c *commandFocusWindowManager) FocusWindow(ctx context.Context, address string) error {
+	rendered := strings.ReplaceAll(c.command, FocusCommandPlaceholder, shellQuote(address))
+	if err := c.runner.Run(ctx, "", "sh", "-c", rendered); err != nil {
+		return fmt.Errorf("focus_command failed: %w", err)
+	}
+	return nil
+}
+
+// shellQuote single-quotes s for sh so addresses can't inject shell syntax
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func newWindowManager(compositor string, runner commandRunner) WindowManager {
+	switch compositor {
+	case CompositorHyprland:
+		return &hyprlandWindowManager{runner: runner}
+	case CompositorSway:
+		return &swayWindowManager{runner: runner}
+	default:
+		return nil
+	}
+}
+
+// hyprlandWindowManager tracks windows by their hyprctl address
+type hyprlandWindowManager struct {
+	runner commandRunner
+}
+
+func (h *hyprlandWindowManager) Name() string {
+	return CompositorHyprland
+}
+
+func (h *hyprlandWindowManager) ActiveWindow(ctx context.Context) (string, error) {
+	output, err := h.runner.Output(ctx, "hyprctl", "-j", "activewindow")
+	if err != nil {
+		return "", fmt.Errorf("hyprctl activewindow failed: %w", err)
+	}
+
+	var window struct {
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal(output, &window); err != nil {
+		return "", fmt.Errorf("failed to parse active window JSON: %w", err)
+	}
+	return window.Address, nil
+}
+
+func (h *hyprlandWindowManager) WindowClass(ctx context.Context, address string) (string, error) {
+	if address == "" {
+		output, err := h.runner.Output(ctx, "hyprctl", "-j", "activewindow")
+		if err != nil {
+			return "", fmt.Errorf("hyprctl activewindow failed: %w", err)
+		}
+		var window struct {
+			Class string `json:"class"`
+		}
+		if err := json.Unmarshal(output, &window); err != nil {
+			return "", fmt.Errorf("failed to parse active window JSON: %w", err)
+		}
+		return window.Class, nil
+	}
+
+	output, err := h.runner.Output(ctx, "hyprctl", "-j", "clients")
+	if err != nil {
+		return "", fmt.Errorf("hyprctl clients failed: %w", err)
+	}
+	var clients []struct {
+		Address string `json:"address"`
+		Class   string `json:"class"`
+	}
+	if err := json.Unmarshal(output, &clients); err != nil {
+		return "", fmt.Errorf("failed to parse clients JSON: %w", err)
+	}
+	for _, client := range clients {
+		if client.Address == address {
+			return client.Class, nil
+		}
+	}
+	return "", fmt.Errorf("window %s not found", address)
+}
+
+func (h *hyprlandWindowManager) FocusWindow(ctx context.Context, address string) error {
+	if err := h.runner.Run(ctx, "", "hyprctl", "dispatch", "focuswindow", address); err != nil {
+		return fmt.Errorf("hyprctl focuswindow failed: %w", err)
+	}
+	return nil
+}
+
+// swayWindowManager tracks windows by their sway container ID
+type swayWindowManager struct {
+	runner commandRunner
+}
+
+// swayNode is the subset of a swaymsg get_tree node needed to find the focused window
+type swayNode struct {
+	ID               int64  `json:"id"`
+	Focused          bool   `json:"focused"`
+	AppID            string `json:"app_id"`
+	WindowProperties struct {
+		Class string `json:"class"`
+	} `json:"window_properties"`
+	Nodes         []swayNode `json:"nodes"`
+	FloatingNodes []swayNode `json:"floating_nodes"`
+}
+
+// class returns the Wayland app_id, or the X11 class for Xwayland windows
+func (n *swayNode) class() string {
+	if n.AppID != "" {
+		return n.AppID
+	}
+	return n.WindowProperties.Class
+}
+
+func (s *swayWindowManager) Name() string {
+	return CompositorSway
+}
+
+func (s *swayWindowManager) ActiveWindow(ctx context.Context) (string, error) {
+	focused, err := s.findNode(ctx, func(node *swayNode) bool { return node.Focused })
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(focused.ID, 10), nil
+}
+
+func (s *swayWindowManager) WindowClass(ctx context.Context, address string) (string, error) {
+	match := func(node *swayNode) bool { return node.Focused }
+	if address != "" {
+		id, err := strconv.ParseInt(address, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid sway container id %q", address)
+		}
+		match = func(node *swayNode) bool { return node.ID == id }
+	}
+
+	node, err := s.findNode(ctx, match)
+	if err != nil {
+		return "", err
+	}
+	return node.class(), nil
+}
+
+// findNode fetches the sway tree and returns the first node accepted by match
+func (s *swayWindowManager) findNode(ctx context.Context, match func(*swayNode) bool) (*swayNode, error) {
+	output, err := s.runner.Output(ctx, "swaymsg", "-t", "get_tree")
+	if err != nil {
+		return nil, fmt.Errorf("swaymsg get_tree failed: %w", err)
+	}
+
+	var root swayNode
+	if err := json.Unmarshal(output, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse sway tree JSON: %w", err)
+	}
+
+	node := findSwayNode(&root, match)
+	if node == nil {
+		return nil, fmt.Errorf("no matching window in sway tree")
+	}
+	return node, nil
+}
+
+func (s *swayWindowManager) FocusWindow(ctx context.Context, address string) error {
+	if _, err := strconv.ParseInt(address, 10, 64); err != nil {
+		return fmt.Errorf("invalid sway container id %q", address)
+	}
+	if err := s.runner.Run(ctx, "", "swaymsg", fmt.Sprintf("[con_id=%s]", address), "focus"); err != nil {
+		return fmt.Errorf("swaymsg focus failed: %w", err)
+	}
+	return nil
+}
+
+func findSwayNode(node *swayNode, match func(*swayNode) bool) *swayNode {
+	if match(node) {
+		return node
+	}
+	for i := range node.Nodes {
+		if found := findSwayNode(&node.Nodes[i], match); found != nil {
+			return found
+		}
+	}
+	for i := range node.FloatingNodes {
+		if found := findSwayNode(&node.FloatingNodes[i], match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
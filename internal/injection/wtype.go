@@ -3,15 +3,21 @@ package injection
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
-	"os/exec"
 	"time"
 )
 
-type wtypeBackend struct{}
+type wtypeBackend struct {
+	runner  commandRunner
+	jitter  *typingJitter // nil types the whole text at once
+	keys    *keyWrap      // nil presses no keys around the text
+	windows WindowManager // nil types into the focused window without refocusing
+	delay   time.Duration // Pause before the first key or typed text
+}
 
 func NewWtypeBackend() Backend {
-	return &wtypeBackend{}
+	return &wtypeBackend{runner: execRunner{}}
 }
 
 func (w *wtypeBackend) Name() string {
@@ -19,7 +25,7 @@ func (w *wtypeBackend) Name() string {
 }
 
 func (w *wtypeBackend) Available() error {
-	if _, err := exec.LookPath("wtype"); err != nil {
+	if _, err := w.runner.LookPath("wtype"); err != nil {
 		return fmt.Errorf("wtype not found: %w (install wtype package)", err)
 	}
 
@@ -35,6 +41,11 @@ func (w *wtypeBackend) Available() error {
 }
 
 func (w *wtypeBackend) Inject(ctx context.Context, text string, timeout time.Duration, windowAddress string) error {
+	// Humanized typing gets extra time for the worst-case delays between characters
+	if w.jitter != nil {
+		timeout += w.jitter.maxDuration(text)
+	}
+	timeout += w.delay
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -42,8 +53,31 @@ func (w *wtypeBackend) Inject(ctx context.Context, text string, timeout time.Dur
 		return err
 	}
 
-	cmd := exec.CommandContext(ctx, "wtype", "--", text)
-	if err := cmd.Run(); err != nil {
+	if windowAddress != "" && w.windows != nil {
+		if err := focusWindow(ctx, w.windows, windowAddress); err != nil {
+			log.Printf("Wtype: Failed to focus window %s: %v, typing into the focused window", windowAddress, err)
+		}
+	}
+
+	if err := waitBeforeInject(ctx, w.delay); err != nil {
+		return fmt.Errorf("wtype failed: %w", err)
+	}
+	if err := claimWrite(ctx); err != nil {
+		return err
+	}
+
+	press := func(combo string) error {
+		return w.runner.Run(ctx, "", "wtype", wtypeKeyArgs(combo)...)
+	}
+	err := w.keys.wrap(press, func() error {
+		if w.jitter != nil {
+			return w.jitter.typeText(ctx, text, func(char string) error {
+				return w.runner.Run(ctx, "", "wtype", "--", char)
+			})
+		}
+		return w.runner.Run(ctx, "", "wtype", "--", text)
+	})
+	if err != nil {
 		return fmt.Errorf("wtype failed: %w", err)
 	}
 
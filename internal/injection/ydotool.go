@@ -3,16 +3,23 @@ package injection
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"time"
 )
 
-type ydotoolBackend struct{}
+type ydotoolBackend struct {
+	runner  commandRunner
+	jitter  *typingJitter // nil types the whole text at once
+	keys    *keyWrap      // nil presses no keys around the text
+	windows WindowManager // nil types into the focused window without refocusing
+	delay   time.Duration // Pause before the first key or typed text
+	layout  string        // Keyboard layout the text has to survive ("" = LayoutUS)
+}
 
 func NewYdotoolBackend() Backend {
-	return &ydotoolBackend{}
+	return &ydotoolBackend{runner: execRunner{}}
 }
 
 func (y *ydotoolBackend) Name() string {
@@ -20,7 +27,7 @@ func (y *ydotoolBackend) Name() string {
 }
 
 func (y *ydotoolBackend) Available() error {
-	if _, err := exec.LookPath("ydotool"); err != nil {
+	if _, err := y.runner.LookPath("ydotool"); err != nil {
 		return fmt.Errorf("ydotool not found: %w (install ydotool package)", err)
 	}
 
@@ -64,16 +71,48 @@ func (y *ydotoolBackend) getSocketPath() string {
 }
 
 func (y *ydotoolBackend) Inject(ctx context.Context, text string, timeout time.Duration, windowAddress string) error {
+	// Humanized typing gets extra time for the worst-case delays between characters
+	if y.jitter != nil {
+		timeout += y.jitter.maxDuration(text)
+	}
+	timeout += y.delay
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	if err := y.Available(); err != nil {
 		return err
 	}
+	// ydotool sends US key positions, so on other layouts symbols and some letters come out wrong
+	if err := checkLayout(y.layout, text); err != nil {
+		return err
+	}
+
+	if windowAddress != "" && y.windows != nil {
+		if err := focusWindow(ctx, y.windows, windowAddress); err != nil {
+			log.Printf("Ydotool: Failed to focus window %s: %v, typing into the focused window", windowAddress, err)
+		}
+	}
+
+	if err := waitBeforeInject(ctx, y.delay); err != nil {
+		return fmt.Errorf("ydotool failed: %w", err)
+	}
+	if err := claimWrite(ctx); err != nil {
+		return err
+	}
 
-	// ydotool type -- "text"
-	cmd := exec.CommandContext(ctx, "ydotool", "type", "--", text)
-	if err := cmd.Run(); err != nil {
+	press := func(combo string) error {
+		return y.runner.Run(ctx, "", "ydotool", "key", combo)
+	}
+	err := y.keys.wrap(press, func() error {
+		if y.jitter != nil {
+			return y.jitter.typeText(ctx, text, func(char string) error {
+				return y.runner.Run(ctx, "", "ydotool", "type", "--", char)
+			})
+		}
+		// ydotool type -- "text"
+		return y.runner.Run(ctx, "", "ydotool", "type", "--", text)
+	})
+	if err != nil {
 		return fmt.Errorf("ydotool failed: %w", err)
 	}
 
@@ -74,6 +74,20 @@ func NewOpenAIProcessor(config Config) *OpenAIProcessor {
 	}
 }
 
+// NewAzureOpenAIProcessor creates a processor for an Azure OpenAI resource, where the
+// configured models name deployments
+func NewAzureOpenAIProcessor(config Config) *OpenAIProcessor {
+	clientConfig := openai.DefaultAzureConfig(config.APIKey, config.Endpoint)
+	if config.APIVersion != "" {
+		clientConfig.APIVersion = config.APIVersion
+	}
+	clientConfig.AzureModelMapperFunc = func(model string) string { return model }
+	return &OpenAIProcessor{
+		client: openai.NewClientWithConfig(clientConfig),
+		config: config,
+	}
+}
+
 // Process cleans up transcribed text using OpenAI's chat completion
 func (p *OpenAIProcessor) Process(ctx context.Context, text string) (string, error) {
 	if strings.TrimSpace(text) == "" {
@@ -85,10 +99,11 @@ func (p *OpenAIProcessor) Process(ctx context.Context, text string) (string, err
 	defer cancel()
 
 	prompt := getPromptForLevel(p.config.Level, p.config.CustomPrompt)
+	model := p.config.ModelForLevel()
 
 	start := time.Now()
 	resp, err := p.client.CreateChatCompletion(llmCtx, openai.ChatCompletionRequest{
-		Model: p.config.Model,
+		Model: model,
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleSystem,
@@ -115,6 +130,6 @@ func (p *OpenAIProcessor) Process(ctx context.Context, text string) (string, err
 	}
 
 	result := strings.TrimSpace(resp.Choices[0].Message.Content)
-	log.Printf("llm-openai: processed in %v: %q -> %q", duration, text, result)
+	log.Printf("llm-openai: processed with %s in %v: %q -> %q", model, duration, text, result)
 	return result, nil
 }
@@ -10,8 +10,52 @@ type Config struct {
 	Provider     string
 	APIKey       string
 	Model        string
-	Level        string // "minimal", "moderate", "thorough", or "custom"
-	CustomPrompt string // Used when Level is "custom"
+	Models       map[string]string // Optional per-level model overrides
+	Level        string            // "minimal", "moderate", "thorough", or "custom"
+	CustomPrompt string            // Used when Level is "custom"
+	Endpoint     string            // Azure OpenAI only: resource URL
+	APIVersion   string            // Azure OpenAI only: api-version query parameter
+}
+
+// ModelForLevel returns the model configured for the current level, falling back to Model
+func (c Config) ModelForLevel() string {
+	if model := c.Models[c.Level]; model != "" {
+		return model
+	}
+	return c.Model
+}
+
+// Levels lists the accepted intervention levels
+var Levels = []string{"minimal", "moderate", "thorough", "custom"}
+
+// IsValidLevel reports whether level is one of Levels
+func IsValidLevel(level string) bool {
+	for _, known := range Levels {
+		if level == known {
+			return true
+		}
+	}
+	return false
+}
+
+// KnownModels lists the chat models accepted for each provider without allow_unknown_model
+var KnownModels = map[string][]string{
+	"openai": {
+		"gpt-4o-mini", "gpt-4o",
+		"gpt-4.1", "gpt-4.1-mini", "gpt-4.1-nano",
+		"gpt-4-turbo", "gpt-3.5-turbo",
+		"o4-mini", "o3-mini",
+	},
+}
+
+// IsKnownModel reports whether the model is in the provider's known model list
+func IsKnownModel(provider, model string) bool {
+	for _, known := range KnownModels[provider] {
+		if model == known {
+			return true
+		}
+	}
+	return false
 }
 
 // Processor processes transcribed text through an LLM
@@ -24,6 +68,11 @@ func NewProcessor(config Config) (Processor, error) {
 	switch config.Provider {
 	case "openai":
 		return NewOpenAIProcessor(config), nil
+	case "azure-openai":
+		if config.Endpoint == "" {
+			return nil, fmt.Errorf("Azure OpenAI endpoint required")
+		}
+		return NewAzureOpenAIProcessor(config), nil
 	default:
 		return nil, fmt.Errorf("unsupported LLM provider: %s", config.Provider)
 	}
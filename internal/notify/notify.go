@@ -4,14 +4,46 @@ import (
 	"github.com/leonardotrapani/hyprvoice/internal/config"
 	"log"
 	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 type Notifier interface {
 	Error(msg string)
 	Notify(title, message string)
+	// StartSession begins a new dictation; notifications until the next call update one toast
+	StartSession()
 }
 
-type Desktop struct{}
+// Desktop sends notifications with notify-send. Empty urgencies fall back to
+// critical for errors and the notify-send default otherwise; zero timeouts leave
+// the expiry to the notification server.
+type Desktop struct {
+	ErrorUrgency string
+	ErrorTimeout time.Duration
+	InfoUrgency  string
+	InfoTimeout  time.Duration
+
+	replace *replaceID // nil sends every notification as a new one
+}
+
+// replaceID remembers the id of the current session's notification so later
+// stages replace it instead of stacking
+type replaceID struct {
+	mu sync.Mutex
+	id uint64
+}
+
+func (d Desktop) StartSession() {
+	if d.replace == nil {
+		return
+	}
+	d.replace.mu.Lock()
+	d.replace.id = 0
+	d.replace.mu.Unlock()
+}
 
 func (d Desktop) RecordingStarted() {
 	d.Notify("Hyprvoice", "Recording Started")
@@ -21,20 +53,61 @@ func (d Desktop) Transcribing() {
 	d.Notify("Hyprvoice", "Transcribing...")
 }
 
-func (Desktop) Error(msg string) {
-	cmd := exec.Command("notify-send", "-a", "Hyprvoice", "-u", "critical", "Hyprvoice Error", msg)
-	if err := cmd.Run(); err != nil {
+func (d Desktop) Error(msg string) {
+	urgency := d.ErrorUrgency
+	if urgency == "" {
+		urgency = "critical"
+	}
+	if err := d.send(notifySendArgs(urgency, d.ErrorTimeout, "Hyprvoice Error", msg)); err != nil {
 		log.Printf("Failed to send error notification: %v", err)
 	}
 }
 
-func (Desktop) Notify(title, message string) {
-	cmd := exec.Command("notify-send", "-a", "Hyprvoice", title, message)
-	if err := cmd.Run(); err != nil {
+func (d Desktop) Notify(title, message string) {
+	if err := d.send(notifySendArgs(d.InfoUrgency, d.InfoTimeout, title, message)); err != nil {
 		log.Printf("Failed to send notification: %v", err)
 	}
 }
 
+// send runs notify-send, replacing the session's notification when one was already shown
+func (d Desktop) send(args []string) error {
+	if d.replace == nil {
+		return exec.Command("notify-send", args...).Run()
+	}
+
+	d.replace.mu.Lock()
+	defer d.replace.mu.Unlock()
+
+	out, err := exec.Command("notify-send", append(replaceArgs(d.replace.id), args...)...).Output()
+	if err != nil {
+		return err
+	}
+	if id, parseErr := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 32); parseErr == nil {
+		d.replace.id = id
+	}
+	return nil
+}
+
+// replaceArgs asks notify-send to print the notification id and, once known, to replace it
+func replaceArgs(id uint64) []string {
+	if id == 0 {
+		return []string{"-p"}
+	}
+	return []string{"-p", "-r", strconv.FormatUint(id, 10)}
+}
+
+// notifySendArgs builds the notify-send arguments, leaving out -u and -t when unset
+func notifySendArgs(urgency string, timeout time.Duration, title, message string) []string {
+	args := []string{"-a", "Hyprvoice"}
+	if urgency != "" {
+		args = append(args, "-u", urgency)
+	}
+	if timeout > 0 {
+		args = append(args, "-t", strconv.FormatInt(timeout.Milliseconds(), 10))
+	}
+	return append(args, title, message)
+}
+
 type Log struct{}
 
 func (l Log) Error(msg string) {
@@ -45,15 +118,27 @@ func (Log) Notify(title, message string) {
 	log.Printf("%s: %s", title, message)
 }
 
+func (Log) StartSession() {}
+
 type Nop struct{}
 
 func (Nop) Error(msg string)             {}
 func (Nop) Notify(title, message string) {}
+func (Nop) StartSession()                {}
 
 func GetNotifierBasedOnConfig(c *config.Config) Notifier {
 	switch c.Notifications.Type {
 	case "desktop":
-		return Desktop{}
+		desktop := Desktop{
+			ErrorUrgency: c.Notifications.ErrorUrgency,
+			ErrorTimeout: c.Notifications.ErrorTimeout,
+			InfoUrgency:  c.Notifications.InfoUrgency,
+			InfoTimeout:  c.Notifications.InfoTimeout,
+		}
+		if c.Notifications.UpdateInPlace {
+			desktop.replace = &replaceID{}
+		}
+		return desktop
 	case "log":
 		return Log{}
 	case "none":
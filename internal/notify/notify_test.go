@@ -1,7 +1,9 @@
 package notify
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/leonardotrapani/hyprvoice/internal/config"
 )
@@ -20,6 +22,73 @@ func TestDesktop_Notify(t *testing.T) {
 	desktop.Transcribing()
 }
 
+func TestNotifySendArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		urgency  string
+		timeout  time.Duration
+		expected []string
+	}{
+		{"defaults", "", 0, []string{"-a", "Hyprvoice", "Title", "Message"}},
+		{"urgency only", "critical", 0, []string{"-a", "Hyprvoice", "-u", "critical", "Title", "Message"}},
+		{"urgency and timeout", "low", 2 * time.Second, []string{"-a", "Hyprvoice", "-u", "low", "-t", "2000", "Title", "Message"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := notifySendArgs(tt.urgency, tt.timeout, "Title", "Message")
+			if !reflect.DeepEqual(args, tt.expected) {
+				t.Errorf("notifySendArgs() = %v, want %v", args, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReplaceArgs(t *testing.T) {
+	if args := replaceArgs(0); !reflect.DeepEqual(args, []string{"-p"}) {
+		t.Errorf("replaceArgs(0) = %v, want [-p]", args)
+	}
+	if args := replaceArgs(42); !reflect.DeepEqual(args, []string{"-p", "-r", "42"}) {
+		t.Errorf("replaceArgs(42) = %v, want [-p -r 42]", args)
+	}
+}
+
+func TestDesktop_StartSession(t *testing.T) {
+	desktop := GetNotifierBasedOnConfig(&config.Config{
+		Notifications: config.NotificationsConfig{Type: "desktop", UpdateInPlace: true},
+	}).(Desktop)
+	if desktop.replace == nil {
+		t.Fatal("expected replace-id tracking with update_in_place")
+	}
+
+	desktop.replace.id = 7
+	desktop.StartSession()
+	if desktop.replace.id != 0 {
+		t.Errorf("id = %d after StartSession, want 0", desktop.replace.id)
+	}
+
+	// Without update_in_place there is nothing to reset
+	Desktop{}.StartSession()
+}
+
+func TestGetNotifierBasedOnConfig_Desktop(t *testing.T) {
+	cfg := &config.Config{
+		Notifications: config.NotificationsConfig{
+			Type:         "desktop",
+			ErrorUrgency: "normal",
+			InfoTimeout:  time.Second,
+		},
+	}
+
+	desktop, ok := GetNotifierBasedOnConfig(cfg).(Desktop)
+	if !ok {
+		t.Fatal("expected Desktop notifier")
+	}
+	if desktop.ErrorUrgency != "normal" || desktop.InfoTimeout != time.Second {
+		t.Errorf("Desktop = %+v, want settings from config", desktop)
+	}
+}
+
 func TestLog_Notify(t *testing.T) {
 	logNotifier := Log{}
 
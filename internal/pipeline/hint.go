@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/leonardotrapani/hyprvoice/internal/transcriber"
+)
+
+// injectionHints map text found in a failed injection's error to the fix, checked in order
+// because one error carries the failures of every backend
+var injectionHints = []struct {
+	match []string // Any of these in the error text selects the hint
+	hint  string
+}{
+	{[]string{"ydotoold", ".ydotool_socket"}, "Start ydotoold: systemctl --user enable --now ydotool"},
+	{[]string{"ydotool failed: exit status"}, "Check that ydotoold is running and can reach /dev/uinput: systemctl --user status ydotool"},
+	{[]string{"WAYLAND_DISPLAY"}, "Run hyprvoice inside your Wayland session so WAYLAND_DISPLAY is set"},
+	{[]string{"executable file not found"}, "Install ydotool, wtype or wl-clipboard, or change injection.backends"},
+}
+
+// Hint suggests what the user can do about the error, or returns "" when there is nothing
+// obvious. The kind selects the stage; the underlying error narrows it down where needed.
+func (e PipelineError) Hint() string {
+	switch e.Kind {
+	case ErrorKindRecording:
+		return "Check the microphone with 'hyprvoice mic-test'"
+	case ErrorKindTranscriptionAuth:
+		return "Check the transcription API key (transcription.api_key or the provider's environment variable)"
+	case ErrorKindTranscriptionNetwork:
+		if errors.Is(e.Err, context.DeadlineExceeded) {
+			return "The provider took too long; check your connection or raise transcription.finalize_timeout"
+		}
+		return "Check your internet connection and the provider's endpoint"
+	case ErrorKindLLM:
+		if transcriber.IsAuthError(e.Err) {
+			return "Check the LLM API key (llm.api_key or the provider's environment variable)"
+		}
+	case ErrorKindInjection:
+		if e.Err == nil {
+			return ""
+		}
+		text := e.Err.Error()
+		for _, candidate := range injectionHints {
+			for _, match := range candidate.match {
+				if strings.Contains(text, match) {
+					return candidate.hint
+				}
+			}
+		}
+	}
+	return ""
+}
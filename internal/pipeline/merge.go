@@ -0,0 +1,51 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MergeState remembers the last injected block of text so a dictation started soon
+// after it, into the same window, extends that block instead of starting a new one
+// (behavior.merge_window). The daemon keeps one for all of its pipelines.
+type MergeState struct {
+	mu     sync.Mutex
+	window string    // Window address the block went to ("" without window tracking)
+	block  string    // Everything injected into the block so far
+	at     time.Time // When the block was last extended
+}
+
+// previous returns the block a dictation that started recording at start should continue
+func (m *MergeState) previous(window string, start time.Time, within time.Duration) (string, bool) {
+	if m == nil || within <= 0 {
+		return "", false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.block == "" || m.window != window || start.Sub(m.at) > within {
+		return "", false
+	}
+	return m.block, true
+}
+
+// record remembers block as the text injected into window, replacing any earlier block
+func (m *MergeState) record(window, block string, at time.Time) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.window, m.block, m.at = window, block, at
+}
+
+// mergeSeparator returns the space that keeps next from running into the end of previous
+func mergeSeparator(previous, next string) string {
+	last, _ := utf8.DecodeLastRuneInString(previous)
+	first, _ := utf8.DecodeRuneInString(next)
+	if previous == "" || next == "" || unicode.IsSpace(last) || unicode.IsSpace(first) {
+		return ""
+	}
+	return " "
+}
@@ -2,13 +2,19 @@ package pipeline
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/leonardotrapani/hyprvoice/internal/config"
 	"github.com/leonardotrapani/hyprvoice/internal/injection"
-	"github.com/leonardotrapani/hyprvoice/internal/llm"
 	"github.com/leonardotrapani/hyprvoice/internal/recording"
 	"github.com/leonardotrapani/hyprvoice/internal/transcriber"
 )
@@ -16,22 +22,44 @@ import (
 type Status string
 type Action string
 
+// ErrorKind classifies pipeline errors so clients can react to them programmatically
+type ErrorKind string
+
+const (
+	ErrorKindRecording            ErrorKind = "recording"
+	ErrorKindTranscriptionAuth    ErrorKind = "transcription_auth"
+	ErrorKindTranscriptionNetwork ErrorKind = "transcription_network"
+	ErrorKindInjection            ErrorKind = "injection"
+	ErrorKindLLM                  ErrorKind = "llm"
+	ErrorKindInternal             ErrorKind = "internal" // A recovered panic, usually a bug in hyprvoice or a provider library
+)
+
 type PipelineError struct {
+	Kind    ErrorKind
 	Title   string
 	Message string
 	Err     error
 }
 
+// Notification is an informational message for the user that is not an error
+type Notification struct {
+	Title   string
+	Message string
+}
+
 const (
 	Idle         Status = "idle"
 	Recording    Status = "recording"
 	Transcribing Status = "transcribing"
+	Confirming   Status = "confirming"
 	Injecting    Status = "injecting"
 )
 
 const (
-	Inject Action = "inject"
-	Cancel Action = "cancel"
+	Inject  Action = "inject"
+	Cancel  Action = "cancel"
+	Confirm Action = "confirm"
+	Discard Action = "discard"
 )
 
 type Pipeline interface {
@@ -40,16 +68,31 @@ type Pipeline interface {
 	Status() Status
 	GetActionCh() chan<- Action
 	GetErrorCh() <-chan PipelineError
+	GetNotifyCh() <-chan Notification
+	GetStatusCh() <-chan Status
+	GetTimingCh() <-chan time.Duration
 	SetWindowAddress(address string)
 	GetWindowAddress() string
+	SetMergeState(merge *MergeState)
+	SetTag(tag string)
 }
 
 type pipeline struct {
 	status        Status
 	actionCh      chan Action
 	errorCh       chan PipelineError
+	notifyCh      chan Notification
+	statusCh      chan Status
+	timingCh      chan time.Duration // How long each finished transcription took, from stopping the recording to the text
 	config        *config.Config
 	windowAddress string
+	tag           string                  // Label from "hyprvoice toggle --tag", kept in logs and failsafe entries ("" = none)
+	merge         *MergeState             // Last injected block, shared across pipelines for behavior.merge_window (nil = never merge)
+	windows       injection.WindowManager // Looks up window classes for behavior.confirm_except_classes (nil = the compositor's)
+
+	recordingStarted time.Time // When the current dictation started recording
+
+	session context.Context // Parent of the run context without the recording deadline, for finalizing
 
 	mu       sync.RWMutex
 	wg       sync.WaitGroup
@@ -63,6 +106,9 @@ func New(cfg *config.Config) Pipeline {
 	return &pipeline{
 		actionCh: make(chan Action, 1),
 		errorCh:  make(chan PipelineError, 10),
+		notifyCh: make(chan Notification, 10),
+		statusCh: make(chan Status, 10),
+		timingCh: make(chan time.Duration, 10),
 		config:   cfg,
 	}
 }
@@ -72,8 +118,23 @@ func (p *pipeline) Run(ctx context.Context) {
 		return
 	}
 
-	runCtx, cancel := context.WithTimeout(ctx, p.config.Recording.Timeout)
-	p.setCancel(cancel)
+	// The recording timeout only bounds recording. Finalizing gets its own deadline from
+	// the session, which ends on Stop but not when the recording runs out of time.
+	session, cancelSession := context.WithCancel(ctx)
+	p.session = session
+
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if p.config.Behavior.RapidMode {
+		// Rapid sessions run until stopped, the timeout applies to each snippet instead
+		runCtx, cancel = context.WithCancel(session)
+	} else {
+		runCtx, cancel = context.WithTimeout(session, p.config.Recording.Timeout)
+	}
+	p.setCancel(func() {
+		cancel()
+		cancelSession()
+	})
 
 	p.wg.Add(1)
 	go p.run(runCtx)
@@ -85,25 +146,43 @@ func (p *pipeline) run(ctx context.Context) {
 		p.setStatus(Idle)
 		p.wg.Done()
 	}()
+	// Transcription, processing and injection all run on this goroutine
+	defer p.recoverPanic("pipeline")
 
 	log.Printf("Pipeline: Starting recording")
 	p.setStatus(Recording)
+	p.recordingStarted = time.Now()
 
 	recorder := recording.NewRecorder(p.config.ToRecordingConfig())
 	frameCh, rErrCh, err := recorder.Start(ctx)
 
 	if err != nil {
 		log.Printf("Pipeline: Recording error: %v", err)
-		p.sendError("Recording Error", "Failed to start recording", err)
+		p.sendError(ErrorKindRecording, "Recording Error", "Failed to start recording", err)
 		return
 	}
 
 	defer recorder.Stop()
 
-	t, err := transcriber.NewTranscriber(p.config.ToTranscriberConfig())
+	go func() {
+		defer p.recoverPanic("recording")
+		for err := range rErrCh {
+			p.sendError(ErrorKindRecording, "Recording Error", "Recording stream error", err)
+		}
+	}()
+
+	if p.config.Behavior.RapidMode {
+		p.runRapid(ctx, frameCh)
+		return
+	}
+
+	stopWarning := p.warnBeforeTimeout()
+	defer stopWarning()
+
+	t, err := p.newTranscriber()
 	if err != nil {
 		log.Printf("Pipeline: Failed to create transcriber: %v", err)
-		p.sendError("Transcription Error", "Failed to create transcriber", err)
+		p.sendError(transcriptionErrorKind(err), "Transcription Error", "Failed to create transcriber", err)
 		return
 	}
 
@@ -113,7 +192,7 @@ func (p *pipeline) run(ctx context.Context) {
 	tErrCh, err := t.Start(ctx, frameCh)
 	if err != nil {
 		log.Printf("Pipeline: Transcriber error: %v", err)
-		p.sendError("Transcription Error", "Failed to start transcriber", err)
+		p.sendError(transcriptionErrorKind(err), "Transcription Error", "Failed to start transcriber", err)
 		return
 	}
 
@@ -121,22 +200,27 @@ func (p *pipeline) run(ctx context.Context) {
 		if stopErr := t.Stop(ctx); stopErr != nil {
 			log.Printf("Pipeline: Error stopping transcriber: %v", stopErr)
 			// Silently call an error now because on simple transcriber we just transcribe all audio when we stop, and might fail when force stop
-			//p.sendError("Transcription Error", "Failed to stop transcriber cleanly", stopErr)
+			//p.sendError(transcriptionErrorKind(stopErr), "Transcription Error", "Failed to stop transcriber cleanly", stopErr)
 		}
 	}()
 
 	// Forward errors from component channels to unified pipeline error channel
 	go func() {
+		defer p.recoverPanic("transcription")
 		for err := range tErrCh {
-			p.sendError("Transcription Error", "Transcription processing error", err)
+			p.sendError(transcriptionErrorKind(err), "Transcription Error", "Transcription processing error", err)
 		}
 	}()
 
-	go func() {
-		for err := range rErrCh {
-			p.sendError("Recording Error", "Recording stream error", err)
-		}
-	}()
+	// Streaming transcribers report text while recording; the channel closes on Stop
+	if pt, ok := t.(transcriber.PartialTranscriber); ok {
+		go func() {
+			defer p.recoverPanic("transcription")
+			for partial := range pt.Partials() {
+				log.Printf("Pipeline: Partial transcription: %q", partial)
+			}
+		}()
+	}
 
 	for {
 		select {
@@ -155,6 +239,133 @@ func (p *pipeline) run(ctx context.Context) {
 	}
 }
 
+// runRapid keeps the recorder running and injects one snippet per inject action,
+// re-arming straight back into recording until the pipeline is stopped
+func (p *pipeline) runRapid(ctx context.Context, frameCh <-chan recording.AudioFrame) {
+	for p.recordSnippet(ctx, frameCh) {
+		// Audio captured while the last snippet was injected is stale, start the next one fresh
+		if !drainFrames(frameCh) {
+			return
+		}
+		log.Printf("Pipeline: Rapid mode, re-arming recording")
+		p.setStatus(Recording)
+		p.recordingStarted = time.Now()
+	}
+}
+
+// recordSnippet feeds frames into a fresh transcriber until an inject action, then
+// finalizes and injects the snippet. It returns false when the session should end.
+func (p *pipeline) recordSnippet(ctx context.Context, frameCh <-chan recording.AudioFrame) bool {
+	snippetCtx, cancel := context.WithTimeout(ctx, p.config.Recording.Timeout)
+	defer cancel()
+	stopWarning := p.warnBeforeTimeout()
+	defer stopWarning()
+
+	t, err := p.newTranscriber()
+	if err != nil {
+		log.Printf("Pipeline: Failed to create transcriber: %v", err)
+		p.sendError(transcriptionErrorKind(err), "Transcription Error", "Failed to create transcriber", err)
+		return false
+	}
+
+	snippetCh := make(chan recording.AudioFrame, p.config.Recording.ChannelBufferSize)
+	tErrCh, err := t.Start(snippetCtx, snippetCh)
+	if err != nil {
+		log.Printf("Pipeline: Transcriber error: %v", err)
+		p.sendError(transcriptionErrorKind(err), "Transcription Error", "Failed to start transcriber", err)
+		return false
+	}
+
+	go func() {
+		defer p.recoverPanic("transcription")
+		for err := range tErrCh {
+			p.sendError(transcriptionErrorKind(err), "Transcription Error", "Transcription processing error", err)
+		}
+	}()
+
+	p.setStatus(Transcribing)
+
+	// abort drops the snippet without transcribing it
+	abort := func() bool {
+		cancel()
+		close(snippetCh)
+		if stopErr := t.Stop(snippetCtx); stopErr != nil {
+			log.Printf("Pipeline: Error stopping transcriber: %v", stopErr)
+		}
+		return false
+	}
+
+	for {
+		select {
+		case frame, ok := <-frameCh:
+			if !ok {
+				return abort()
+			}
+			select {
+			case snippetCh <- frame:
+			default:
+			}
+
+		case action := <-p.actionCh:
+			switch action {
+			case Inject:
+				log.Printf("Pipeline: Inject action received, finalizing rapid snippet")
+				p.setStatus(Injecting)
+				close(snippetCh)
+				finalCtx, cancelFinal := p.finalizeContext()
+				p.finishTranscription(finalCtx, t)
+				cancelFinal()
+				return ctx.Err() == nil
+			}
+
+		case <-snippetCtx.Done():
+			return abort()
+		}
+	}
+}
+
+// warnBeforeTimeout notifies once recording.timeout_warning of recording.timeout has passed
+// while still recording, so a long dictation isn't cut off without warning. The returned
+// function cancels the pending warning.
+func (p *pipeline) warnBeforeTimeout() func() bool {
+	fraction, timeout := p.config.Recording.TimeoutWarning, p.config.Recording.Timeout
+	if fraction <= 0 || timeout <= 0 {
+		return func() bool { return false }
+	}
+
+	warnAfter := time.Duration(float64(timeout) * fraction)
+	remaining := (timeout - warnAfter).Round(time.Second)
+	timer := time.AfterFunc(warnAfter, func() {
+		// Recording ends in Transcribing: audio keeps streaming to the transcriber until the next toggle
+		if status := p.Status(); status != Recording && status != Transcribing {
+			return
+		}
+		log.Printf("Pipeline: Recording timeout in %v", remaining)
+		p.sendNotification("Hyprvoice", fmt.Sprintf("Recording stops in %v, toggle to finish now", remaining))
+	})
+	return timer.Stop
+}
+
+// drainFrames discards buffered frames and reports whether the recorder is still running
+func drainFrames(frameCh <-chan recording.AudioFrame) bool {
+	for {
+		select {
+		case _, ok := <-frameCh:
+			if !ok {
+				return false
+			}
+		default:
+			return true
+		}
+	}
+}
+
+func (p *pipeline) newTranscriber() (transcriber.Transcriber, error) {
+	transcriberConfig := p.config.ToTranscriberConfig()
+	transcriberConfig.UploadProgress = p.reportUploadProgress
+	return transcriber.NewTranscriber(transcriberConfig)
+}
+
 func (p *pipeline) Status() Status {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -164,7 +375,16 @@ func (p *pipeline) Status() Status {
 func (p *pipeline) setStatus(status Status) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if p.status == status {
+		return
+	}
 	p.status = status
+
+	select {
+	case p.statusCh <- status:
+	default:
+		log.Printf("Pipeline: Status channel full, dropping status change: %s", status)
+	}
 }
 
 func (p *pipeline) setCancel(cancel context.CancelFunc) {
@@ -191,8 +411,35 @@ func (p *pipeline) GetErrorCh() <-chan PipelineError {
 	return p.errorCh
 }
 
-func (p *pipeline) sendError(title, message string, err error) {
+func (p *pipeline) GetNotifyCh() <-chan Notification {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.notifyCh
+}
+
+func (p *pipeline) GetStatusCh() <-chan Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.statusCh
+}
+
+func (p *pipeline) GetTimingCh() <-chan time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.timingCh
+}
+
+// sendTiming reports a finished transcription's duration without blocking if nobody reads it
+func (p *pipeline) sendTiming(d time.Duration) {
+	select {
+	case p.timingCh <- d:
+	default:
+	}
+}
+
+func (p *pipeline) sendError(kind ErrorKind, title, message string, err error) {
 	pipelineErr := PipelineError{
+		Kind:    kind,
 		Title:   title,
 		Message: message,
 		Err:     err,
@@ -205,6 +452,45 @@ func (p *pipeline) sendError(title, message string, err error) {
 	}
 }
 
+// recoverPanic must be deferred directly. It turns a panic in a pipeline goroutine into an
+// ErrorKindInternal error so a bug in provider or backend code ends this dictation instead of
+// taking down the daemon; run's own deferred cleanup then resets the pipeline to idle.
+func (p *pipeline) recoverPanic(where string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	log.Printf("Pipeline: Recovered from panic in %s: %v\n%s", where, r, debug.Stack())
+	p.sendError(ErrorKindInternal, "Internal Error", fmt.Sprintf("Unexpected failure in %s, dictation aborted", where), fmt.Errorf("panic: %v", r))
+}
+
+// transcriptionErrorKind tells rejected or missing credentials apart from other transcription failures
+func transcriptionErrorKind(err error) ErrorKind {
+	if transcriber.IsAuthError(err) {
+		return ErrorKindTranscriptionAuth
+	}
+	return ErrorKindTranscriptionNetwork
+}
+
+func (p *pipeline) sendNotification(title, message string) {
+	select {
+	case p.notifyCh <- Notification{Title: title, Message: message}:
+	default:
+		log.Printf("Pipeline: Notification channel full, dropping notification: %s", message)
+	}
+}
+
+// uploadNotifyMinBytes is the upload size from which progress is shown to the user (~30s of 16kHz mono audio)
+const uploadNotifyMinBytes = 1 << 20
+
+func (p *pipeline) reportUploadProgress(sent, total int64) {
+	percent := sent * 100 / total
+	log.Printf("Pipeline: Uploaded %d/%d bytes of audio (%d%%)", sent, total, percent)
+	if total >= uploadNotifyMinBytes && sent < total {
+		p.sendNotification("Hyprvoice", fmt.Sprintf("Uploading audio... %d%%", percent))
+	}
+}
+
 func (p *pipeline) handleInjectAction(ctx context.Context, recorder *recording.Recorder, t transcriber.Transcriber) {
 	status := p.Status()
 
@@ -218,47 +504,345 @@ func (p *pipeline) handleInjectAction(ctx context.Context, recorder *recording.R
 
 	recorder.Stop()
 
+	finalCtx, cancel := p.finalizeContext()
+	defer cancel()
+	p.finishTranscription(finalCtx, t)
+	p.setStatus(Idle)
+}
+
+// finalizeContext bounds the upload, processing and injection after a recording stops by
+// transcription.finalize_timeout instead of what is left of recording.timeout, so a
+// dictation that ran close to the timeout still gets transcribed. Stopping the pipeline
+// still cancels it.
+func (p *pipeline) finalizeContext() (context.Context, context.CancelFunc) {
+	parent := p.session
+	if parent == nil {
+		parent = context.Background()
+	}
+	timeout := p.config.Transcription.FinalizeTimeout
+	if timeout <= 0 {
+		timeout = config.DefaultFinalizeTimeout
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// confirmContext bounds how long text waits for confirm or inject by behavior.confirm_timeout.
+// It hangs off the session rather than the finalize context, so a slow upload doesn't
+// shorten the wait; stopping the pipeline still cancels it.
+func (p *pipeline) confirmContext() (context.Context, context.CancelFunc) {
+	parent := p.session
+	if parent == nil {
+		parent = context.Background()
+	}
+	timeout := p.config.Behavior.ConfirmTimeout
+	if timeout <= 0 {
+		timeout = config.DefaultConfirmTimeout
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// finishTranscription stops the transcriber and runs the filters, processing stages,
+// confirmation and injection on the final text
+func (p *pipeline) finishTranscription(ctx context.Context, t transcriber.Transcriber) {
+	start := time.Now()
 	if err := t.Stop(ctx); err != nil {
-		p.sendError("Transcription Error", "Failed to stop transcriber during injection", err)
+		switch ctx.Err() {
+		case context.Canceled:
+			log.Printf("Pipeline: Transcription cancelled during upload")
+		case context.DeadlineExceeded:
+			p.sendError(ErrorKindTranscriptionNetwork, "Transcription Error", "Transcription timed out", err)
+		default:
+			p.sendError(transcriptionErrorKind(err), "Transcription Error", "Failed to stop transcriber during injection", err)
+		}
 		return
 	}
 
-	transcriptionText, err := t.GetFinalTranscription()
+	result, err := t.GetFinalTranscription()
 	if err != nil {
-		p.sendError("Transcription Error", "Failed to retrieve transcription", err)
+		p.sendError(transcriptionErrorKind(err), "Transcription Error", "Failed to retrieve transcription", err)
 		return
 	}
+	elapsed := time.Since(start)
+	p.sendTiming(elapsed)
+	log.Printf("Pipeline: Transcription of %v of audio via %s %s took %v",
+		result.Duration.Round(time.Millisecond), result.Provider, result.Model, elapsed.Round(time.Millisecond))
+	transcriptionText := result.Text
 	log.Printf("Pipeline: Raw transcription text: %s", transcriptionText)
 
-	// LLM post-processing if enabled
-	if p.config.Processing.Mode == "llm" && transcriptionText != "" {
-		log.Printf("Pipeline: Processing with LLM...")
-		processor, llmErr := llm.NewProcessor(p.config.ToLLMConfig())
-		if llmErr != nil {
-			log.Printf("Pipeline: Failed to create LLM processor, using raw: %v", llmErr)
+	if p.config.Transcription.RepetitionFilter {
+		if collapsed := transcriber.CollapseRepetitions(transcriptionText, p.config.Transcription.MaxRepetitions); collapsed != transcriptionText {
+			log.Printf("Pipeline: Collapsed repeated phrases: %s", collapsed)
+			transcriptionText = collapsed
+		}
+	}
+
+	// Skip LLM and injection entirely for silence or known Whisper hallucinations
+	if transcriber.IsEmptyTranscription(transcriptionText) {
+		log.Printf("Pipeline: Transcription is empty, nothing to inject")
+		p.sendNotification("Hyprvoice", "No speech detected")
+		return
+	}
+	if transcriber.IsHallucination(transcriptionText, p.config.Transcription.HallucinationPhrases) {
+		log.Printf("Pipeline: Transcription %q matches a known hallucination, discarding", transcriptionText)
+		p.sendNotification("Hyprvoice", "No speech detected")
+		return
+	}
+
+	// Post-processing stages (processing.pipeline), skipping any that fail
+	if stages, stageErr := NewStages(p.config); stageErr != nil {
+		log.Printf("Pipeline: Failed to set up processing stages, using raw: %v", stageErr)
+	} else if len(stages) > 0 {
+		processedText, procErr := RunStages(ctx, stages, transcriptionText)
+		if llmFailed(procErr) && !p.config.LLM.FallbackToRaw {
+			p.copyRawToClipboard(ctx, transcriptionText, procErr)
+			return
+		}
+		if errors.Is(procErr, errDegradedOutput) {
+			log.Printf("Pipeline: Warning: discarding LLM output and keeping its input: %v", procErr)
+		} else if procErr != nil {
+			log.Printf("Pipeline: Processing stage failed, continuing without it: %v", procErr)
+		}
+		if processedText != transcriptionText {
+			log.Printf("Pipeline: Processed text: %s", processedText)
+			transcriptionText = processedText
+		}
+	}
+
+	if p.config.Processing.ContinueSentence {
+		transcriptionText = continueSentence(transcriptionText)
+	}
+	// A replacement can strip the only word; that is not an injection failure
+	if transcriber.IsEmptyTranscription(transcriptionText) {
+		log.Printf("Pipeline: Processing left nothing to inject")
+		p.sendNotification("Hyprvoice", "Nothing left to inject after processing")
+		return
+	}
+
+	windowAddress := p.GetWindowAddress()
+	p.mu.RLock()
+	merge := p.merge
+	p.mu.RUnlock()
+	// Under explicit_inject the text lands wherever the cursor is at inject time, not
+	// necessarily in the captured window, so there is no block to continue or record
+	var previous string
+	var merging bool
+	if !p.config.Behavior.ExplicitInject {
+		previous, merging = merge.previous(windowAddress, p.recordingStarted, p.config.Behavior.MergeWindow)
+	}
+	if merging {
+		// The block already has its prefix, the new text only follows on from it
+		log.Printf("Pipeline: Continuing the text injected into this window moments ago")
+		transcriptionText = mergeSeparator(previous, transcriptionText) + transcriptionText + p.config.Injection.Suffix
+	} else {
+		transcriptionText = p.config.Injection.Prefix + transcriptionText + p.config.Injection.Suffix
+	}
+	block := previous + transcriptionText
+
+	target := windowAddress
+	var hold func(ctx context.Context, text string) bool
+	if p.config.Behavior.ExplicitInject {
+		hold = p.awaitInject
+		// The user puts the cursor where the text belongs, possibly in another window
+		target = ""
+	} else if p.config.Behavior.ConfirmBeforeInject && !p.trustedWindow(ctx, windowAddress) {
+		hold = p.awaitConfirmation
+	}
+	if hold != nil {
+		confirmCtx, cancelConfirm := p.confirmContext()
+		released := hold(confirmCtx, transcriptionText)
+		cancelConfirm()
+		if !released {
+			return
+		}
+		// The wait may have outlasted the finalize deadline, so the injection gets its own
+		var cancel context.CancelFunc
+		ctx, cancel = p.finalizeContext()
+		defer cancel()
+	}
+
+	log.Printf("Pipeline: Final text for injection: %s%s", p.getTag(), transcriptionText)
+
+	injector := injection.NewInjector(p.config.ToInjectionConfig())
+
+	injectText := transcriptionText
+	if merging && injection.CopyOnly(p.config.Injection.Backends) {
+		// Nothing was typed, so the clipboard has to hold the whole block
+		injectText = block
+	}
+	if err := injector.Inject(ctx, injectText, target); errors.Is(err, injection.ErrDeniedWindow) {
+		p.sendError(ErrorKindInjection, "Injection Blocked", "Refused to inject into a protected window", err)
+	} else if err != nil {
+		message := "Failed to inject text"
+		if !errors.Is(ctx.Err(), context.Canceled) { // Canceling drops the text on purpose
+			if path, saveErr := p.saveFailsafe(transcriptionText); saveErr != nil {
+				log.Printf("Pipeline: Failed to save transcription to failsafe file: %v", saveErr)
+			} else if path != "" {
+				message = fmt.Sprintf("Failed to inject text, saved to %s", path)
+			}
+		}
+		p.sendError(ErrorKindInjection, "Injection Error", message, err)
+	} else {
+		if !p.config.Behavior.ExplicitInject {
+			merge.record(windowAddress, block, time.Now())
+		}
+		if injection.CopyOnly(p.config.Injection.Backends) {
+			log.Printf("Pipeline: Text copied to clipboard")
+			p.sendNotification("Hyprvoice", "Copied to clipboard")
 		} else {
-			processedText, procErr := processor.Process(ctx, transcriptionText)
-			if procErr != nil {
-				log.Printf("Pipeline: LLM processing failed, using raw: %v", procErr)
-			} else {
-				log.Printf("Pipeline: LLM cleaned text: %s", processedText)
-				transcriptionText = processedText
+			log.Printf("Pipeline: Text injection completed successfully")
+		}
+	}
+}
+
+// copyRawToClipboard hands the unprocessed transcription over through the clipboard
+// when the LLM failed and llm.fallback_to_raw is off, so it is neither typed nor lost
+func (p *pipeline) copyRawToClipboard(ctx context.Context, text string, llmErr error) {
+	log.Printf("Pipeline: LLM failed and fallback_to_raw is off, copying raw text to clipboard: %v", llmErr)
+	if err := injection.CopyToClipboard(ctx, text, p.config.Injection.ClipboardMIME); err != nil {
+		message := "LLM failed and the raw text could not be copied"
+		if path, saveErr := p.saveFailsafe(text); saveErr != nil {
+			log.Printf("Pipeline: Failed to save transcription to failsafe file: %v", saveErr)
+		} else if path != "" {
+			message = fmt.Sprintf("LLM failed; raw text saved to %s", path)
+		}
+		p.sendError(ErrorKindLLM, "LLM Error", message, errors.Join(llmErr, err))
+		return
+	}
+	p.sendError(ErrorKindLLM, "LLM Error", "LLM failed; raw text in clipboard", llmErr)
+}
+
+// saveFailsafe appends text to behavior.failsafe_file so a failed injection doesn't lose it.
+// It returns the expanded path, or "" when no failsafe file is configured.
+func (p *pipeline) saveFailsafe(text string) (string, error) {
+	path := os.ExpandEnv(p.config.Behavior.FailsafeFile)
+	if path == "" {
+		return "", nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("create failsafe directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", fmt.Errorf("open failsafe file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%s %s%s\n", time.Now().Format(time.RFC3339), p.getTag(), text); err != nil {
+		return "", fmt.Errorf("write failsafe file: %w", err)
+	}
+	return path, nil
+}
+
+// trustedWindow reports whether the captured window's class is on behavior.confirm_except_classes,
+// so its text is injected without confirmation. Any doubt about the class means asking.
+func (p *pipeline) trustedWindow(ctx context.Context, windowAddress string) bool {
+	if len(p.config.Behavior.ConfirmExceptClasses) == 0 {
+		return false
+	}
+	windows := p.windows
+	if windows == nil {
+		windows = injection.NewWindowManager(p.config.Injection.Compositor)
+	}
+	if windows == nil {
+		log.Printf("Pipeline: window classes unavailable on this compositor, confirm_except_classes not applied")
+		return false
+	}
+
+	class, err := windows.WindowClass(ctx, windowAddress)
+	if err != nil {
+		log.Printf("Pipeline: Could not determine window class, asking for confirmation: %v", err)
+		return false
+	}
+	for _, trusted := range p.config.Behavior.ConfirmExceptClasses {
+		if strings.EqualFold(class, trusted) {
+			log.Printf("Pipeline: Window class %q is trusted, skipping confirmation", class)
+			return true
+		}
+	}
+	return false
+}
+
+// awaitConfirmation shows the final text and blocks until it is confirmed or discarded.
+// Returns true if the text should be injected.
+func (p *pipeline) awaitConfirmation(ctx context.Context, text string) bool {
+	log.Printf("Pipeline: Awaiting confirmation before injection")
+	p.setStatus(Confirming)
+	p.sendNotification("Hyprvoice - Confirm Transcription", fmt.Sprintf("%s\n\nRun 'hyprvoice confirm' to inject or 'hyprvoice discard' to drop it", text))
+	return p.holdForConfirm(ctx, text, Confirm)
+}
+
+// awaitInject holds the text for behavior.explicit_inject until "hyprvoice inject", so the
+// user can put the cursor where it belongs first. It shares the confirming state but only
+// the inject action releases the text.
+func (p *pipeline) awaitInject(ctx context.Context, text string) bool {
+	log.Printf("Pipeline: Holding transcription until inject")
+	p.setStatus(Confirming)
+	p.sendNotification("Hyprvoice - Transcription Ready", fmt.Sprintf("%s\n\nRun 'hyprvoice inject' to type it at the cursor or 'hyprvoice discard' to drop it", text))
+	return p.holdForConfirm(ctx, text, Inject)
+}
+
+// holdForConfirm waits for release (Confirm or Inject, depending on the flow) or discard and
+// reports whether the text was released. Text still held when behavior.confirm_timeout
+// runs out is kept, see keepUnconfirmed.
+func (p *pipeline) holdForConfirm(ctx context.Context, text string, release Action) bool {
+	for {
+		select {
+		case action := <-p.actionCh:
+			switch action {
+			case release:
+				log.Printf("Pipeline: Transcription released by %s", action)
+				p.setStatus(Injecting)
+				return true
+			case Discard:
+				log.Printf("Pipeline: Transcription discarded")
+				p.sendNotification("Hyprvoice", "Transcription Discarded")
+				return false
+			default:
+				log.Printf("Pipeline: Ignoring action %s while waiting for %s", action, release)
+			}
+		case <-ctx.Done():
+			log.Printf("Pipeline: Context done while waiting for %s: %v", release, ctx.Err())
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				p.keepUnconfirmed(text, release)
 			}
+			return false
 		}
 	}
+}
 
-	log.Printf("Pipeline: Final text for injection: %s", transcriptionText)
+// unconfirmedCopyTimeout bounds copying text whose confirmation timed out; the context
+// it was held under has already expired
+const unconfirmedCopyTimeout = 3 * time.Second
 
-	injector := injection.NewInjector(p.config.ToInjectionConfig())
+// keepUnconfirmed puts text nobody confirmed in time into the clipboard and the failsafe
+// file, like a failed injection, so it isn't lost without a trace
+func (p *pipeline) keepUnconfirmed(text string, release Action) {
+	ctx, cancel := context.WithTimeout(context.Background(), unconfirmedCopyTimeout)
+	defer cancel()
 
-	windowAddress := p.GetWindowAddress()
-	if err := injector.Inject(ctx, transcriptionText, windowAddress); err != nil {
-		p.sendError("Injection Error", "Failed to inject text", err)
+	var kept []string
+	copyErr := injection.CopyToClipboard(ctx, text, p.config.Injection.ClipboardMIME)
+	if copyErr != nil {
+		log.Printf("Pipeline: Failed to copy unconfirmed transcription to clipboard: %v", copyErr)
 	} else {
-		log.Printf("Pipeline: Text injection completed successfully")
+		kept = append(kept, "copied to clipboard")
+	}
+	if path, err := p.saveFailsafe(text); err != nil {
+		log.Printf("Pipeline: Failed to save transcription to failsafe file: %v", err)
+	} else if path != "" {
+		kept = append(kept, "saved to "+path)
 	}
 
-	p.setStatus(Idle)
+	waited, title := "Confirmation", "Confirmation Timed Out"
+	if release == Inject {
+		waited, title = "Inject", "Inject Timed Out"
+	}
+	message := waited + " timed out, transcription dropped"
+	if len(kept) > 0 {
+		message = waited + " timed out, transcription " + strings.Join(kept, " and ")
+	}
+	p.sendError(ErrorKindInjection, title, message, context.DeadlineExceeded)
 }
 
 func (p *pipeline) Stop() {
@@ -277,6 +861,30 @@ func (p *pipeline) SetWindowAddress(address string) {
 	p.windowAddress = address
 }
 
+// SetMergeState shares the daemon's last injected block so this dictation can continue it
+func (p *pipeline) SetMergeState(merge *MergeState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.merge = merge
+}
+
+// SetTag labels this dictation so its log lines and failsafe entry can be told apart
+func (p *pipeline) SetTag(tag string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tag = tag
+}
+
+// getTag returns the dictation's label formatted for log lines and failsafe entries ("" when untagged)
+func (p *pipeline) getTag() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.tag == "" {
+		return ""
+	}
+	return "[" + p.tag + "] "
+}
+
 func (p *pipeline) GetWindowAddress() string {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -2,10 +2,20 @@ package pipeline
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/leonardotrapani/hyprvoice/internal/config"
+	"github.com/leonardotrapani/hyprvoice/internal/injection"
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+	"github.com/leonardotrapani/hyprvoice/internal/transcriber"
+	"github.com/sashabaranov/go-openai"
 )
 
 func TestNew(t *testing.T) {
@@ -277,6 +287,7 @@ func TestStatus_String(t *testing.T) {
 		{Idle, "idle"},
 		{Recording, "recording"},
 		{Transcribing, "transcribing"},
+		{Confirming, "confirming"},
 		{Injecting, "injecting"},
 	}
 
@@ -295,6 +306,8 @@ func TestAction_String(t *testing.T) {
 		expected string
 	}{
 		{Inject, "inject"},
+		{Confirm, "confirm"},
+		{Discard, "discard"},
 	}
 
 	for _, tt := range tests {
@@ -308,11 +321,16 @@ func TestAction_String(t *testing.T) {
 
 func TestPipelineError_Struct(t *testing.T) {
 	err := PipelineError{
+		Kind:    ErrorKindInjection,
 		Title:   "Test Title",
 		Message: "Test Message",
 		Err:     nil,
 	}
 
+	if err.Kind != ErrorKindInjection {
+		t.Errorf("Kind = %s, want %s", err.Kind, ErrorKindInjection)
+	}
+
 	if err.Title != "Test Title" {
 		t.Errorf("Title = %s, want %s", err.Title, "Test Title")
 	}
@@ -377,3 +395,770 @@ func TestPipeline_ConcurrentAccess(t *testing.T) {
 	<-done
 	<-done
 }
+
+func TestPipeline_RecoverPanic(t *testing.T) {
+	p := New(&config.Config{}).(*pipeline)
+
+	func() {
+		defer p.recoverPanic("transcription")
+		panic("provider bug")
+	}()
+
+	select {
+	case err := <-p.GetErrorCh():
+		if err.Kind != ErrorKindInternal {
+			t.Errorf("Kind = %q, want %q", err.Kind, ErrorKindInternal)
+		}
+		if err.Err == nil || !strings.Contains(err.Err.Error(), "provider bug") {
+			t.Errorf("Err = %v, want the panic value", err.Err)
+		}
+	default:
+		t.Fatal("recoverPanic() should report the panic as a pipeline error")
+	}
+}
+
+func TestPipeline_WarnBeforeTimeout(t *testing.T) {
+	cfg := &config.Config{Recording: config.RecordingConfig{Timeout: 40 * time.Millisecond, TimeoutWarning: 0.5}}
+
+	p := New(cfg).(*pipeline)
+	p.setStatus(Transcribing)
+	defer p.warnBeforeTimeout()()
+	select {
+	case n := <-p.GetNotifyCh():
+		if !strings.Contains(n.Message, "Recording stops in") {
+			t.Errorf("Message = %q, want a timeout warning", n.Message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no warning before the recording timeout")
+	}
+
+	// Once injecting, the recording is over and there is nothing to warn about
+	p = New(cfg).(*pipeline)
+	p.setStatus(Injecting)
+	p.warnBeforeTimeout()
+	time.Sleep(60 * time.Millisecond)
+	select {
+	case n := <-p.GetNotifyCh():
+		t.Errorf("unexpected notification %q after recording ended", n.Message)
+	default:
+	}
+
+	cfg.Recording.TimeoutWarning = 0
+	if p.warnBeforeTimeout()() {
+		t.Error("timeout_warning = 0 should not arm a warning")
+	}
+}
+
+func TestTranscriptionErrorKind(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"missing api key", fmt.Errorf("OpenAI %w", transcriber.ErrAPIKeyRequired), ErrorKindTranscriptionAuth},
+		{"unauthorized", fmt.Errorf("openai transcribe: %w", &openai.APIError{HTTPStatusCode: 401}), ErrorKindTranscriptionAuth},
+		{"server error", fmt.Errorf("openai transcribe: %w", &openai.APIError{HTTPStatusCode: 500}), ErrorKindTranscriptionNetwork},
+		{"connection refused", errors.New("dial tcp: connection refused"), ErrorKindTranscriptionNetwork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transcriptionErrorKind(tt.err); got != tt.want {
+				t.Errorf("transcriptionErrorKind() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipeline_StatusCh(t *testing.T) {
+	p := New(&config.Config{}).(*pipeline)
+	statusCh := p.GetStatusCh()
+
+	p.setStatus(Recording)
+	p.setStatus(Recording) // unchanged status is not reported again
+	p.setStatus(Transcribing)
+
+	var got []Status
+	for len(statusCh) > 0 {
+		got = append(got, <-statusCh)
+	}
+
+	want := []Status{Recording, Transcribing}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("status changes = %v, want %v", got, want)
+	}
+}
+
+func TestPipeline_SaveFailsafe(t *testing.T) {
+	p := New(&config.Config{}).(*pipeline)
+	if path, err := p.saveFailsafe("lost words"); path != "" || err != nil {
+		t.Errorf("saveFailsafe() = %q, %v, want disabled without failsafe_file", path, err)
+	}
+
+	dir := t.TempDir()
+	t.Setenv("HYPRVOICE_TEST_DIR", dir)
+	p.config.Behavior.FailsafeFile = "$HYPRVOICE_TEST_DIR/state/failed.txt"
+
+	for _, text := range []string{"first try", "second try"} {
+		if _, err := p.saveFailsafe(text); err != nil {
+			t.Fatalf("saveFailsafe() error = %v", err)
+		}
+	}
+
+	path := filepath.Join(dir, "state", "failed.txt")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read failsafe file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], " first try") || !strings.HasSuffix(lines[1], " second try") {
+		t.Errorf("failsafe file = %q, want both transcriptions appended", data)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm() != 0600 {
+		t.Errorf("failsafe file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A tagged dictation carries its label in the entry
+	p.SetTag("standup")
+	if _, err := p.saveFailsafe("third try"); err != nil {
+		t.Fatalf("saveFailsafe() error = %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.HasSuffix(string(data), " [standup] third try\n") {
+		t.Errorf("failsafe file = %q, want the tagged entry last", data)
+	}
+}
+
+func TestContinueSentence(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"And then we left.", "and then we left."},
+		{"  Which is fine", "  which is fine"},
+		{"I think so", "I think so"},
+		{"I'm not sure", "I'm not sure"},
+		{"NASA launched it", "NASA launched it"},
+		{"Écoute bien", "écoute bien"},
+		{"already lowercase", "already lowercase"},
+		{"\"Quoted\" start", "\"Quoted\" start"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := continueSentence(tt.input); got != tt.want {
+				t.Errorf("continueSentence(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeStage struct {
+	name   string
+	suffix string
+	err    error
+}
+
+func (s fakeStage) Name() string { return s.name }
+
+func (s fakeStage) Process(_ context.Context, text string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return text + s.suffix, nil
+}
+
+func TestRunStages(t *testing.T) {
+	stages := []Stage{
+		fakeStage{name: "a", suffix: " a"},
+		fakeStage{name: "broken", err: errors.New("boom")},
+		fakeStage{name: "b", suffix: " b"},
+	}
+	got, err := RunStages(context.Background(), stages, "text")
+	if got != "text a b" {
+		t.Errorf("RunStages() = %q, want %q", got, "text a b")
+	}
+	if err == nil || !strings.Contains(err.Error(), "broken stage: boom") {
+		t.Errorf("RunStages() error = %v, want the broken stage's error", err)
+	}
+}
+
+func TestNewStages(t *testing.T) {
+	cfg := &config.Config{Processing: config.ProcessingConfig{
+		Mode:         "raw",
+		Pipeline:     []string{config.StageSnippets, config.StageLLM, config.StageReplace},
+		Replacements: map[string]string{"hyper voice": "Hyprvoice"},
+		Snippets:     map[string]string{"my email": "jane@example.com"},
+	}}
+	stages, err := NewStages(cfg)
+	if err != nil {
+		t.Fatalf("NewStages() error = %v", err)
+	}
+	var names []string
+	for _, stage := range stages {
+		names = append(names, stage.Name())
+	}
+	if want := "snippets,replace"; strings.Join(names, ",") != want {
+		t.Errorf("NewStages() stages = %v, want %s (llm skipped in raw mode)", names, want)
+	}
+
+	cfg.Processing.Pipeline = []string{}
+	if stages, _ := NewStages(cfg); len(stages) != 0 {
+		t.Errorf("NewStages() with an empty pipeline = %d stages, want 0", len(stages))
+	}
+}
+
+func TestReplaceStage(t *testing.T) {
+	stage := newReplaceStage(map[string]string{
+		"hyper voice": "Hyprvoice",
+		"hyper":       "Hypr",
+		"cube":        "kube",
+		"café":        "Café Central",
+		"über":        "Uber",
+		"c++":         "C++",
+		"gonna":       "going to",
+		"going":       "heading",
+		"to":          "2",
+	})
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"I use Hyper Voice daily.", "I use Hyprvoice daily."},
+		{"hyper and cube", "Hypr and kube"},
+		{"cubes and hyperlinks stay", "cubes and hyperlinks stay"},
+		{"hyper voices", "Hypr voices"},
+		{"Meet at the CAFÉ, then call über.", "Meet at the Café Central, then call Uber."},
+		{"cafés and überall stay", "cafés and überall stay"},
+		{"I write c++. And c++x stays", "I write C++. And c++x stays"},
+		{"gonna go to say going", "going to go 2 say heading"},
+	}
+	for _, tt := range tests {
+		if got, _ := stage.Process(context.Background(), tt.input); got != tt.want {
+			t.Errorf("replace(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSnippetStage(t *testing.T) {
+	stage := newSnippetStage(map[string]string{"My Email": "jane@example.com"})
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"my email", "jane@example.com"},
+		{" My email. ", "jane@example.com"},
+		{"Send it to my email.", "Send it to my email."},
+	}
+	for _, tt := range tests {
+		if got, _ := stage.Process(context.Background(), tt.input); got != tt.want {
+			t.Errorf("snippets(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+type fakeProcessor struct {
+	output string
+}
+
+func (p fakeProcessor) Process(_ context.Context, _ string) (string, error) {
+	return p.output, nil
+}
+
+func TestLLMStage_MinOutputRatio(t *testing.T) {
+	input := "um so I think we should uh ship the release on Friday"
+	tests := []struct {
+		name     string
+		output   string
+		ratio    float64
+		wantErr  bool
+		wantText string
+	}{
+		{"cleaned", "I think we should ship the release on Friday.", 0.5, false, "I think we should ship the release on Friday."},
+		{"truncated", "Ship it.", 0.5, true, ""},
+		{"truncated without guard", "Ship it.", 0, false, "Ship it."},
+		{"empty", "  ", 0, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage := llmStage{processor: fakeProcessor{tt.output}, minOutputRatio: tt.ratio}
+			got, err := stage.Process(context.Background(), input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, errDegradedOutput) {
+				t.Errorf("Process() error = %v, want errDegradedOutput", err)
+			}
+			if got != tt.wantText {
+				t.Errorf("Process() = %q, want %q", got, tt.wantText)
+			}
+		})
+	}
+}
+
+type failingProcessor struct{}
+
+func (failingProcessor) Process(_ context.Context, _ string) (string, error) {
+	return "", errors.New("429 too many requests")
+}
+
+func TestLLMFailed(t *testing.T) {
+	raw := "um ship it on friday"
+	tests := []struct {
+		name  string
+		stage Stage
+		want  bool
+	}{
+		{"request error", llmStage{processor: failingProcessor{}}, true},
+		{"degraded output", llmStage{processor: fakeProcessor{" "}}, true},
+		{"llm succeeded", llmStage{processor: fakeProcessor{"Ship it on Friday."}}, false},
+		{"other stage failed", commandStage{command: "exit 1", timeout: time.Second}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RunStages(context.Background(), []Stage{tt.stage}, raw)
+			if got := llmFailed(err); got != tt.want {
+				t.Errorf("llmFailed(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandStage(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		want    string
+		wantErr string
+	}{
+		{"stdout replaces the text", "tr a-z A-Z", "HELLO WORLD", ""},
+		{"only the final newline is trimmed", "printf 'a\\n\\n'", "a\n", ""},
+		{"exit status fails the stage", "echo broken >&2; exit 3", "", "broken"},
+		{"empty output fails the stage", "cat >/dev/null", "", "empty output"},
+		{"timeout", "sleep 5", "", "timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage := commandStage{command: tt.command, timeout: 200 * time.Millisecond}
+			got, err := stage.Process(context.Background(), "hello world")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Process() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Process() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	// A failing command leaves the text for the next stage unchanged
+	text, err := RunStages(context.Background(), []Stage{commandStage{command: "exit 1", timeout: time.Second}}, "keep me")
+	if text != "keep me" || err == nil {
+		t.Errorf("RunStages() = %q, %v, want the input back with an error", text, err)
+	}
+}
+
+// fixedTranscriber returns text as its final transcription
+type fixedTranscriber struct {
+	text string
+}
+
+func (f fixedTranscriber) Start(context.Context, <-chan recording.AudioFrame) (<-chan error, error) {
+	return nil, nil
+}
+func (f fixedTranscriber) Stop(context.Context) error { return nil }
+func (f fixedTranscriber) GetFinalTranscription() (transcriber.TranscriptionResult, error) {
+	return transcriber.TranscriptionResult{Text: f.text}, nil
+}
+
+func TestPipeline_FinishTranscription_NothingToInject(t *testing.T) {
+	cfg := &config.Config{Processing: config.ProcessingConfig{
+		Mode:         "raw",
+		Pipeline:     []string{config.StageReplace},
+		Replacements: map[string]string{"um": ""},
+	}}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"  ", "No speech detected"},
+		{"Um", "Nothing left to inject after processing"},
+	}
+	for _, tt := range tests {
+		p := New(cfg).(*pipeline)
+		p.finishTranscription(context.Background(), fixedTranscriber{text: tt.text})
+
+		select {
+		case err := <-p.errorCh:
+			t.Errorf("finishTranscription(%q) sent error %q, want a notification", tt.text, err.Message)
+		case n := <-p.notifyCh:
+			if n.Message != tt.want {
+				t.Errorf("finishTranscription(%q) notification = %q, want %q", tt.text, n.Message, tt.want)
+			}
+		default:
+			t.Errorf("finishTranscription(%q) sent nothing, want %q", tt.text, tt.want)
+		}
+	}
+}
+
+func TestPipeline_FinishTranscription_MergeWindow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dictation.txt")
+	cfg := &config.Config{
+		Processing: config.ProcessingConfig{Mode: "raw"},
+		Injection: config.InjectionConfig{
+			Backends: []string{"file"},
+			FilePath: path,
+			FileMode: injection.FileModeOverwrite,
+			Prefix:   "> ",
+		},
+		Behavior: config.BehaviorConfig{MergeWindow: 10 * time.Second},
+	}
+	merge := &MergeState{}
+
+	tests := []struct {
+		name    string
+		text    string
+		started time.Duration // When the dictation started recording, relative to now
+		want    string
+	}{
+		{"first dictation", "First part.", 0, "> First part."},
+		{"within the window", "second part.", 0, " second part."},
+		{"after the window", "New block.", time.Minute, "> New block."},
+	}
+	for _, tt := range tests {
+		p := New(cfg).(*pipeline)
+		p.SetMergeState(merge)
+		p.recordingStarted = time.Now().Add(tt.started)
+		p.finishTranscription(context.Background(), fixedTranscriber{text: tt.text})
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := strings.TrimSuffix(string(data), "\n"); got != tt.want {
+			t.Errorf("%s: injected %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if block, ok := merge.previous("", time.Now(), time.Second); !ok || block != "> New block." {
+		t.Errorf("merge.previous() = %q, %v, want the latest block", block, ok)
+	}
+	if _, ok := merge.previous("0xother", time.Now(), time.Second); ok {
+		t.Error("merge.previous() should not continue a block in another window")
+	}
+}
+
+func TestPipeline_FinishTranscription_MergeWindowExplicitInject(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dictation.txt")
+	cfg := &config.Config{
+		Processing: config.ProcessingConfig{Mode: "raw"},
+		Injection: config.InjectionConfig{
+			Backends: []string{"file"},
+			FilePath: path,
+			FileMode: injection.FileModeOverwrite,
+			Prefix:   "> ",
+		},
+		Behavior: config.BehaviorConfig{MergeWindow: 10 * time.Second, ExplicitInject: true},
+	}
+	merge := &MergeState{}
+
+	for _, text := range []string{"First part.", "second part."} {
+		p := New(cfg).(*pipeline)
+		p.SetMergeState(merge)
+		p.SetWindowAddress("0xcaptured")
+		p.recordingStarted = time.Now()
+		p.GetActionCh() <- Inject // Released as soon as it is held
+		p.finishTranscription(context.Background(), fixedTranscriber{text: text})
+	}
+
+	// The cursor may have been in another window, so each dictation starts a new block
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSuffix(string(data), "\n"); got != "> second part." {
+		t.Errorf("injected %q, want %q", got, "> second part.")
+	}
+	if block, ok := merge.previous("0xcaptured", time.Now(), time.Minute); ok {
+		t.Errorf("merge.previous() = %q, want nothing recorded under explicit_inject", block)
+	}
+}
+
+func TestMergeSeparator(t *testing.T) {
+	tests := []struct {
+		previous, next, want string
+	}{
+		{"Hello.", "world", " "},
+		{"Hello. ", "world", ""},
+		{"Hello.\n", "world", ""},
+		{"Hello.", " world", ""},
+		{"", "world", ""},
+	}
+	for _, tt := range tests {
+		if got := mergeSeparator(tt.previous, tt.next); got != tt.want {
+			t.Errorf("mergeSeparator(%q, %q) = %q, want %q", tt.previous, tt.next, got, tt.want)
+		}
+	}
+}
+
+func TestStripLLMFormatting(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		output string
+		want   string
+	}{
+		{"plain", "um ship it friday", "Ship it Friday.", "Ship it Friday."},
+		{"preamble", "um ship it friday", "Here is the cleaned text: Ship it Friday.", "Ship it Friday."},
+		{"preamble on its own line", "um ship it friday", "Sure! Here's the corrected version:\n\nShip it Friday.", "Ship it Friday."},
+		{"wrapping quotes", "ship it friday", `"Ship it Friday."`, "Ship it Friday."},
+		{"curly quotes", "ship it friday", "“Ship it Friday.”", "Ship it Friday."},
+		{"two quoted phrases", "say yes or no", `"Yes" or "no"`, `"Yes" or "no"`},
+		{"dictated quotes", `"ship it" he said`, `"Ship it," he said."`, `"Ship it," he said."`},
+		{"code fence", "ship it friday", "```text\nShip it Friday.\n```", "Ship it Friday."},
+		{"bullets", "first ship second test", "- Ship it.\n- Test it.", "Ship it.\nTest it."},
+		{"dictated bullets", "- ship it", "- Ship it.", "- Ship it."},
+		{"bold and heading", "note ship it friday", "## Note\n**Ship it** Friday.", "Note\nShip it Friday."},
+	}
+	for _, tt := range tests {
+		if got := stripLLMFormatting(tt.input, tt.output); got != tt.want {
+			t.Errorf("%s: stripLLMFormatting() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	stage := llmStage{processor: fakeProcessor{`Here is the cleaned text: "Ship it."`}, stripFormatting: true}
+	if got, err := stage.Process(context.Background(), "ship it"); err != nil || got != "Ship it." {
+		t.Errorf("llmStage.Process() with strip_formatting = %q, %v, want %q", got, err, "Ship it.")
+	}
+}
+
+func TestPipeline_FinalizeContext(t *testing.T) {
+	cfg := &config.Config{
+		Recording:     config.RecordingConfig{Timeout: 10 * time.Millisecond},
+		Transcription: config.TranscriptionConfig{FinalizeTimeout: time.Minute},
+	}
+	p := New(cfg).(*pipeline)
+	session, cancelSession := context.WithCancel(context.Background())
+	defer cancelSession()
+	p.session = session
+
+	// A recording that used up its timeout still leaves the full finalize time
+	runCtx, cancelRun := context.WithTimeout(session, cfg.Recording.Timeout)
+	defer cancelRun()
+	<-runCtx.Done()
+
+	finalCtx, cancel := p.finalizeContext()
+	defer cancel()
+	if err := finalCtx.Err(); err != nil {
+		t.Fatalf("finalize context already done: %v", err)
+	}
+	if deadline, ok := finalCtx.Deadline(); !ok || time.Until(deadline) < 50*time.Second {
+		t.Errorf("finalize deadline = %v away, want about transcription.finalize_timeout", time.Until(deadline))
+	}
+
+	// Stopping the pipeline still aborts finalizing
+	cancelSession()
+	select {
+	case <-finalCtx.Done():
+	case <-time.After(time.Second):
+		t.Error("finalize context should end when the session is canceled")
+	}
+
+	p.config = &config.Config{}
+	defaultCtx, cancelDefault := p.finalizeContext()
+	defer cancelDefault()
+	if deadline, ok := defaultCtx.Deadline(); !ok || time.Until(deadline) > config.DefaultFinalizeTimeout {
+		t.Errorf("finalize deadline without config = %v away, want %v", time.Until(deadline), config.DefaultFinalizeTimeout)
+	}
+}
+
+// classWindowManager reports a fixed class for every window
+type classWindowManager struct {
+	class string
+	err   error
+}
+
+func (c classWindowManager) Name() string { return "fake" }
+func (c classWindowManager) ActiveWindow(ctx context.Context) (string, error) {
+	return "0xabc", nil
+}
+func (c classWindowManager) FocusWindow(ctx context.Context, address string) error { return nil }
+func (c classWindowManager) WindowClass(ctx context.Context, address string) (string, error) {
+	return c.class, c.err
+}
+
+func TestPipeline_TrustedWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted []string
+		windows classWindowManager
+		want    bool
+	}{
+		{name: "no trusted classes", windows: classWindowManager{class: "obsidian"}, want: false},
+		{name: "trusted class", trusted: []string{"obsidian"}, windows: classWindowManager{class: "obsidian"}, want: true},
+		{name: "case-insensitive", trusted: []string{"Obsidian"}, windows: classWindowManager{class: "obsidian"}, want: true},
+		{name: "other class", trusted: []string{"obsidian"}, windows: classWindowManager{class: "discord"}, want: false},
+		{name: "class unknown", trusted: []string{"obsidian"}, windows: classWindowManager{err: errors.New("no such window")}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Behavior: config.BehaviorConfig{ConfirmBeforeInject: true, ConfirmExceptClasses: tt.trusted}}
+			p := New(cfg).(*pipeline)
+			p.windows = tt.windows
+			if got := p.trustedWindow(context.Background(), "0xabc"); got != tt.want {
+				t.Errorf("trustedWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipelineError_Hint(t *testing.T) {
+	tests := []struct {
+		name string
+		err  PipelineError
+		want string // Substring of the hint, "" for none
+	}{
+		{name: "auth", err: PipelineError{Kind: ErrorKindTranscriptionAuth, Err: transcriber.ErrAPIKeyRequired}, want: "API key"},
+		{name: "timeout", err: PipelineError{Kind: ErrorKindTranscriptionNetwork, Err: context.DeadlineExceeded}, want: "finalize_timeout"},
+		{name: "network", err: PipelineError{Kind: ErrorKindTranscriptionNetwork, Err: errors.New("dial tcp: no such host")}, want: "internet connection"},
+		{name: "ydotoold down", err: PipelineError{Kind: ErrorKindInjection, Err: errors.New("all injection backends failed: ydotool: ydotoold socket not found - ensure ydotoold is running")}, want: "systemctl --user enable --now ydotool"},
+		{name: "no wayland", err: PipelineError{Kind: ErrorKindInjection, Err: errors.New("wtype: WAYLAND_DISPLAY not set - wtype requires Wayland session")}, want: "Wayland session"},
+		{name: "denied window", err: PipelineError{Kind: ErrorKindInjection, Err: injection.ErrDeniedWindow}, want: ""},
+		{name: "llm failure", err: PipelineError{Kind: ErrorKindLLM, Err: errors.New("model overloaded")}, want: ""},
+		{name: "internal", err: PipelineError{Kind: ErrorKindInternal, Err: errors.New("panic: boom")}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hint := tt.err.Hint()
+			if tt.want == "" {
+				if hint != "" {
+					t.Errorf("Hint() = %q, want none", hint)
+				}
+				return
+			}
+			if !strings.Contains(hint, tt.want) {
+				t.Errorf("Hint() = %q, want it to mention %q", hint, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipeline_AwaitInject(t *testing.T) {
+	for _, tt := range []struct {
+		action Action
+		want   bool
+	}{
+		{Inject, true},
+		{Discard, false},
+	} {
+		t.Run(string(tt.action), func(t *testing.T) {
+			p := New(&config.Config{Behavior: config.BehaviorConfig{ExplicitInject: true}}).(*pipeline)
+			done := make(chan bool)
+			go func() { done <- p.awaitInject(context.Background(), "hello") }()
+
+			notification := <-p.GetNotifyCh()
+			if !strings.Contains(notification.Message, "hyprvoice inject") {
+				t.Errorf("notification = %q, want it to mention hyprvoice inject", notification.Message)
+			}
+			if status := p.Status(); status != Confirming {
+				t.Errorf("Status() = %s, want %s while holding the text", status, Confirming)
+			}
+			p.GetActionCh() <- Confirm // Belongs to confirm_before_inject and must not release the text
+			p.GetActionCh() <- tt.action
+			if got := <-done; got != tt.want {
+				t.Errorf("awaitInject() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipeline_ConfirmationTimeoutKeepsText(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{Behavior: config.BehaviorConfig{ConfirmBeforeInject: true, FailsafeFile: filepath.Join(dir, "failed.txt")}}
+	p := New(cfg).(*pipeline)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if p.awaitConfirmation(ctx, "important words") {
+		t.Fatal("awaitConfirmation() = true, want false after the timeout")
+	}
+
+	data, err := os.ReadFile(cfg.Behavior.FailsafeFile)
+	if err != nil || !strings.HasSuffix(string(data), " important words\n") {
+		t.Errorf("failsafe file = %q, %v, want the unconfirmed text", data, err)
+	}
+	select {
+	case pipelineErr := <-p.GetErrorCh():
+		if !strings.Contains(pipelineErr.Message, "saved to") {
+			t.Errorf("error message = %q, want it to say where the text went", pipelineErr.Message)
+		}
+	default:
+		t.Error("a timed out confirmation should be reported")
+	}
+
+	// Canceling is a deliberate discard and keeps nothing
+	os.Remove(cfg.Behavior.FailsafeFile)
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	p.awaitConfirmation(canceled, "dropped words")
+	if _, err := os.Stat(cfg.Behavior.FailsafeFile); !os.IsNotExist(err) {
+		t.Errorf("canceled confirmation wrote the failsafe file: %v", err)
+	}
+}
+
+func TestPipeline_FinishTranscription_ReleaseAfterFinalizeDeadline(t *testing.T) {
+	tests := []struct {
+		name     string
+		behavior config.BehaviorConfig
+		release  Action
+	}{
+		{"confirm", config.BehaviorConfig{ConfirmBeforeInject: true, ConfirmTimeout: time.Minute}, Confirm},
+		{"explicit inject", config.BehaviorConfig{ExplicitInject: true, ConfirmTimeout: time.Minute}, Inject},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "dictation.txt")
+			cfg := &config.Config{
+				Processing: config.ProcessingConfig{Mode: "raw"},
+				Injection:  config.InjectionConfig{Backends: []string{"file"}, FilePath: path, FileMode: injection.FileModeOverwrite},
+				Behavior:   tt.behavior,
+			}
+			p := New(cfg).(*pipeline)
+
+			// The upload used up the finalize budget while the text was held
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			done := make(chan struct{})
+			go func() {
+				p.finishTranscription(ctx, fixedTranscriber{text: "Reviewed text."})
+				close(done)
+			}()
+			<-ctx.Done()
+			time.Sleep(20 * time.Millisecond)
+			if status := p.Status(); status != Confirming {
+				t.Fatalf("Status() = %s after the finalize deadline, want %s", status, Confirming)
+			}
+			p.GetActionCh() <- tt.release
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("finishTranscription() did not return after %s", tt.release)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil || strings.TrimSuffix(string(data), "\n") != "Reviewed text." {
+				t.Errorf("injected %q, %v, want the released text", data, err)
+			}
+		})
+	}
+}
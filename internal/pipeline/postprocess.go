@@ -0,0 +1,363 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/leonardotrapani/hyprvoice/internal/config"
+	"github.com/leonardotrapani/hyprvoice/internal/llm"
+)
+
+// Stage is one post-processing step in processing.pipeline
+type Stage interface {
+	Name() string
+	Process(ctx context.Context, text string) (string, error)
+}
+
+// NewStages builds the configured processing.pipeline in order. The llm stage is
+// left out unless processing.mode is "llm", so the mode still switches LLM cleanup on and off.
+func NewStages(cfg *config.Config) ([]Stage, error) {
+	names := cfg.Processing.Pipeline
+	if names == nil {
+		names = config.DefaultProcessingPipeline
+	}
+
+	var stages []Stage
+	for _, name := range names {
+		switch name {
+		case config.StageReplace:
+			if len(cfg.Processing.Replacements) > 0 {
+				stages = append(stages, newReplaceStage(cfg.Processing.Replacements))
+			}
+		case config.StageLLM:
+			if cfg.Processing.Mode != "llm" {
+				continue
+			}
+			processor, err := llm.NewProcessor(cfg.ToLLMConfig())
+			if err != nil {
+				return nil, fmt.Errorf("failed to create LLM processor: %w", err)
+			}
+			stages = append(stages, llmStage{processor: processor, minOutputRatio: cfg.LLM.MinOutputRatio, stripFormatting: cfg.LLM.StripFormatting})
+		case config.StageSnippets:
+			if len(cfg.Processing.Snippets) > 0 {
+				stages = append(stages, newSnippetStage(cfg.Processing.Snippets))
+			}
+		case config.StageCommand:
+			if cfg.Processing.Command != "" {
+				timeout := cfg.Processing.CommandTimeout
+				if timeout <= 0 {
+					timeout = config.DefaultCommandTimeout
+				}
+				stages = append(stages, commandStage{command: cfg.Processing.Command, timeout: timeout})
+			}
+		default:
+			return nil, fmt.Errorf("unknown processing stage: %s", name)
+		}
+	}
+	return stages, nil
+}
+
+// RunStages passes text through each stage in order. A failing stage is skipped, so the
+// next stage gets its input unchanged; the failures are returned joined alongside the text.
+func RunStages(ctx context.Context, stages []Stage, text string) (string, error) {
+	var errs []error
+	for _, stage := range stages {
+		processed, err := stage.Process(ctx, text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s stage: %w", stage.Name(), err))
+			continue
+		}
+		text = processed
+	}
+	return text, errors.Join(errs...)
+}
+
+// errDegradedOutput marks LLM output rejected by the llm.min_output_ratio guard
+var errDegradedOutput = errors.New("LLM output looks degraded")
+
+// errLLMFailed marks an LLM request that returned no output at all
+var errLLMFailed = errors.New("LLM request failed")
+
+// llmFailed reports whether the llm stage failed or had its output rejected
+func llmFailed(err error) bool {
+	return errors.Is(err, errLLMFailed) || errors.Is(err, errDegradedOutput)
+}
+
+type llmStage struct {
+	processor       llm.Processor
+	minOutputRatio  float64
+	stripFormatting bool
+}
+
+func (s llmStage) Name() string { return config.StageLLM }
+
+// Process rejects empty output, and output shorter than minOutputRatio of the input,
+// so an over-summarizing model can't silently mangle the dictation
+func (s llmStage) Process(ctx context.Context, text string) (string, error) {
+	processed, err := s.processor.Process(ctx, text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errLLMFailed, err)
+	}
+	if s.stripFormatting {
+		if stripped := stripLLMFormatting(text, processed); stripped != processed {
+			log.Printf("Pipeline: Stripped formatting the LLM added: %q -> %q", processed, stripped)
+			processed = stripped
+		}
+	}
+
+	in := utf8.RuneCountInString(strings.TrimSpace(text))
+	out := utf8.RuneCountInString(strings.TrimSpace(processed))
+	if out == 0 && in > 0 {
+		return "", fmt.Errorf("%w: empty output", errDegradedOutput)
+	}
+	if float64(out) < s.minOutputRatio*float64(in) {
+		return "", fmt.Errorf("%w: %d of %d characters, below llm.min_output_ratio %g", errDegradedOutput, out, in, s.minOutputRatio)
+	}
+	return processed, nil
+}
+
+// llmFormatting is markup models add despite being told to return plain text,
+// each with what replaces it (llm.strip_formatting)
+var llmFormatting = []struct {
+	pattern *regexp.Regexp
+	with    string
+}{
+	// "Here is the cleaned text:", "Sure! Here's the corrected version:", "Corrected text:"
+	{regexp.MustCompile(`(?i)^\s*(?:(?:sure|certainly|okay|ok|of course)[,.!]?\s+)?(?:here(?:'s| is| are)\b[^\n:]{0,60}|(?:the )?(?:cleaned|corrected|revised|edited|fixed)(?:[- ]up)? (?:text|version|transcription)):\s*`), ""},
+	{regexp.MustCompile("(?s)^\\s*```[a-z]*\\n(.*?)\\n```\\s*$"), "$1"},
+	{regexp.MustCompile(`(?m)^[ \t]*[-*•][ \t]+`), ""},
+	{regexp.MustCompile(`(?m)^[ \t]*#{1,6}[ \t]+`), ""},
+	{regexp.MustCompile(`\*\*([^*\n]+)\*\*`), "$1"},
+}
+
+// wrappingQuotes are the quote pairs a model may put around its whole answer
+var wrappingQuotes = [][2]string{{`"`, `"`}, {"“", "”"}, {"«", "»"}, {"'", "'"}, {"‘", "’"}}
+
+// stripLLMFormatting removes preambles, code fences, bullets, headings, bold markers and
+// quotes around the whole answer from LLM output. Markup that already appears in the
+// dictated input is left alone, since then the speaker asked for it.
+func stripLLMFormatting(input, output string) string {
+	for _, f := range llmFormatting {
+		if !f.pattern.MatchString(input) {
+			output = f.pattern.ReplaceAllString(output, f.with)
+		}
+	}
+	output = strings.TrimSpace(output)
+
+	input = strings.TrimSpace(input)
+	for _, q := range wrappingQuotes {
+		left, right := q[0], q[1]
+		if len(output) <= len(left)+len(right) || strings.HasPrefix(input, left) ||
+			!strings.HasPrefix(output, left) || !strings.HasSuffix(output, right) {
+			continue
+		}
+		// Only one pair around everything, not two quoted phrases like "yes" or "no"
+		inner := output[len(left) : len(output)-len(right)]
+		if !strings.Contains(inner, left) && !strings.Contains(inner, right) {
+			return strings.TrimSpace(inner)
+		}
+	}
+	return output
+}
+
+type replacement struct {
+	pattern *regexp.Regexp // Matches the key, ignoring case, at the start of the text only
+	with    string
+}
+
+// replaceStage substitutes whole words or phrases, ignoring case. Keys match only between
+// characters that aren't letters, digits or underscores in any script, since Go's \b only
+// knows ASCII, and the text is rewritten in one pass so a replacement is never replaced again.
+type replaceStage struct {
+	replacements []replacement
+}
+
+func newReplaceStage(words map[string]string) replaceStage {
+	// Longest first so "hyper voice" wins over a shorter "hyper" entry
+	from := make([]string, 0, len(words))
+	for word := range words {
+		from = append(from, word)
+	}
+	sort.Slice(from, func(i, j int) bool {
+		if len(from[i]) != len(from[j]) {
+			return len(from[i]) > len(from[j])
+		}
+		return from[i] < from[j]
+	})
+
+	stage := replaceStage{}
+	for _, word := range from {
+		key := strings.TrimSpace(word)
+		if key == "" {
+			continue
+		}
+		pattern := regexp.MustCompile(`\A(?i:` + regexp.QuoteMeta(key) + `)`)
+		stage.replacements = append(stage.replacements, replacement{pattern, words[word]})
+	}
+	return stage
+}
+
+func (s replaceStage) Name() string { return config.StageReplace }
+
+func (s replaceStage) Process(_ context.Context, text string) (string, error) {
+	if len(s.replacements) == 0 {
+		return text, nil
+	}
+
+	var b strings.Builder
+	copied := 0 // Text before this offset is already in b
+	for pos := 0; pos < len(text); {
+		if end, with, ok := s.matchAt(text, pos); ok {
+			b.WriteString(text[copied:pos])
+			b.WriteString(with)
+			pos, copied = end, end
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[pos:])
+		pos += size
+	}
+	if copied == 0 {
+		return text, nil
+	}
+	b.WriteString(text[copied:])
+	return b.String(), nil
+}
+
+// matchAt returns the end of the longest key that stands as a whole word at pos, and
+// what replaces it
+func (s replaceStage) matchAt(text string, pos int) (int, string, bool) {
+	if before, _ := utf8.DecodeLastRuneInString(text[:pos]); pos > 0 && isWordRune(before) {
+		return 0, "", false
+	}
+	for _, r := range s.replacements {
+		loc := r.pattern.FindStringIndex(text[pos:])
+		if loc == nil || loc[1] == 0 {
+			continue
+		}
+		end := pos + loc[1]
+		if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(after) {
+			continue
+		}
+		return end, r.with, true
+	}
+	return 0, "", false
+}
+
+// isWordRune reports whether r continues a word: a letter, digit, combining mark or underscore
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_'
+}
+
+// snippetStage replaces a dictation that is only a trigger phrase with its expansion
+type snippetStage struct {
+	snippets map[string]string
+}
+
+func newSnippetStage(snippets map[string]string) snippetStage {
+	stage := snippetStage{snippets: make(map[string]string, len(snippets))}
+	for trigger, expansion := range snippets {
+		stage.snippets[normalizeTrigger(trigger)] = expansion
+	}
+	return stage
+}
+
+func (s snippetStage) Name() string { return config.StageSnippets }
+
+func (s snippetStage) Process(_ context.Context, text string) (string, error) {
+	if expansion, ok := s.snippets[normalizeTrigger(text)]; ok {
+		return expansion, nil
+	}
+	return text, nil
+}
+
+// commandStage pipes the text to a user's shell command and uses its stdout. A failure,
+// timeout, or empty output fails the stage, so RunStages keeps the text unchanged.
+type commandStage struct {
+	command string
+	timeout time.Duration
+}
+
+func (s commandStage) Name() string { return config.StageCommand }
+
+func (s commandStage) Process(ctx context.Context, text string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.command)
+	cmd.Stdin = strings.NewReader(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // Don't hang on background children holding stdout open
+
+	output, err := cmd.Output()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("timed out after %v", s.timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+
+	result := strings.TrimSuffix(string(output), "\n")
+	if strings.TrimSpace(result) == "" {
+		return "", fmt.Errorf("empty output")
+	}
+	return result, nil
+}
+
+// normalizeTrigger lowercases a phrase and drops the surrounding whitespace and the
+// punctuation Whisper tends to add, so "My email." matches the trigger "my email"
+func normalizeTrigger(text string) string {
+	return strings.ToLower(strings.TrimFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}))
+}
+
+// continueSentence lowercases the first word so a dictated fragment can be inserted
+// into the middle of an existing sentence. The pronoun "I" (and its contractions) and
+// all-caps words such as acronyms are left untouched.
+func continueSentence(text string) string {
+	trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:len(text)-len(trimmed)]
+
+	end := strings.IndexFunc(trimmed, func(r rune) bool { return !unicode.IsLetter(r) })
+	word := trimmed
+	if end >= 0 {
+		word = trimmed[:end]
+	}
+
+	// "I'm" and "I'll" stop at the apostrophe, so this also covers contractions
+	if word == "" || word == "I" || isAllCaps(word) {
+		return text
+	}
+
+	first, size := utf8.DecodeRuneInString(trimmed)
+	return leading + string(unicode.ToLower(first)) + trimmed[size:]
+}
+
+func isAllCaps(word string) bool {
+	if utf8.RuneCountInString(word) < 2 {
+		return false
+	}
+	for _, r := range word {
+		if !unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
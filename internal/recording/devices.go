@@ -0,0 +1,103 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ListDevices returns the names of the capture devices the backend can record from right now,
+// as accepted by pw-record --target or parec --device
+func ListDevices(ctx context.Context, backend string) ([]string, error) {
+	listCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if backend == BackendPulse {
+		output, err := exec.CommandContext(listCtx, "pactl", "list", "short", "sources").Output()
+		if err != nil {
+			return nil, fmt.Errorf("pactl list sources failed: %w", err)
+		}
+		return parsePulseSources(output), nil
+	}
+
+	output, err := exec.CommandContext(listCtx, "pw-cli", "ls", "Node").Output()
+	if err != nil {
+		return nil, fmt.Errorf("pw-cli ls Node failed: %w", err)
+	}
+	return parsePipeWireNodes(output), nil
+}
+
+// parsePipeWireNodes extracts node.name values from `pw-cli ls Node` output
+func parsePipeWireNodes(output []byte) []string {
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.TrimSpace(key) != "node.name" {
+			continue
+		}
+		names = append(names, strings.Trim(strings.TrimSpace(value), `"`))
+	}
+	return names
+}
+
+// parsePulseSources extracts source names from `pactl list short sources` output
+// (tab-separated: index, name, driver, sample spec, state)
+func parsePulseSources(output []byte) []string {
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) >= 2 && fields[1] != "" {
+			names = append(names, fields[1])
+		}
+	}
+	return names
+}
+
+// pickDevice returns the first candidate that is currently available
+func pickDevice(candidates, available []string) (string, bool) {
+	present := make(map[string]bool, len(available))
+	for _, name := range available {
+		present[name] = true
+	}
+	for _, name := range candidates {
+		if present[name] {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// selectDevice walks DeviceFallback in order and returns the first device that is plugged in,
+// falling back to Device (empty = default input) when none of them are
+func (r *Recorder) selectDevice(ctx context.Context, backend string) string {
+	if len(r.config.DeviceFallback) == 0 {
+		return r.config.Device
+	}
+
+	available, err := ListDevices(ctx, backend)
+	if err != nil {
+		log.Printf("Recording: cannot list devices (%v), using %s", err, describeDevice(r.config.Device))
+		return r.config.Device
+	}
+	if device, ok := pickDevice(r.config.DeviceFallback, available); ok {
+		log.Printf("Recording: using device %s", device)
+		return device
+	}
+	log.Printf("Recording: none of %v available, using %s", r.config.DeviceFallback, describeDevice(r.config.Device))
+	return r.config.Device
+}
+
+// describeDevice names a device for log messages
+func describeDevice(device string) string {
+	if device == "" {
+		return "the default input"
+	}
+	return device
+}
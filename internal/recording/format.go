@@ -0,0 +1,84 @@
+package recording
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Sample formats the recorder can capture. Anything other than FormatS16 is converted to
+// s16 as it is read, since levels, silence trimming and the WAV upload all assume 16-bit samples.
+const (
+	FormatS16 = "s16" // 16-bit signed integer (default, no conversion)
+	FormatS24 = "s24" // 24-bit signed integer, packed in 3 bytes
+	FormatS32 = "s32" // 32-bit signed integer
+	FormatF32 = "f32" // 32-bit float in [-1, 1]
+)
+
+// SupportedFormats lists the accepted recording.format values
+var SupportedFormats = []string{FormatS16, FormatS24, FormatS32, FormatF32}
+
+// IsSupportedFormat reports whether format is one of SupportedFormats
+func IsSupportedFormat(format string) bool {
+	return sampleSize(format) > 0
+}
+
+// sampleSize returns the bytes per sample for format, or 0 when it is not supported
+func sampleSize(format string) int {
+	switch format {
+	case FormatS16:
+		return 2
+	case FormatS24:
+		return 3
+	case FormatS32, FormatF32:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// sampleConverter turns captured audio into little-endian s16. Reads from the capture tool
+// can end mid-sample, so the leftover bytes are kept for the next call.
+type sampleConverter struct {
+	format  string
+	pending []byte
+}
+
+func newSampleConverter(format string) *sampleConverter {
+	return &sampleConverter{format: format}
+}
+
+// convert returns data as s16, or a copy of it when the format already is s16
+func (c *sampleConverter) convert(data []byte) []byte {
+	if c.format == FormatS16 {
+		return append([]byte(nil), data...)
+	}
+
+	size := sampleSize(c.format)
+	data = append(c.pending, data...)
+	whole := len(data) - len(data)%size
+	c.pending = append([]byte(nil), data[whole:]...)
+
+	out := make([]byte, 0, whole/size*2)
+	for i := 0; i < whole; i += size {
+		out = binary.LittleEndian.AppendUint16(out, uint16(toS16(c.format, data[i:i+size])))
+	}
+	return out
+}
+
+// toS16 converts one little-endian sample to a 16-bit value
+func toS16(format string, sample []byte) int16 {
+	switch format {
+	case FormatS24:
+		v := int32(sample[0]) | int32(sample[1])<<8 | int32(int8(sample[2]))<<16
+		return int16(v >> 8)
+	case FormatS32:
+		return int16(int32(binary.LittleEndian.Uint32(sample)) >> 16)
+	case FormatF32:
+		f := math.Float32frombits(binary.LittleEndian.Uint32(sample))
+		if math.IsNaN(float64(f)) {
+			return 0
+		}
+		return int16(max(-1, min(1, f)) * math.MaxInt16)
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package recording
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// SilenceDBFS is reported for empty or all-zero audio
+const SilenceDBFS = -96.0
+
+const (
+	// TrimThresholdDBFS is the RMS level below which a window counts as silence for TrimSilence
+	TrimThresholdDBFS = -50.0
+
+	trimWindow  = 20 * time.Millisecond
+	trimPadding = 250 * time.Millisecond // Kept around speech so soft word onsets and endings survive
+)
+
+// Levels returns the peak and RMS level of 16-bit little-endian PCM in dBFS,
+// where 0 is full scale and SilenceDBFS means no signal at all
+func Levels(pcm []byte) (peak, rms float64) {
+	samples := len(pcm) / 2
+	if samples == 0 {
+		return SilenceDBFS, SilenceDBFS
+	}
+
+	var maxAbs, sumSquares float64
+	for i := 0; i < samples; i++ {
+		sample := math.Abs(float64(int16(binary.LittleEndian.Uint16(pcm[2*i:]))))
+		maxAbs = max(maxAbs, sample)
+		sumSquares += sample * sample
+	}
+	return toDBFS(maxAbs), toDBFS(math.Sqrt(sumSquares / float64(samples)))
+}
+
+// TrimSilence drops leading and trailing silence from 16-bit little-endian PCM,
+// measuring RMS in short windows against TrimThresholdDBFS and keeping a little padding
+// around the speech. Audio that is silent throughout comes back empty.
+func TrimSilence(pcm []byte, sampleRate, channels int) []byte {
+	frameBytes := 2 * channels
+	window := int(int64(sampleRate)*int64(trimWindow)/int64(time.Second)) * frameBytes
+	padding := int(int64(sampleRate)*int64(trimPadding)/int64(time.Second)) * frameBytes
+	if window <= 0 {
+		return pcm
+	}
+
+	first, last := -1, -1
+	for start := 0; start < len(pcm); start += window {
+		end := min(start+window, len(pcm))
+		if _, rms := Levels(pcm[start:end]); rms >= TrimThresholdDBFS {
+			if first < 0 {
+				first = start
+			}
+			last = end
+		}
+	}
+	if first < 0 {
+		return pcm[:0]
+	}
+	return pcm[max(first-padding, 0):min(last+padding, len(pcm))]
+}
+
+func toDBFS(amplitude float64) float64 {
+	if amplitude == 0 {
+		return SilenceDBFS
+	}
+	return max(20*math.Log10(amplitude/math.MaxInt16), SilenceDBFS)
+}
@@ -0,0 +1,58 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// ErrPulseAudioNotFound is returned when the PulseAudio tools are missing or the server does not respond
+var ErrPulseAudioNotFound = errors.New("PulseAudio not available")
+
+// CheckPulseAudioAvailable verifies that parec is installed and a PulseAudio-compatible server responds
+// (either PulseAudio itself or pipewire-pulse)
+func CheckPulseAudioAvailable(ctx context.Context) error {
+	for _, tool := range []string{"parec", "pactl"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("%w (%s missing: install pulseaudio-utils)", ErrPulseAudioNotFound, tool)
+		}
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := exec.CommandContext(checkCtx, "pactl", "info").Run(); err != nil {
+		return fmt.Errorf("%w (server not running or accessible): %v", ErrPulseAudioNotFound, err)
+	}
+	return nil
+}
+
+func (r *Recorder) buildParecArgs() []string {
+	args := []string{
+		"--raw",
+		"--format=" + pulseSampleFormat(r.config.Format),
+		"--rate=" + strconv.Itoa(r.config.SampleRate),
+		"--channels=" + strconv.Itoa(r.config.Channels),
+	}
+	if r.device != "" {
+		args = append(args, "--device="+r.device)
+	}
+	return args
+}
+
+// pulseSampleFormat maps PipeWire format names to their PulseAudio equivalents
+func pulseSampleFormat(format string) string {
+	switch format {
+	case "s16":
+		return "s16le"
+	case "s24":
+		return "s24le"
+	case "s32":
+		return "s32le"
+	case "f32":
+		return "float32le"
+	default:
+		return format
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"log"
 	"os/exec"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -19,29 +20,55 @@ type AudioFrame struct {
 	Timestamp time.Time
 }
 
+// Recording backends
+const (
+	BackendPipeWire = "pipewire" // Capture with pw-record (default)
+	BackendPulse    = "pulse"    // Capture with parec
+	BackendAuto     = "auto"     // Prefer PipeWire, fall back to PulseAudio
+)
+
 type Config struct {
+	Backend           string // BackendPipeWire (default), BackendPulse, or BackendAuto
 	SampleRate        int
 	Channels          int
 	Format            string
 	BufferSize        int
 	Device            string
+	DeviceFallback    []string // Devices tried in order before Device; the first one plugged in is used
 	ChannelBufferSize int
 	Timeout           time.Duration
+	StartDelay        time.Duration // Audio captured during this initial window is discarded
 }
 
 type Recorder struct {
 	config    Config
 	recording atomic.Bool
 
-	mu     sync.Mutex // guards cmd and cancel
-	cmd    *exec.Cmd
-	cancel context.CancelFunc
+	mu      sync.Mutex // guards cmd, cancel, backend and device
+	cmd     *exec.Cmd
+	cancel  context.CancelFunc
+	backend string // backend chosen by Start
+	device  string // device chosen by Start; empty records from the default input
 
 	wg sync.WaitGroup
 }
 
 func NewRecorder(config Config) *Recorder {
-	return &Recorder{config: config}
+	return &Recorder{config: config, device: config.Device}
+}
+
+// Backend returns the backend chosen by the last Start
+func (r *Recorder) Backend() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.backend
+}
+
+// Device returns the device chosen by the last Start, empty for the default input
+func (r *Recorder) Device() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.device
 }
 
 func (r *Recorder) IsRecording() bool {
@@ -57,9 +84,11 @@ func (r *Recorder) Start(ctx context.Context) (<-chan AudioFrame, <-chan error,
 		return nil, nil, err
 	}
 
-	if err := CheckPipeWireAvailable(ctx); err != nil {
-		return nil, nil, fmt.Errorf("PipeWire not available: %w", err)
+	backend, err := ResolveBackend(ctx, r.config.Backend)
+	if err != nil {
+		return nil, nil, err
 	}
+	device := r.selectDevice(ctx, backend)
 
 	recordingCtx, cancel := context.WithCancel(ctx)
 
@@ -68,6 +97,8 @@ func (r *Recorder) Start(ctx context.Context) (<-chan AudioFrame, <-chan error,
 
 	r.mu.Lock()
 	r.cancel = cancel
+	r.backend = backend
+	r.device = device
 	r.mu.Unlock()
 
 	r.recording.Store(true)
@@ -100,8 +131,13 @@ func (r *Recorder) captureLoop(ctx context.Context, frameCh chan<- AudioFrame, e
 		r.wg.Done()
 	}()
 
-	args := r.buildPwRecordArgs()
-	cmd := exec.CommandContext(ctx, "pw-record", args...)
+	r.mu.Lock()
+	tool, args := "pw-record", r.buildPwRecordArgs()
+	if r.backend == BackendPulse {
+		tool, args = "parec", r.buildParecArgs()
+	}
+	r.mu.Unlock()
+	cmd := exec.CommandContext(ctx, tool, args...)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -118,7 +154,7 @@ func (r *Recorder) captureLoop(ctx context.Context, frameCh chan<- AudioFrame, e
 	}
 
 	if err := cmd.Start(); err != nil {
-		r.emitErr(errCh, fmt.Errorf("start pw-record: %w", err))
+		r.emitErr(errCh, fmt.Errorf("start %s: %w", tool, err))
 		r.requestCancel()
 		return
 	}
@@ -127,6 +163,13 @@ func (r *Recorder) captureLoop(ctx context.Context, frameCh chan<- AudioFrame, e
 	r.cmd = cmd
 	r.mu.Unlock()
 
+	captureStart := time.Now().Add(r.config.StartDelay)
+	if r.config.StartDelay > 0 {
+		log.Printf("Recording: discarding the first %v of audio", r.config.StartDelay)
+	}
+
+	converter := newSampleConverter(r.config.Format)
+
 	go func() {
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
@@ -144,10 +187,16 @@ func (r *Recorder) captureLoop(ctx context.Context, frameCh chan<- AudioFrame, e
 			var droppedCount int
 			lastDropLog := time.Now()
 			n, readErr := stdout.Read(buffer)
+			var frameData []byte
 			if n > 0 {
-				frameData := make([]byte, n)
-				copy(frameData, buffer[:n])
-
+				// Convert even the dropped audio so the converter stays aligned to sample boundaries
+				frameData = converter.convert(buffer[:n])
+			}
+			if len(frameData) > 0 && time.Now().Before(captureStart) {
+				// Drop audio from the start delay so keybind clicks and notification sounds aren't transcribed
+				frameData = nil
+			}
+			if len(frameData) > 0 {
 				frame := AudioFrame{Data: frameData, Timestamp: time.Now()}
 
 				select {
@@ -202,22 +251,61 @@ func (r *Recorder) buildPwRecordArgs() []string {
 		"--channels", strconv.Itoa(r.config.Channels),
 		"-", // stdout
 	}
-	if r.config.Device != "" {
-		args = append(args, "--target", r.config.Device)
+	if r.device != "" {
+		args = append(args, "--target", r.device)
 	}
 	return args
 }
 
+// ResolveBackend checks that the configured backend is usable and returns the backend to record with.
+// BackendAuto prefers PipeWire and falls back to PulseAudio.
+func ResolveBackend(ctx context.Context, backend string) (string, error) {
+	switch backend {
+	case BackendPulse:
+		if err := CheckPulseAudioAvailable(ctx); err != nil {
+			return "", err
+		}
+		return BackendPulse, nil
+	case BackendAuto:
+		pwErr := CheckPipeWireAvailable(ctx)
+		if pwErr == nil {
+			return BackendPipeWire, nil
+		}
+		if err := CheckPulseAudioAvailable(ctx); err != nil {
+			return "", fmt.Errorf("no audio backend available: %v; %v", pwErr, err)
+		}
+		log.Printf("Recording: PipeWire unavailable (%v), using PulseAudio", pwErr)
+		return BackendPulse, nil
+	default:
+		if err := CheckPipeWireAvailable(ctx); err != nil {
+			if _, lookErr := exec.LookPath("parec"); lookErr == nil {
+				return "", fmt.Errorf("%w; to record with PulseAudio instead set recording.backend = \"pulse\"", err)
+			}
+			return "", err
+		}
+		return BackendPipeWire, nil
+	}
+}
+
+// Errors returned by CheckPipeWireAvailable
+var (
+	ErrPipeWireNotFound   = errors.New("PipeWire not found; hyprvoice requires PipeWire for audio capture")
+	ErrPipeWireNotRunning = errors.New("PipeWire is installed but not running")
+)
+
+// CheckPipeWireAvailable verifies that the PipeWire tools are installed and the PipeWire server responds
 func CheckPipeWireAvailable(ctx context.Context) error {
-	if _, err := exec.LookPath("pw-record"); err != nil {
-		return fmt.Errorf("pw-record not found: %w (install pipewire-tools)", err)
+	for _, tool := range []string{"pw-record", "pw-cli"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("%w (%s missing: install pipewire and its command-line tools, e.g. pipewire-tools or pipewire-bin)", ErrPipeWireNotFound, tool)
+		}
 	}
 	// Use a short timeout to avoid hangs on misconfigured systems.
 	checkCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 	cmd := exec.CommandContext(checkCtx, "pw-cli", "info", "all")
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("PipeWire not running or accessible: %w", err)
+		return fmt.Errorf("%w (start it with: systemctl --user start pipewire): %v", ErrPipeWireNotRunning, err)
 	}
 	return nil
 }
@@ -238,8 +326,18 @@ func (r *Recorder) validateConfig() error {
 	if r.config.Format == "" {
 		return fmt.Errorf("invalid Format: empty")
 	}
-	// For s16, sample frame size is 2 bytes per sample per channel.
-	if r.config.Format == "s16" {
+	if !IsSupportedFormat(r.config.Format) {
+		return fmt.Errorf("unsupported Format: %s (must be one of %s)", r.config.Format, strings.Join(SupportedFormats, ", "))
+	}
+	if r.config.Backend != "" && r.config.Backend != BackendPipeWire && r.config.Backend != BackendPulse && r.config.Backend != BackendAuto {
+		return fmt.Errorf("invalid Backend: %s", r.config.Backend)
+	}
+	if r.config.StartDelay < 0 {
+		return fmt.Errorf("invalid StartDelay: %v", r.config.StartDelay)
+	}
+	// For s16, sample frame size is 2 bytes per sample per channel. Other formats are
+	// converted, and the converter carries split samples over to the next read.
+	if r.config.Format == FormatS16 {
 		frameBytes := 2 * r.config.Channels
 		if r.config.BufferSize%frameBytes != 0 {
 			log.Printf("Recording: BufferSize %d not aligned to frame size %d; audio frames may split",
@@ -1,8 +1,13 @@
 package recording
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"errors"
+	"math"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -127,6 +132,18 @@ func TestRecorder_ValidateConfig(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "unsupported format",
+			config: Config{
+				SampleRate:        16000,
+				Channels:          1,
+				Format:            "u8",
+				BufferSize:        8192,
+				ChannelBufferSize: 30,
+				Timeout:           5 * time.Minute,
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid timeout",
 			config: Config{
@@ -139,6 +156,45 @@ func TestRecorder_ValidateConfig(t *testing.T) {
 			},
 			wantErr: false, // Timeout validation is not implemented in validateConfig
 		},
+		{
+			name: "valid start delay",
+			config: Config{
+				SampleRate:        16000,
+				Channels:          1,
+				Format:            "s16",
+				BufferSize:        8192,
+				ChannelBufferSize: 30,
+				Timeout:           5 * time.Minute,
+				StartDelay:        200 * time.Millisecond,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown backend",
+			config: Config{
+				Backend:           "alsa",
+				SampleRate:        16000,
+				Channels:          1,
+				Format:            "s16",
+				BufferSize:        8192,
+				ChannelBufferSize: 30,
+				Timeout:           5 * time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative start delay",
+			config: Config{
+				SampleRate:        16000,
+				Channels:          1,
+				Format:            "s16",
+				BufferSize:        8192,
+				ChannelBufferSize: 30,
+				Timeout:           5 * time.Minute,
+				StartDelay:        -time.Second,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -228,6 +284,132 @@ func TestCheckPipeWireAvailable(t *testing.T) {
 	t.Logf("CheckPipeWireAvailable() succeeded - pw-record is available")
 }
 
+func TestCheckPipeWireAvailable_MissingTools(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	err := CheckPipeWireAvailable(context.Background())
+	if !errors.Is(err, ErrPipeWireNotFound) {
+		t.Fatalf("CheckPipeWireAvailable() error = %v, want %v", err, ErrPipeWireNotFound)
+	}
+	if !strings.Contains(err.Error(), "pw-record missing") {
+		t.Errorf("error %q should name the missing tool", err)
+	}
+}
+
+func TestRecorder_BuildParecArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{
+			name:   "default config",
+			config: Config{SampleRate: 16000, Channels: 1, Format: "s16"},
+			expected: []string{
+				"--raw",
+				"--format=s16le",
+				"--rate=16000",
+				"--channels=1",
+			},
+		},
+		{
+			name:   "with device",
+			config: Config{SampleRate: 48000, Channels: 2, Format: "f32", Device: "alsa_input.usb-mic"},
+			expected: []string{
+				"--raw",
+				"--format=float32le",
+				"--rate=48000",
+				"--channels=2",
+				"--device=alsa_input.usb-mic",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := NewRecorder(tt.config).buildParecArgs()
+			if strings.Join(args, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("buildParecArgs() = %v, want %v", args, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveBackend_MissingTools(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	ctx := context.Background()
+
+	if _, err := ResolveBackend(ctx, BackendPulse); !errors.Is(err, ErrPulseAudioNotFound) {
+		t.Errorf("ResolveBackend(pulse) error = %v, want %v", err, ErrPulseAudioNotFound)
+	}
+	if _, err := ResolveBackend(ctx, BackendPipeWire); !errors.Is(err, ErrPipeWireNotFound) {
+		t.Errorf("ResolveBackend(pipewire) error = %v, want %v", err, ErrPipeWireNotFound)
+	}
+	if _, err := ResolveBackend(ctx, BackendAuto); err == nil {
+		t.Error("ResolveBackend(auto) should fail when neither backend is installed")
+	}
+}
+
+func TestParseDeviceLists(t *testing.T) {
+	pwOutput := []byte(`	id 45, type PipeWire:Interface:Node/3
+ 		object.serial = "45"
+ 		node.description = "Built-in Audio Analog Stereo"
+ 		node.name = "alsa_input.pci-0000_00_1f.3.analog-stereo"
+ 		media.class = "Audio/Source"
+	id 52, type PipeWire:Interface:Node/3
+ 		node.name = "alsa_input.usb-headset"
+`)
+	want := "alsa_input.pci-0000_00_1f.3.analog-stereo alsa_input.usb-headset"
+	if got := parsePipeWireNodes(pwOutput); strings.Join(got, " ") != want {
+		t.Errorf("parsePipeWireNodes() = %v, want %v", got, want)
+	}
+
+	pulseOutput := []byte("1\talsa_output.monitor\tmodule-alsa-card.c\ts16le 2ch 44100Hz\tSUSPENDED\n" +
+		"2\talsa_input.usb-headset\tmodule-alsa-card.c\ts16le 1ch 48000Hz\tRUNNING\n")
+	want = "alsa_output.monitor alsa_input.usb-headset"
+	if got := parsePulseSources(pulseOutput); strings.Join(got, " ") != want {
+		t.Errorf("parsePulseSources() = %v, want %v", got, want)
+	}
+}
+
+func TestPickDevice(t *testing.T) {
+	candidates := []string{"usb-headset", "builtin"}
+
+	tests := []struct {
+		name      string
+		available []string
+		want      string
+		wantOK    bool
+	}{
+		{name: "prefers first", available: []string{"builtin", "usb-headset"}, want: "usb-headset", wantOK: true},
+		{name: "falls back", available: []string{"builtin"}, want: "builtin", wantOK: true},
+		{name: "none present", available: []string{"hdmi"}, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pickDevice(candidates, tt.available)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("pickDevice() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRecorder_SelectDeviceWithoutFallback(t *testing.T) {
+	recorder := NewRecorder(Config{Device: "hw:0"})
+	if got := recorder.selectDevice(context.Background(), BackendPipeWire); got != "hw:0" {
+		t.Errorf("selectDevice() = %q, want the configured device", got)
+	}
+
+	// Listing fails without pw-cli, so the configured device is kept
+	t.Setenv("PATH", t.TempDir())
+	recorder = NewRecorder(Config{Device: "hw:0", DeviceFallback: []string{"usb-headset"}})
+	if got := recorder.selectDevice(context.Background(), BackendPipeWire); got != "hw:0" {
+		t.Errorf("selectDevice() = %q, want hw:0 when devices cannot be listed", got)
+	}
+}
+
 func TestAudioFrame(t *testing.T) {
 	data := []byte{1, 2, 3, 4}
 	timestamp := time.Now()
@@ -252,6 +434,51 @@ func TestAudioFrame(t *testing.T) {
 	}
 }
 
+func TestLevels(t *testing.T) {
+	if peak, rms := Levels(nil); peak != SilenceDBFS || rms != SilenceDBFS {
+		t.Errorf("Levels(nil) = %v, %v, want silence", peak, rms)
+	}
+
+	// Full-scale square wave: peak and RMS both at 0 dBFS
+	square := []byte{0xff, 0x7f, 0x01, 0x80, 0xff, 0x7f, 0x01, 0x80}
+	if peak, rms := Levels(square); math.Abs(peak) > 0.01 || math.Abs(rms) > 0.01 {
+		t.Errorf("Levels(full scale) = %v, %v, want 0, 0", peak, rms)
+	}
+
+	// One sample at half scale among silence
+	quiet := []byte{0, 0, 0, 0, 0, 0, 0x00, 0x40}
+	peak, rms := Levels(quiet)
+	if math.Abs(peak-(-6.02)) > 0.05 {
+		t.Errorf("peak = %v, want about -6 dBFS", peak)
+	}
+	if math.Abs(rms-(-12.04)) > 0.05 {
+		t.Errorf("rms = %v, want about -12 dBFS", rms)
+	}
+}
+
+func TestTrimSilence(t *testing.T) {
+	const sampleRate = 16000
+	silence := make([]byte, sampleRate*2) // 1s
+	tone := make([]byte, sampleRate/2*2)  // 0.5s at half scale
+	for i := 0; i < len(tone); i += 2 {
+		tone[i], tone[i+1] = 0x00, 0x40
+	}
+	pcm := append(append(append([]byte{}, silence...), tone...), silence...)
+
+	trimmed := TrimSilence(pcm, sampleRate, 1)
+	padding := sampleRate / 4 * 2 // 250ms either side
+	if want := len(tone) + 2*padding; len(trimmed) != want {
+		t.Errorf("TrimSilence() kept %d bytes, want %d", len(trimmed), want)
+	}
+
+	if got := TrimSilence(silence, sampleRate, 1); len(got) != 0 {
+		t.Errorf("TrimSilence(silence) kept %d bytes, want 0", len(got))
+	}
+	if got := TrimSilence(tone, sampleRate, 1); len(got) != len(tone) {
+		t.Errorf("TrimSilence(tone) kept %d bytes, want all %d", len(got), len(tone))
+	}
+}
+
 // TestRecorder_Start tests the Start method with mocked external dependencies
 // This is a simplified test that focuses on the logic rather than actual audio capture
 func TestRecorder_Start(t *testing.T) {
@@ -376,3 +603,50 @@ func TestRecorder_Start_InvalidConfig(t *testing.T) {
 		t.Errorf("Start() should fail with invalid config")
 	}
 }
+
+func TestSampleConverter(t *testing.T) {
+	s16 := func(values ...int16) []byte {
+		var out []byte
+		for _, v := range values {
+			out = binary.LittleEndian.AppendUint16(out, uint16(v))
+		}
+		return out
+	}
+	f32 := func(values ...float32) []byte {
+		var out []byte
+		for _, v := range values {
+			out = binary.LittleEndian.AppendUint32(out, math.Float32bits(v))
+		}
+		return out
+	}
+
+	tests := []struct {
+		format string
+		input  []byte
+		want   []byte
+	}{
+		{FormatS16, s16(1, -2, 32767), s16(1, -2, 32767)},
+		{FormatS24, []byte{0x00, 0x00, 0x40, 0x00, 0x00, 0xC0, 0xFF, 0xFF, 0xFF}, s16(0x4000, -0x4000, -1)},
+		{FormatS32, []byte{0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00, 0x80}, s16(0x4000, -0x8000)},
+		{FormatF32, f32(0, 0.5, -1, 2, float32(math.NaN())), s16(0, 16383, -32767, 32767, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			// Feed one byte at a time to check samples split across reads
+			converter := newSampleConverter(tt.format)
+			var got []byte
+			for i := range tt.input {
+				got = append(got, converter.convert(tt.input[i:i+1])...)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("convert() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, format := range []string{"s8", "u16", "f64"} {
+		if IsSupportedFormat(format) {
+			t.Errorf("IsSupportedFormat(%q) = true, want false", format)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package testutil
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// MemTransport is an in-memory bus.Transport. Each Dial hands the far end of a net.Pipe
+// to the listener, so tests exercise the daemon's real accept loop without a socket file.
+type MemTransport struct {
+	mu       sync.Mutex
+	listener *memListener
+}
+
+func NewMemTransport() *MemTransport {
+	return &MemTransport{}
+}
+
+func (t *MemTransport) Listen() (net.Listener, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.listener != nil && !t.listener.isClosed() {
+		return nil, errors.New("memory transport already listening")
+	}
+	t.listener = &memListener{conns: make(chan net.Conn), done: make(chan struct{})}
+	return t.listener, nil
+}
+
+func (t *MemTransport) Dial() (net.Conn, error) {
+	t.mu.Lock()
+	listener := t.listener
+	t.mu.Unlock()
+	if listener == nil {
+		return nil, errors.New("memory transport: connection refused")
+	}
+
+	client, server := net.Pipe()
+	select {
+	case listener.conns <- server:
+		return client, nil
+	case <-listener.done:
+		client.Close()
+		server.Close()
+		return nil, errors.New("memory transport: connection refused")
+	}
+}
+
+type memListener struct {
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *memListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *memListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *memListener) isClosed() bool {
+	select {
+	case <-l.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *memListener) Addr() net.Addr { return memAddr{} }
+
+type memAddr struct{}
+
+func (memAddr) Network() string { return "memory" }
+func (memAddr) String() string  { return "hyprvoice" }
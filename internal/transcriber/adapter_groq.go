@@ -0,0 +1,69 @@
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// GroqAdapter implements TranscriptionAdapter for the Groq Whisper API.
+// The Task field in config selects the transcription or translation endpoint;
+// translation always outputs English and the Language field hints at the source language.
+type GroqAdapter struct {
+	client *openai.Client
+	config Config
+}
+
+func NewGroqAdapter(config Config) *GroqAdapter {
+	clientConfig := openai.DefaultConfig(config.APIKey)
+	clientConfig.BaseURL = "https://api.groq.com/openai/v1"
+	clientConfig.HTTPClient = newProgressHTTPClient(config.UploadProgress)
+	client := openai.NewClientWithConfig(clientConfig)
+
+	return &GroqAdapter{
+		client: client,
+		config: config,
+	}
+}
+
+func (a *GroqAdapter) Transcribe(ctx context.Context, audioData []byte) (string, error) {
+	if len(audioData) == 0 {
+		return "", nil
+	}
+
+	// Convert raw PCM to WAV format
+	wavData, err := convertToWAV(audioData)
+	if err != nil {
+		return "", fmt.Errorf("convert to WAV: %w", err)
+	}
+
+	req := openai.AudioRequest{
+		Model:    a.config.Model,
+		Reader:   bytes.NewReader(wavData),
+		FilePath: "audio.wav",
+		Language: a.config.Language,
+		Prompt:   a.config.Prompt,
+		Format:   audioResponseFormat(a.config.ResponseFormat),
+	}
+
+	start := time.Now()
+	var resp openai.AudioResponse
+	if a.config.Task == TaskTranslate {
+		resp, err = a.client.CreateTranslation(ctx, req)
+	} else {
+		resp, err = a.client.CreateTranscription(ctx, req)
+	}
+	duration := time.Since(start)
+
+	if err != nil {
+		log.Printf("groq-adapter: %s API call failed after %v: %v", a.config.Task, duration, err)
+		return "", fmt.Errorf("groq %s: %w", a.config.Task, err)
+	}
+
+	log.Printf("groq-adapter: %s of %d bytes finished in %v: %q", a.config.Task, len(audioData), duration, resp.Text)
+	return audioResponseText(resp, a.config.ResponseFormat)
+}
@@ -3,6 +3,7 @@ package transcriber
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"time"
@@ -10,20 +11,39 @@ import (
 	"github.com/sashabaranov/go-openai"
 )
 
-// OpenAIAdapter implements TranscriptionAdapter for OpenAI Whisper API
+// OpenAIAdapter implements TranscriptionAdapter for OpenAI Whisper API.
+// The Task field in config selects the transcription or translation endpoint.
+// The GPT-4o transcribe models share the transcription endpoint but only accept text or json.
 type OpenAIAdapter struct {
 	client *openai.Client
 	config Config
 }
 
 func NewOpenAIAdapter(config Config) *OpenAIAdapter {
-	client := openai.NewClient(config.APIKey)
+	clientConfig := openai.DefaultConfig(config.APIKey)
+	clientConfig.HTTPClient = newProgressHTTPClient(config.UploadProgress)
+	client := openai.NewClientWithConfig(clientConfig)
 	return &OpenAIAdapter{
 		client: client,
 		config: config,
 	}
 }
 
+// NewAzureOpenAIAdapter talks to an Azure OpenAI resource: api-key header auth, the
+// api-version query parameter, and config.Model naming the deployment rather than the model
+func NewAzureOpenAIAdapter(config Config) *OpenAIAdapter {
+	clientConfig := openai.DefaultAzureConfig(config.APIKey, config.Endpoint)
+	if config.APIVersion != "" {
+		clientConfig.APIVersion = config.APIVersion
+	}
+	clientConfig.AzureModelMapperFunc = func(model string) string { return model }
+	clientConfig.HTTPClient = newProgressHTTPClient(config.UploadProgress)
+	return &OpenAIAdapter{
+		client: openai.NewClientWithConfig(clientConfig),
+		config: config,
+	}
+}
+
 func (a *OpenAIAdapter) Transcribe(ctx context.Context, audioData []byte) (string, error) {
 	if len(audioData) == 0 {
 		return "", nil
@@ -35,23 +55,68 @@ func (a *OpenAIAdapter) Transcribe(ctx context.Context, audioData []byte) (strin
 		return "", fmt.Errorf("convert to WAV: %w", err)
 	}
 
-	// Create transcription request
+	format := a.config.ResponseFormat
+	if IsGPT4oTranscribeModel(a.config.Model) {
+		if a.config.Task == TaskTranslate {
+			return "", fmt.Errorf("openai %s: %s cannot translate (use whisper-1)", a.config.Task, a.config.Model)
+		}
+		if format != "" && format != ResponseFormatText && format != ResponseFormatJSON {
+			log.Printf("openai-adapter: %s does not support %s output, using json", a.config.Model, format)
+			format = ResponseFormatJSON
+		}
+	}
+
 	req := openai.AudioRequest{
 		Model:    a.config.Model,
 		Reader:   bytes.NewReader(wavData),
 		FilePath: "audio.wav",
 		Language: a.config.Language,
+		Prompt:   a.config.Prompt,
+		Format:   audioResponseFormat(format),
 	}
 
 	start := time.Now()
-	resp, err := a.client.CreateTranscription(ctx, req)
+	var resp openai.AudioResponse
+	if a.config.Task == TaskTranslate {
+		resp, err = a.client.CreateTranslation(ctx, req)
+	} else {
+		resp, err = a.client.CreateTranscription(ctx, req)
+	}
 	duration := time.Since(start)
 
 	if err != nil {
-		log.Printf("openai-adapter: API call failed after %v: %v", duration, err)
-		return "", fmt.Errorf("openai transcription: %w", err)
+		log.Printf("openai-adapter: %s API call failed after %v: %v", a.config.Task, duration, err)
+		return "", fmt.Errorf("openai %s: %w", a.config.Task, err)
 	}
 
-	log.Printf("openai-adapter: transcribed %d bytes in %v: %q", len(audioData), duration, resp.Text)
-	return resp.Text, nil
+	log.Printf("openai-adapter: %s of %d bytes finished in %v: %q", a.config.Task, len(audioData), duration, resp.Text)
+	return audioResponseText(resp, format)
+}
+
+// audioResponseFormat maps a configured format to the API value. Plain text uses the API's
+// default JSON response so the text comes back without the raw body's trailing newline.
+func audioResponseFormat(format string) openai.AudioResponseFormat {
+	switch format {
+	case "", ResponseFormatText:
+		return ""
+	default:
+		return openai.AudioResponseFormat(format)
+	}
+}
+
+// audioResponseText renders a response in the configured format. Subtitle formats arrive
+// as the raw body in Text; JSON formats are re-encoded from the decoded response.
+func audioResponseText(resp openai.AudioResponse, format string) (string, error) {
+	switch format {
+	case ResponseFormatJSON:
+		data, err := json.Marshal(struct {
+			Text string `json:"text"`
+		}{resp.Text})
+		return string(data), err
+	case ResponseFormatVerboseJSON:
+		data, err := json.MarshalIndent(resp, "", "  ")
+		return string(data), err
+	default:
+		return resp.Text, nil
+	}
 }
@@ -3,14 +3,26 @@ package transcriber
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
 )
 
+// The raw PCM format recordings arrive in and convertToWAV labels them with
+const (
+	sampleRate = 16000
+	channels   = 1
+)
+
+// audioDuration returns how long size bytes of raw PCM play for
+func audioDuration(size int) time.Duration {
+	return time.Duration(size) * time.Second / (sampleRate * channels * 2)
+}
+
 // convertToWAV converts raw 16-bit PCM audio to WAV format
 func convertToWAV(rawAudio []byte) ([]byte, error) {
 	var buf bytes.Buffer
 
-	const sampleRate = 16000
-	const channels = 1
 	const bitsPerSample = 16
 	const byteRate = sampleRate * channels * bitsPerSample / 8
 	const blockAlign = channels * bitsPerSample / 8
@@ -40,3 +52,64 @@ func convertToWAV(rawAudio []byte) ([]byte, error) {
 
 	return buf.Bytes(), nil
 }
+
+// LoadWAVFile reads a WAV file and returns its raw PCM samples in the format the
+// recorder produces (16 kHz, mono, 16-bit), so recordings can be re-transcribed
+func LoadWAVFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio file: %w", err)
+	}
+	pcm, err := decodeWAV(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return pcm, nil
+}
+
+// decodeWAV walks the RIFF chunks and returns the data chunk, rejecting formats
+// other than 16 kHz mono 16-bit PCM since adapters re-wrap the samples with that header
+func decodeWAV(data []byte) ([]byte, error) {
+	const convertHint = "convert with: ffmpeg -i input -ar 16000 -ac 1 -c:a pcm_s16le output.wav"
+
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAV file (%s)", convertHint)
+	}
+
+	formatChecked := false
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := data[offset+8:]
+		if size > len(body) {
+			size = len(body) // Tolerate truncated files and streaming writers that leave the size unset
+		}
+		body = body[:size]
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("invalid WAV fmt chunk")
+			}
+			audioFormat := binary.LittleEndian.Uint16(body[0:2])
+			channels := binary.LittleEndian.Uint16(body[2:4])
+			sampleRate := binary.LittleEndian.Uint32(body[4:8])
+			bitsPerSample := binary.LittleEndian.Uint16(body[14:16])
+			if audioFormat != 1 || channels != 1 || sampleRate != 16000 || bitsPerSample != 16 {
+				return nil, fmt.Errorf("unsupported WAV format (format %d, %d channels, %d Hz, %d-bit); need 16000 Hz mono 16-bit PCM (%s)",
+					audioFormat, channels, sampleRate, bitsPerSample, convertHint)
+			}
+			formatChecked = true
+		case "data":
+			if !formatChecked {
+				return nil, fmt.Errorf("WAV data chunk before fmt chunk")
+			}
+			return body, nil
+		}
+
+		// Chunks are padded to an even size
+		offset += 8 + size + size%2
+	}
+
+	return nil, fmt.Errorf("WAV file has no data chunk")
+}
@@ -0,0 +1,109 @@
+package transcriber
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+)
+
+// DeferredTranscriber buffers frames while recording and only starts the wrapped
+// transcriber at Stop, replaying the audio into it. Nothing reaches the provider
+// until the recording is done, at the cost of streaming transcribers losing their head start.
+type DeferredTranscriber struct {
+	inner Transcriber
+
+	frames   []recording.AudioFrame
+	framesMu sync.Mutex
+
+	errCh     chan error
+	closeErrs sync.Once
+
+	running bool
+	wg      sync.WaitGroup
+}
+
+func NewDeferredTranscriber(inner Transcriber) *DeferredTranscriber {
+	return &DeferredTranscriber{inner: inner}
+}
+
+func (t *DeferredTranscriber) Start(ctx context.Context, frameCh <-chan recording.AudioFrame) (<-chan error, error) {
+	if t.running {
+		return nil, fmt.Errorf("transcriber already running")
+	}
+
+	t.running = true
+	t.errCh = make(chan error, 1)
+
+	t.wg.Add(1)
+	go t.collectAudio(ctx, frameCh)
+
+	// The channel stays open until the wrapped transcriber's errors are forwarded in Stop
+	return t.errCh, nil
+}
+
+func (t *DeferredTranscriber) Stop(ctx context.Context) error {
+	if !t.running {
+		return nil
+	}
+
+	t.wg.Wait()
+	t.running = false
+
+	t.framesMu.Lock()
+	frames := t.frames
+	t.frames = nil
+	t.framesMu.Unlock()
+
+	replay := make(chan recording.AudioFrame, len(frames))
+	for _, frame := range frames {
+		replay <- frame
+	}
+	close(replay)
+
+	log.Printf("transcriber: starting deferred transcription of %d frames", len(frames))
+	innerErrCh, err := t.inner.Start(ctx, replay)
+	if err != nil {
+		t.closeErrCh()
+		return err
+	}
+	go func() {
+		defer t.closeErrCh()
+		for err := range innerErrCh {
+			t.errCh <- err
+		}
+	}()
+
+	return t.inner.Stop(ctx)
+}
+
+func (t *DeferredTranscriber) GetFinalTranscription() (TranscriptionResult, error) {
+	return t.inner.GetFinalTranscription()
+}
+
+func (t *DeferredTranscriber) closeErrCh() {
+	t.closeErrs.Do(func() { close(t.errCh) })
+}
+
+func (t *DeferredTranscriber) collectAudio(ctx context.Context, frameCh <-chan recording.AudioFrame) {
+	defer t.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("transcriber: stopping deferred audio collection")
+			return
+
+		case frame, ok := <-frameCh:
+			if !ok {
+				return
+			}
+
+			t.framesMu.Lock()
+			t.frames = append(t.frames, frame)
+			t.framesMu.Unlock()
+		}
+	}
+}
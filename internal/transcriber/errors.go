@@ -0,0 +1,34 @@
+package transcriber
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ErrAPIKeyRequired is returned when a provider is configured without an API key
+var ErrAPIKeyRequired = errors.New("API key required")
+
+// IsAuthError reports whether err was caused by a missing or rejected API key
+func IsAuthError(err error) bool {
+	if errors.Is(err, ErrAPIKeyRequired) {
+		return true
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return isAuthStatus(apiErr.HTTPStatusCode)
+	}
+
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return isAuthStatus(reqErr.HTTPStatusCode)
+	}
+
+	return false
+}
+
+func isAuthStatus(code int) bool {
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
@@ -0,0 +1,104 @@
+package transcriber
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultMaxRepetitions is how many back-to-back copies of a phrase are kept as real speech
+const DefaultMaxRepetitions = 3
+
+// maxRepeatedPhraseWords bounds the phrase length checked for repetition loops
+const maxRepeatedPhraseWords = 8
+
+// DefaultHallucinationPhrases are phrases Whisper commonly returns for silence or noise.
+// Only phrases nobody dictates on their own are listed; "thank you" and "you" are common
+// hallucinations too, but also real dictations, so users opt into those.
+var DefaultHallucinationPhrases = []string{
+	"thanks for watching",
+	"thank you for watching",
+	"please subscribe",
+	"like and subscribe",
+}
+
+// IsEmptyTranscription reports whether the text contains nothing but whitespace
+func IsEmptyTranscription(text string) bool {
+	return strings.TrimSpace(text) == ""
+}
+
+// IsHallucination reports whether the whole transcription matches one of the known
+// hallucination phrases. Matching ignores case, surrounding whitespace and trailing punctuation.
+func IsHallucination(text string, phrases []string) bool {
+	normalized := normalizeTranscription(text)
+	if normalized == "" {
+		return false
+	}
+
+	for _, phrase := range phrases {
+		if normalized == normalizeTranscription(phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeTranscription(text string) string {
+	text = strings.ToLower(strings.TrimSpace(text))
+	return strings.TrimRight(text, ".!?,;: ")
+}
+
+// CollapseRepetitions removes Whisper's looping artifact ("thank you thank you thank you ...").
+// A phrase of up to eight words repeated back to back more than maxRepeats times is reduced to
+// a single occurrence; shorter runs such as "very very" are kept. Words are compared ignoring
+// case and punctuation. The text is returned unchanged when nothing was collapsed.
+func CollapseRepetitions(text string, maxRepeats int) string {
+	words := strings.Fields(text)
+	keys := make([]string, len(words))
+	for i, word := range words {
+		keys[i] = strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		}))
+	}
+
+	out := make([]string, 0, len(words))
+	collapsed := false
+	for i := 0; i < len(words); {
+		if size, count := loopAt(keys, i, maxRepeats); count > 0 {
+			out = append(out, words[i:i+size]...)
+			i += size * count
+			collapsed = true
+			continue
+		}
+		out = append(out, words[i])
+		i++
+	}
+
+	if !collapsed {
+		return text
+	}
+	return strings.Join(out, " ")
+}
+
+// loopAt finds the shortest phrase starting at i that is repeated back to back more than
+// maxRepeats times and returns its length in words and number of copies (0, 0 if none)
+func loopAt(keys []string, i, maxRepeats int) (size, count int) {
+	for size = 1; size <= maxRepeatedPhraseWords && i+2*size <= len(keys); size++ {
+		count = 1
+		for start := i + size; start+size <= len(keys) && equalWords(keys[i:i+size], keys[start:start+size]); start += size {
+			count++
+		}
+		if count > maxRepeats {
+			return size, count
+		}
+	}
+	return 0, 0
+}
+
+func equalWords(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,514 @@
+package transcriber
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+)
+
+// RealtimeURL is OpenAI's realtime endpoint for transcription-only sessions
+const RealtimeURL = "wss://api.openai.com/v1/realtime?intent=transcription"
+
+const (
+	realtimeSampleRate    = 24000            // The realtime API only accepts 24 kHz pcm16
+	realtimeDialTimeout   = 5 * time.Second  // Give up on the websocket and use batch after this
+	realtimeFinishTimeout = 30 * time.Second // Longest wait for the last transcripts after Stop
+	realtimePartialBuffer = 32
+)
+
+// errRealtimeClosed marks a session we closed ourselves, so the reader exits quietly
+var errRealtimeClosed = errors.New("realtime session closed")
+
+// PartialTranscriber is implemented by transcribers that produce text while audio is
+// still being recorded
+type PartialTranscriber interface {
+	Transcriber
+	Partials() <-chan string
+}
+
+// RealtimeTranscriber streams audio to OpenAI's realtime API as it is recorded, so the
+// transcript is ready moments after recording stops. It keeps a copy of all audio and
+// falls back to a batch request through adapter if the websocket fails at any point.
+type RealtimeTranscriber struct {
+	adapter TranscriptionAdapter
+	config  Config
+	url     string
+
+	// Audio collection; streamed counts the 24 kHz samples already sent
+	audioBuffer []byte
+	bufferMu    sync.Mutex
+	streamMu    sync.Mutex
+	streamed    int
+	audioReady  chan struct{} // Signals new audio to streamLoop, closed when collection ends
+
+	// Websocket session
+	connReady chan struct{} // Closed once the dial succeeded or failed
+	conn      *wsConn
+	failMu    sync.Mutex
+	failErr   error
+
+	// Items committed by the server, in order, and their transcripts
+	itemsMu     sync.Mutex
+	itemsCond   *sync.Cond
+	items       []string
+	transcripts map[string]string
+	deltas      map[string]string
+	pending     int
+	commitSent  bool
+	commitAcked bool
+
+	partials chan string
+
+	// Control
+	running   bool
+	wg        sync.WaitGroup
+	sessionWg sync.WaitGroup // The session's reader and streamer
+
+	// Transcription result
+	resultMu sync.RWMutex
+	result   TranscriptionResult
+}
+
+func NewRealtimeTranscriber(config Config, adapter TranscriptionAdapter) *RealtimeTranscriber {
+	t := &RealtimeTranscriber{
+		adapter:     adapter,
+		config:      config,
+		url:         RealtimeURL,
+		transcripts: make(map[string]string),
+		deltas:      make(map[string]string),
+		partials:    make(chan string, realtimePartialBuffer),
+	}
+	t.itemsCond = sync.NewCond(&t.itemsMu)
+	return t
+}
+
+// Partials delivers the transcript so far each time the server sends more of it.
+// Updates are dropped rather than blocking when nobody keeps up.
+func (t *RealtimeTranscriber) Partials() <-chan string {
+	return t.partials
+}
+
+func (t *RealtimeTranscriber) Start(ctx context.Context, frameCh <-chan recording.AudioFrame) (<-chan error, error) {
+	if t.running {
+		return nil, fmt.Errorf("transcriber already running")
+	}
+
+	t.running = true
+	t.connReady = make(chan struct{})
+	t.audioReady = make(chan struct{}, 1)
+
+	errCh := make(chan error, 1)
+
+	go t.connect(ctx)
+
+	t.wg.Add(1)
+	go t.collectAudio(ctx, frameCh, errCh)
+
+	return errCh, nil
+}
+
+func (t *RealtimeTranscriber) Stop(ctx context.Context) error {
+	if !t.running {
+		return nil
+	}
+
+	t.wg.Wait()
+	t.running = false
+	defer close(t.partials)
+
+	segments, err := t.finishRealtime(ctx)
+	<-t.connReady // The dial gives up within realtimeDialTimeout
+	if t.conn != nil {
+		t.fail(errRealtimeClosed)
+		t.conn.Close()
+		t.sessionWg.Wait()
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("realtime-transcriber: %v, falling back to batch transcription", err)
+		return t.transcribeBatch(ctx)
+	}
+
+	text := strings.Join(segments, " ")
+	log.Printf("realtime-transcriber: transcription completed: %q", text)
+
+	t.bufferMu.Lock()
+	duration := audioDuration(len(t.audioBuffer))
+	t.bufferMu.Unlock()
+
+	t.resultMu.Lock()
+	t.result = TranscriptionResult{
+		Text:     text,
+		Language: t.config.Language,
+		Duration: duration,
+		Segments: segments,
+		Provider: t.config.Provider,
+		Model:    t.config.Model,
+	}
+	t.resultMu.Unlock()
+
+	return nil
+}
+
+func (t *RealtimeTranscriber) GetFinalTranscription() (TranscriptionResult, error) {
+	t.resultMu.RLock()
+	defer t.resultMu.RUnlock()
+	return t.result, nil
+}
+
+// connect opens the session in the background so recording never waits on the network.
+// Audio recorded while it connects is streamed as soon as the session is up.
+func (t *RealtimeTranscriber) connect(ctx context.Context) {
+	conn, err := t.openSession(ctx)
+	if err != nil {
+		t.fail(err)
+		close(t.connReady)
+		return
+	}
+
+	t.conn = conn
+	t.sessionWg.Add(2)
+	go t.readEvents(conn)
+	close(t.connReady)
+	go t.streamLoop()
+}
+
+// openSession dials the websocket and configures a transcription session
+func (t *RealtimeTranscriber) openSession(ctx context.Context) (*wsConn, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, realtimeDialTimeout)
+	defer cancel()
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+t.config.APIKey)
+	header.Set("OpenAI-Beta", "realtime=v1")
+
+	start := time.Now()
+	conn, err := dialWebSocket(dialCtx, t.url, header)
+	if err != nil {
+		return nil, fmt.Errorf("connect to realtime API: %w", err)
+	}
+	log.Printf("realtime-transcriber: connected in %v", time.Since(start))
+
+	if err := t.send(conn, map[string]any{
+		"type": "transcription_session.update",
+		"session": map[string]any{
+			"input_audio_format": "pcm16",
+			"input_audio_transcription": map[string]any{
+				"model":    t.config.Model,
+				"language": t.config.Language,
+				"prompt":   t.config.Prompt,
+			},
+			"turn_detection": map[string]any{"type": "server_vad"},
+		},
+	}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("configure realtime session: %w", err)
+	}
+	return conn, nil
+}
+
+func (t *RealtimeTranscriber) collectAudio(ctx context.Context, frameCh <-chan recording.AudioFrame, errCh chan<- error) {
+	defer func() {
+		close(t.audioReady)
+		close(errCh)
+		t.wg.Done()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("realtime-transcriber: stopping audio collection")
+			return
+
+		case frame, ok := <-frameCh:
+			if !ok {
+				log.Printf("realtime-transcriber: audio channel closed")
+				return
+			}
+
+			t.bufferMu.Lock()
+			t.audioBuffer = append(t.audioBuffer, frame.Data...)
+			t.bufferMu.Unlock()
+
+			// Recording never waits on the network; streamLoop picks the audio up
+			select {
+			case t.audioReady <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// streamLoop sends audio whenever collectAudio signals more of it, starting with what
+// was recorded while connecting
+func (t *RealtimeTranscriber) streamLoop() {
+	defer t.sessionWg.Done()
+
+	t.streamAudio()
+	for range t.audioReady {
+		t.streamAudio()
+	}
+}
+
+// streamAudio sends the audio recorded since the last call, once the session is up
+func (t *RealtimeTranscriber) streamAudio() {
+	select {
+	case <-t.connReady:
+	default:
+		return // Still connecting: streamLoop sends the backlog once it is done
+	}
+	if t.conn == nil || t.failed() != nil {
+		return
+	}
+
+	t.streamMu.Lock()
+	defer t.streamMu.Unlock()
+
+	t.bufferMu.Lock()
+	chunk, next := upsamplePCM(t.audioBuffer, t.streamed)
+	t.bufferMu.Unlock()
+	if len(chunk) == 0 {
+		return
+	}
+
+	if err := t.send(t.conn, map[string]any{
+		"type":  "input_audio_buffer.append",
+		"audio": base64.StdEncoding.EncodeToString(chunk),
+	}); err != nil {
+		t.fail(fmt.Errorf("stream audio: %w", err))
+		return
+	}
+	t.streamed = next
+}
+
+// finishRealtime commits the remaining audio and waits for every committed item's
+// transcript, returning them in order. Any error means the realtime result can't be trusted.
+func (t *RealtimeTranscriber) finishRealtime(ctx context.Context) ([]string, error) {
+	select {
+	case <-t.connReady:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if err := t.failed(); err != nil {
+		return nil, err
+	}
+
+	// Closing the connection when ctx ends unblocks a write to a stalled server
+	stopClose := context.AfterFunc(ctx, func() { t.conn.conn.Close() })
+	defer stopClose()
+
+	t.streamAudio()
+
+	t.itemsMu.Lock()
+	t.commitSent = true
+	t.itemsMu.Unlock()
+	if err := t.send(t.conn, map[string]any{"type": "input_audio_buffer.commit"}); err != nil {
+		t.fail(fmt.Errorf("commit audio: %w", err))
+	}
+
+	// Wake the wait below when ctx ends or the server takes too long
+	waitCtx, cancel := context.WithTimeout(ctx, realtimeFinishTimeout)
+	defer cancel()
+	stop := context.AfterFunc(waitCtx, func() {
+		t.itemsMu.Lock()
+		t.itemsCond.Broadcast()
+		t.itemsMu.Unlock()
+	})
+	defer stop()
+
+	t.itemsMu.Lock()
+	defer t.itemsMu.Unlock()
+	for !(t.commitAcked && t.pending == 0) {
+		if err := t.failed(); err != nil {
+			return nil, err
+		}
+		if waitCtx.Err() != nil {
+			return nil, fmt.Errorf("waiting for realtime transcript: %w", waitCtx.Err())
+		}
+		t.itemsCond.Wait()
+	}
+	return t.segments(), nil
+}
+
+// realtimeEvent holds the fields of the server events the transcriber uses
+type realtimeEvent struct {
+	Type       string `json:"type"`
+	ItemID     string `json:"item_id"`
+	Delta      string `json:"delta"`
+	Transcript string `json:"transcript"`
+	Error      *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (t *RealtimeTranscriber) readEvents(conn *wsConn) {
+	defer t.sessionWg.Done()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			t.fail(fmt.Errorf("realtime connection lost: %w", err))
+			return
+		}
+
+		var event realtimeEvent
+		if err := json.Unmarshal(message, &event); err != nil {
+			log.Printf("realtime-transcriber: ignoring malformed event: %v", err)
+			continue
+		}
+		if err := t.handleEvent(event); err != nil {
+			t.fail(err)
+			return
+		}
+	}
+}
+
+func (t *RealtimeTranscriber) handleEvent(event realtimeEvent) error {
+	t.itemsMu.Lock()
+	defer t.itemsMu.Unlock()
+	defer t.itemsCond.Broadcast()
+
+	switch event.Type {
+	case "input_audio_buffer.committed":
+		t.items = append(t.items, event.ItemID)
+		t.pending++
+		if t.commitSent {
+			t.commitAcked = true
+		}
+
+	case "conversation.item.input_audio_transcription.delta":
+		t.deltas[event.ItemID] += event.Delta
+		t.sendPartial()
+
+	case "conversation.item.input_audio_transcription.completed":
+		if _, done := t.transcripts[event.ItemID]; !done {
+			t.pending--
+		}
+		t.transcripts[event.ItemID] = event.Transcript
+		delete(t.deltas, event.ItemID)
+		t.sendPartial()
+
+	case "conversation.item.input_audio_transcription.failed":
+		return fmt.Errorf("realtime transcription failed for %s", event.ItemID)
+
+	case "error":
+		if event.Error == nil {
+			return errors.New("realtime API error")
+		}
+		// Server VAD already committed everything: the final commit has nothing to add
+		if event.Error.Code == "input_audio_buffer_commit_empty" && t.commitSent {
+			t.commitAcked = true
+			return nil
+		}
+		return fmt.Errorf("realtime API error: %s", event.Error.Message)
+	}
+	return nil
+}
+
+// segments returns the text of every item in commit order, using the partial text
+// for items still in progress. Callers hold itemsMu.
+func (t *RealtimeTranscriber) segments() []string {
+	var parts []string
+	for _, id := range t.items {
+		text, done := t.transcripts[id]
+		if !done {
+			text = t.deltas[id]
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return parts
+}
+
+// sendPartial publishes the transcript so far without blocking. Callers hold itemsMu.
+func (t *RealtimeTranscriber) sendPartial() {
+	select {
+	case t.partials <- strings.Join(t.segments(), " "):
+	default:
+	}
+}
+
+func (t *RealtimeTranscriber) send(conn *wsConn, event map[string]any) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return conn.WriteText(data)
+}
+
+// fail records the first websocket error and wakes anyone waiting on the session
+func (t *RealtimeTranscriber) fail(err error) {
+	t.failMu.Lock()
+	if t.failErr == nil {
+		t.failErr = err
+		if err != errRealtimeClosed {
+			log.Printf("realtime-transcriber: %v", err)
+		}
+	}
+	t.failMu.Unlock()
+
+	t.itemsMu.Lock()
+	t.itemsCond.Broadcast()
+	t.itemsMu.Unlock()
+}
+
+func (t *RealtimeTranscriber) failed() error {
+	t.failMu.Lock()
+	defer t.failMu.Unlock()
+	return t.failErr
+}
+
+// transcribeBatch sends all recorded audio through the adapter, as SimpleTranscriber would
+func (t *RealtimeTranscriber) transcribeBatch(ctx context.Context) error {
+	t.bufferMu.Lock()
+	batch := NewSimpleTranscriber(t.config, t.adapter)
+	batch.audioBuffer = append([]byte(nil), t.audioBuffer...)
+	t.bufferMu.Unlock()
+
+	if err := batch.transcribeAll(ctx); err != nil {
+		return err
+	}
+	result, _ := batch.GetFinalTranscription()
+
+	t.resultMu.Lock()
+	t.result = result
+	t.resultMu.Unlock()
+	return nil
+}
+
+// upsamplePCM linearly resamples 16 kHz s16 audio to the 24 kHz the realtime API wants.
+// It returns the output samples from index next up to the end of pcm and the index to
+// continue from, so audio can be streamed as it grows without seams between chunks.
+func upsamplePCM(pcm []byte, next int) ([]byte, int) {
+	samples := len(pcm) / 2
+	var out []byte
+	for ; ; next++ {
+		pos := next * sampleRate // Position in the input, in 1/realtimeSampleRate sample units
+		i, frac := pos/realtimeSampleRate, pos%realtimeSampleRate
+		if i+1 >= samples && !(frac == 0 && i < samples) {
+			break
+		}
+		a := int(int16(binary.LittleEndian.Uint16(pcm[2*i:])))
+		sample := a
+		if frac != 0 {
+			b := int(int16(binary.LittleEndian.Uint16(pcm[2*i+2:])))
+			sample = a + (b-a)*frac/realtimeSampleRate
+		}
+		out = binary.LittleEndian.AppendUint16(out, uint16(int16(sample)))
+	}
+	return out, next
+}
@@ -23,8 +23,8 @@ type SimpleTranscriber struct {
 	wg      sync.WaitGroup
 
 	// Transcription result
-	transcriptionMu   sync.RWMutex
-	transcriptionText string
+	resultMu sync.RWMutex
+	result   TranscriptionResult
 }
 
 func NewSimpleTranscriber(config Config, adapter TranscriptionAdapter) *SimpleTranscriber {
@@ -61,10 +61,10 @@ func (t *SimpleTranscriber) Stop(ctx context.Context) error {
 	return t.transcribeAll(ctx)
 }
 
-func (t *SimpleTranscriber) GetFinalTranscription() (string, error) {
-	t.transcriptionMu.RLock()
-	defer t.transcriptionMu.RUnlock()
-	return t.transcriptionText, nil
+func (t *SimpleTranscriber) GetFinalTranscription() (TranscriptionResult, error) {
+	t.resultMu.RLock()
+	defer t.resultMu.RUnlock()
+	return t.result, nil
 }
 
 func (t *SimpleTranscriber) collectAudio(ctx context.Context, frameCh <-chan recording.AudioFrame, errCh chan<- error) {
@@ -102,6 +102,16 @@ func (t *SimpleTranscriber) transcribeAll(ctx context.Context) error {
 		log.Printf("transcriber: no audio data to transcribe")
 		return nil
 	}
+	duration := audioDuration(len(audioData))
+
+	if t.config.TrimSilence {
+		trimmed := recording.TrimSilence(audioData, sampleRate, channels)
+		log.Printf("transcriber: trimmed silence, %d of %d bytes left", len(trimmed), len(audioData))
+		if len(trimmed) == 0 {
+			return nil
+		}
+		audioData = trimmed
+	}
 
 	log.Printf("transcriber: transcribing %d bytes of audio", len(audioData))
 
@@ -114,9 +124,15 @@ func (t *SimpleTranscriber) transcribeAll(ctx context.Context) error {
 
 	log.Printf("transcriber: transcription completed: %q", text)
 
-	t.transcriptionMu.Lock()
-	t.transcriptionText = text
-	t.transcriptionMu.Unlock()
+	t.resultMu.Lock()
+	t.result = TranscriptionResult{
+		Text:     text,
+		Language: t.config.Language,
+		Duration: duration,
+		Provider: t.config.Provider,
+		Model:    t.config.Model,
+	}
+	t.resultMu.Unlock()
 
 	return nil
 }
@@ -3,6 +3,7 @@ package transcriber
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/leonardotrapani/hyprvoice/internal/recording"
 )
@@ -11,7 +12,18 @@ import (
 type Transcriber interface {
 	Start(ctx context.Context, frameCh <-chan recording.AudioFrame) (<-chan error, error)
 	Stop(ctx context.Context) error
-	GetFinalTranscription() (string, error)
+	GetFinalTranscription() (TranscriptionResult, error)
+}
+
+// TranscriptionResult is a finished transcription and what is known about it, so
+// notifications and logs describe the same result the injector receives
+type TranscriptionResult struct {
+	Text     string
+	Language string        // Language requested from the provider; empty when it auto-detected
+	Duration time.Duration // Length of the recorded audio
+	Segments []string      // Utterances in order when the transcriber splits them (realtime), else nil
+	Provider string
+	Model    string
 }
 
 // Adapter interface for different transcription backends
@@ -19,44 +31,175 @@ type TranscriptionAdapter interface {
 	Transcribe(ctx context.Context, audioData []byte) (string, error)
 }
 
+// Transcription tasks
+const (
+	TaskTranscribe = "transcribe" // Speech to text in the spoken language
+	TaskTranslate  = "translate"  // Speech to English text
+)
+
+// SupportedModels lists the models each provider accepts for each task
+var SupportedModels = map[string]map[string][]string{
+	"openai": {
+		TaskTranscribe: {"whisper-1", "gpt-4o-transcribe", "gpt-4o-mini-transcribe"},
+		TaskTranslate:  {"whisper-1"},
+	},
+	"groq": {
+		TaskTranscribe: {"whisper-large-v3", "whisper-large-v3-turbo"},
+		TaskTranslate:  {"whisper-large-v3"},
+	},
+}
+
+// DefaultModel returns the model used when transcription.model is left empty, matching
+// the configure wizard: whisper-1 for OpenAI, whisper-large-v3-turbo for Groq transcription,
+// and whisper-large-v3 for Groq translation (turbo cannot translate)
+func DefaultModel(provider, task string) string {
+	provider, task = NormalizeProvider(provider, task)
+	switch {
+	case provider == "openai":
+		return "whisper-1"
+	case provider == "groq" && task == TaskTranslate:
+		return "whisper-large-v3"
+	case provider == "groq":
+		return "whisper-large-v3-turbo"
+	}
+	return ""
+}
+
+// Response formats for file transcription; live dictation always uses ResponseFormatText
+const (
+	ResponseFormatText        = "text"
+	ResponseFormatJSON        = "json"
+	ResponseFormatVerboseJSON = "verbose_json"
+	ResponseFormatSRT         = "srt"
+	ResponseFormatVTT         = "vtt"
+)
+
+// SupportedResponseFormats lists the response formats each provider can return
+var SupportedResponseFormats = map[string][]string{
+	"openai":       {ResponseFormatText, ResponseFormatJSON, ResponseFormatVerboseJSON, ResponseFormatSRT, ResponseFormatVTT},
+	"azure-openai": {ResponseFormatText, ResponseFormatJSON, ResponseFormatVerboseJSON, ResponseFormatSRT, ResponseFormatVTT},
+	"groq":         {ResponseFormatText, ResponseFormatJSON, ResponseFormatVerboseJSON},
+}
+
+// IsGPT4oTranscribeModel reports whether model is one of OpenAI's GPT-4o speech models.
+// Unlike whisper-1 they cannot translate and only return text or json.
+func IsGPT4oTranscribeModel(model string) bool {
+	return model == "gpt-4o-transcribe" || model == "gpt-4o-mini-transcribe"
+}
+
+// ResponseFormats returns the response formats the provider can return with the model
+func ResponseFormats(provider, model string) []string {
+	if (provider == "openai" || provider == "azure-openai") && IsGPT4oTranscribeModel(model) {
+		return []string{ResponseFormatText, ResponseFormatJSON}
+	}
+	return SupportedResponseFormats[provider]
+}
+
+// IsSupportedResponseFormat reports whether the provider can return the format with the model
+func IsSupportedResponseFormat(provider, model, format string) bool {
+	for _, supported := range ResponseFormats(provider, model) {
+		if format == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSupportedModel reports whether the provider is known to accept the model for the task
+func IsSupportedModel(provider, task, model string) bool {
+	for _, supported := range SupportedModels[provider][task] {
+		if model == supported {
+			return true
+		}
+	}
+	return false
+}
+
 // Configuration for the transcriber
 type Config struct {
 	Provider string
+	Task     string // TaskTranscribe (default) or TaskTranslate
 	APIKey   string
 	Language string
 	Model    string
+	Prompt   string // Whisper prompt biasing vocabulary and style; empty sends none
+
+	Endpoint   string // Azure OpenAI only: resource URL, e.g. https://myresource.openai.azure.com
+	APIVersion string // Azure OpenAI only: api-version query parameter
+
+	TrimSilence bool // Cut leading and trailing silence before upload
+
+	ResponseFormat string // ResponseFormat* constant; empty means plain text
+
+	Realtime bool // OpenAI only: stream audio over the realtime websocket while recording
+	Deferred bool // Hold the audio and only start the provider transcriber once recording stops
+
+	UploadProgress UploadProgressFunc // Optional: called as the audio upload progresses
 }
 
-// NewTranscriber creates a new simple transcriber
+// NormalizeProvider maps legacy provider names ("groq-transcription", "groq-translation")
+// to the provider/task pair that replaced them. An empty task defaults to TaskTranscribe.
+func NormalizeProvider(provider, task string) (string, string) {
+	switch provider {
+	case "groq-transcription":
+		provider, task = "groq", TaskTranscribe
+	case "groq-translation":
+		provider, task = "groq", TaskTranslate
+	}
+	if task == "" {
+		task = TaskTranscribe
+	}
+	return provider, task
+}
+
+// NewTranscriber creates a simple transcriber, or a realtime one when config.Realtime is set
 func NewTranscriber(config Config) (Transcriber, error) {
+	config.Provider, config.Task = NormalizeProvider(config.Provider, config.Task)
+	if config.Task != TaskTranscribe && config.Task != TaskTranslate {
+		return nil, fmt.Errorf("unsupported task: %s", config.Task)
+	}
+
 	// Create the appropriate adapter
 	var adapter TranscriptionAdapter
 
 	switch config.Provider {
 	case "openai":
 		if config.APIKey == "" {
-			return nil, fmt.Errorf("OpenAI API key required")
+			return nil, fmt.Errorf("OpenAI %w", ErrAPIKeyRequired)
 		}
 		adapter = NewOpenAIAdapter(config)
 
-	case "groq-transcription":
+	case "groq":
 		if config.APIKey == "" {
-			return nil, fmt.Errorf("Groq API key required")
+			return nil, fmt.Errorf("Groq %w", ErrAPIKeyRequired)
 		}
-		adapter = NewGroqTranscriptionAdapter(config)
+		adapter = NewGroqAdapter(config)
 
-	case "groq-translation":
+	case "azure-openai":
 		if config.APIKey == "" {
-			return nil, fmt.Errorf("Groq API key required")
+			return nil, fmt.Errorf("Azure OpenAI %w", ErrAPIKeyRequired)
+		}
+		if config.Endpoint == "" {
+			return nil, fmt.Errorf("Azure OpenAI endpoint required")
 		}
-		adapter = NewGroqTranslationAdapter(config)
+		adapter = NewAzureOpenAIAdapter(config)
 
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", config.Provider)
 	}
 
-	// Create simple transcriber that collects all audio
-	transcriber := NewSimpleTranscriber(config, adapter)
+	var transcriber Transcriber
+	// Realtime streaming only yields plain text; the batch adapter stays as its fallback
+	if config.Realtime && config.Provider == "openai" && config.Task == TaskTranscribe &&
+		(config.ResponseFormat == "" || config.ResponseFormat == ResponseFormatText) {
+		transcriber = NewRealtimeTranscriber(config, adapter)
+	} else {
+		// Create simple transcriber that collects all audio
+		transcriber = NewSimpleTranscriber(config, adapter)
+	}
 
+	if config.Deferred {
+		return NewDeferredTranscriber(transcriber), nil
+	}
 	return transcriber, nil
 }
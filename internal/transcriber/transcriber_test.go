@@ -1,12 +1,23 @@
 package transcriber
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/leonardotrapani/hyprvoice/internal/recording"
+	"github.com/sashabaranov/go-openai"
 )
 
 func TestNewTranscriber(t *testing.T) {
@@ -75,6 +86,36 @@ func TestNewTranscriber(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid groq translate config",
+			config: Config{
+				Provider: "groq",
+				Task:     TaskTranslate,
+				APIKey:   "gsk-test-key",
+				Model:    "whisper-large-v3",
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid openai translate config",
+			config: Config{
+				Provider: "openai",
+				Task:     TaskTranslate,
+				APIKey:   "test-key",
+				Model:    "whisper-1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "unsupported task",
+			config: Config{
+				Provider: "groq",
+				Task:     "summarize",
+				APIKey:   "gsk-test-key",
+				Model:    "whisper-large-v3",
+			},
+			wantErr: true,
+		},
 		{
 			name: "unsupported provider",
 			config: Config{
@@ -263,9 +304,9 @@ func TestSimpleTranscriber_GetFinalTranscription(t *testing.T) {
 		return
 	}
 
-	// Should return empty string initially
-	if transcription != "" {
-		t.Errorf("GetFinalTranscription() = %q, want empty string", transcription)
+	// Should return empty text initially
+	if transcription.Text != "" {
+		t.Errorf("GetFinalTranscription() = %q, want empty string", transcription.Text)
 	}
 }
 
@@ -386,14 +427,64 @@ func TestSimpleTranscriber_TranscribeAll(t *testing.T) {
 					return
 				}
 
-				if result != tt.expectedResult {
-					t.Errorf("GetFinalTranscription() = %q, want %q", result, tt.expectedResult)
+				if result.Text != tt.expectedResult {
+					t.Errorf("GetFinalTranscription() = %q, want %q", result.Text, tt.expectedResult)
 				}
 			}
 		})
 	}
 }
 
+func TestSimpleTranscriber_Result(t *testing.T) {
+	config := Config{Provider: "groq", Task: TaskTranscribe, Language: "it", Model: "whisper-large-v3"}
+	transcriber := NewSimpleTranscriber(config, &MockTranscriptionAdapter{})
+	transcriber.audioBuffer = make([]byte, 3*sampleRate*2) // 3s
+
+	if err := transcriber.transcribeAll(context.Background()); err != nil {
+		t.Fatalf("transcribeAll() error = %v", err)
+	}
+	result, _ := transcriber.GetFinalTranscription()
+	want := TranscriptionResult{Text: "mock transcription", Language: "it", Duration: 3 * time.Second, Provider: "groq", Model: "whisper-large-v3"}
+	if fmt.Sprint(result) != fmt.Sprint(want) {
+		t.Errorf("GetFinalTranscription() = %+v, want %+v", result, want)
+	}
+}
+
+func TestSimpleTranscriber_TrimSilence(t *testing.T) {
+	speech := make([]byte, sampleRate) // 0.5s at half scale
+	for i := 0; i < len(speech); i += 2 {
+		speech[i], speech[i+1] = 0x00, 0x40
+	}
+
+	var uploaded []byte
+	adapter := &MockTranscriptionAdapter{
+		TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+			uploaded = audioData
+			return "hello", nil
+		},
+	}
+	transcriber := NewSimpleTranscriber(Config{TrimSilence: true}, adapter)
+	transcriber.audioBuffer = append(make([]byte, 2*sampleRate*2), speech...)
+
+	if err := transcriber.transcribeAll(context.Background()); err != nil {
+		t.Fatalf("transcribeAll() error = %v", err)
+	}
+	if len(uploaded) == 0 || len(uploaded) >= len(transcriber.audioBuffer) {
+		t.Errorf("uploaded %d of %d bytes, want leading silence trimmed", len(uploaded), len(transcriber.audioBuffer))
+	}
+
+	// All silence never reaches the provider
+	uploaded = nil
+	silent := NewSimpleTranscriber(Config{TrimSilence: true}, adapter)
+	silent.audioBuffer = make([]byte, sampleRate*2)
+	if err := silent.transcribeAll(context.Background()); err != nil {
+		t.Fatalf("transcribeAll() error = %v", err)
+	}
+	if uploaded != nil {
+		t.Errorf("uploaded %d bytes of silence, want no request", len(uploaded))
+	}
+}
+
 func TestNewSimpleTranscriber(t *testing.T) {
 	config := Config{
 		Provider: "openai",
@@ -447,3 +538,590 @@ func TestTranscriptionAdapter(t *testing.T) {
 		t.Errorf("Transcribe() = %q, want %q", result, "test result")
 	}
 }
+
+func TestIsEmptyTranscription(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"", true},
+		{"   ", true},
+		{"\n\t ", true},
+		{"hello", false},
+		{"  hello  ", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsEmptyTranscription(tt.text); got != tt.want {
+			t.Errorf("IsEmptyTranscription(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestIsHallucination(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		phrases []string
+		want    bool
+	}{
+		{"exact match", "thanks for watching", DefaultHallucinationPhrases, true},
+		{"case and punctuation", " Please subscribe. ", DefaultHallucinationPhrases, true},
+		{"exclamation", "Thanks for watching!", DefaultHallucinationPhrases, true},
+		{"real thanks kept by default", "Thank you.", DefaultHallucinationPhrases, false},
+		{"opted-in phrase", "Thank you.", []string{"thank you"}, true},
+		{"phrase inside sentence", "thank you for watching the kids", DefaultHallucinationPhrases, false},
+		{"normal speech", "hello world", DefaultHallucinationPhrases, false},
+		{"empty text", "", DefaultHallucinationPhrases, false},
+		{"custom phrase", "Subtitles by the community.", []string{"subtitles by the community"}, true},
+		{"filter disabled", "thank you", []string{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsHallucination(tt.text, tt.phrases); got != tt.want {
+				t.Errorf("IsHallucination(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeProvider(t *testing.T) {
+	tests := []struct {
+		provider     string
+		task         string
+		wantProvider string
+		wantTask     string
+	}{
+		{"groq-transcription", "", "groq", TaskTranscribe},
+		{"groq-translation", "", "groq", TaskTranslate},
+		{"groq", "", "groq", TaskTranscribe},
+		{"groq", TaskTranslate, "groq", TaskTranslate},
+		{"openai", "", "openai", TaskTranscribe},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.task, func(t *testing.T) {
+			provider, task := NormalizeProvider(tt.provider, tt.task)
+			if provider != tt.wantProvider || task != tt.wantTask {
+				t.Errorf("NormalizeProvider(%q, %q) = (%q, %q), want (%q, %q)", tt.provider, tt.task, provider, task, tt.wantProvider, tt.wantTask)
+			}
+		})
+	}
+}
+
+func TestProgressReader_ReportsSteps(t *testing.T) {
+	data := make([]byte, 1000)
+	var reports []int64
+
+	reader := &progressReader{
+		ctx:   context.Background(),
+		body:  io.NopCloser(bytes.NewReader(data)),
+		total: int64(len(data)),
+		onProgress: func(sent, total int64) {
+			reports = append(reports, sent)
+		},
+	}
+
+	buf := make([]byte, 100)
+	for {
+		_, err := reader.Read(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+	}
+
+	want := []int64{300, 500, 800, 1000}
+	if fmt.Sprint(reports) != fmt.Sprint(want) {
+		t.Errorf("progress reports = %v, want %v", reports, want)
+	}
+}
+
+func TestProgressReader_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := &progressReader{
+		ctx:        ctx,
+		body:       io.NopCloser(bytes.NewReader(make([]byte, 100))),
+		total:      100,
+		onProgress: func(sent, total int64) {},
+	}
+
+	cancel()
+
+	n, err := reader.Read(make([]byte, 10))
+	if n != 0 || err != context.Canceled {
+		t.Errorf("Read() after cancel = (%d, %v), want (0, %v)", n, err, context.Canceled)
+	}
+}
+
+func TestIsAuthError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"missing api key", mustNewTranscriberErr(t, Config{Provider: "groq", Model: "whisper-large-v3"}), true},
+		{"unauthorized", fmt.Errorf("groq transcribe: %w", &openai.APIError{HTTPStatusCode: 401}), true},
+		{"forbidden request", fmt.Errorf("openai transcribe: %w", &openai.RequestError{HTTPStatusCode: 403}), true},
+		{"rate limited", fmt.Errorf("openai transcribe: %w", &openai.APIError{HTTPStatusCode: 429}), false},
+		{"network", errors.New("dial tcp: i/o timeout"), false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsAuthError(tt.err); got != tt.want {
+				t.Errorf("IsAuthError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func mustNewTranscriberErr(t *testing.T, config Config) error {
+	t.Helper()
+	_, err := NewTranscriber(config)
+	if err == nil {
+		t.Fatalf("NewTranscriber(%+v) should fail", config)
+	}
+	return err
+}
+
+func TestCollapseRepetitions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want string
+	}{
+		{"no repetition", "hello world", 3, "hello world"},
+		{"within threshold", "very very very good", 3, "very very very good"},
+		{"single word loop", "no no no no no", 3, "no"},
+		{"phrase loop", "Thank you. Thank you. Thank you. Thank you.", 3, "Thank you."},
+		{"loop after speech", "see you tomorrow thank you thank you thank you thank you", 3, "see you tomorrow thank you"},
+		{"loop before speech", "so so so so let's start", 3, "so let's start"},
+		{"lower threshold", "okay okay", 1, "okay"},
+		{"preserves spacing when unchanged", "  spaced   text ", 3, "  spaced   text "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CollapseRepetitions(tt.text, tt.max); got != tt.want {
+				t.Errorf("CollapseRepetitions(%q, %d) = %q, want %q", tt.text, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeWAV(t *testing.T) {
+	pcm := []byte{1, 2, 3, 4, 5, 6}
+	wav, err := convertToWAV(pcm)
+	if err != nil {
+		t.Fatalf("convertToWAV() error = %v", err)
+	}
+
+	got, err := decodeWAV(wav)
+	if err != nil {
+		t.Fatalf("decodeWAV() error = %v", err)
+	}
+	if string(got) != string(pcm) {
+		t.Errorf("decodeWAV() = %v, want %v", got, pcm)
+	}
+
+	// 44.1 kHz audio must be resampled first
+	resampled := append([]byte{}, wav...)
+	binary.LittleEndian.PutUint32(resampled[24:28], 44100)
+	if _, err := decodeWAV(resampled); err == nil {
+		t.Error("decodeWAV() should reject non-16 kHz audio")
+	}
+
+	if _, err := decodeWAV([]byte("not a wav file")); err == nil {
+		t.Error("decodeWAV() should reject non-WAV data")
+	}
+}
+
+func TestAudioResponseText(t *testing.T) {
+	resp := openai.AudioResponse{Text: "hello world", Language: "english"}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "hello world"},
+		{ResponseFormatText, "hello world"},
+		{ResponseFormatJSON, `{"text":"hello world"}`},
+		{ResponseFormatSRT, "hello world"}, // Subtitle bodies are passed through as-is
+	}
+	for _, tt := range tests {
+		got, err := audioResponseText(resp, tt.format)
+		if err != nil || got != tt.want {
+			t.Errorf("audioResponseText(%q) = %q, %v; want %q", tt.format, got, err, tt.want)
+		}
+	}
+
+	verbose, err := audioResponseText(resp, ResponseFormatVerboseJSON)
+	if err != nil || !strings.Contains(verbose, `"language": "english"`) {
+		t.Errorf("audioResponseText(verbose_json) = %q, %v", verbose, err)
+	}
+
+	if got := audioResponseFormat(ResponseFormatText); got != "" {
+		t.Errorf("audioResponseFormat(text) = %q, want API default", got)
+	}
+	if got := audioResponseFormat(ResponseFormatVTT); got != openai.AudioResponseFormatVTT {
+		t.Errorf("audioResponseFormat(vtt) = %q, want vtt", got)
+	}
+}
+
+func TestGPT4oTranscribeModels(t *testing.T) {
+	for _, model := range []string{"gpt-4o-transcribe", "gpt-4o-mini-transcribe"} {
+		if !IsSupportedModel("openai", TaskTranscribe, model) {
+			t.Errorf("IsSupportedModel(openai, transcribe, %s) = false, want true", model)
+		}
+		if IsSupportedModel("openai", TaskTranslate, model) {
+			t.Errorf("IsSupportedModel(openai, translate, %s) = true, want false", model)
+		}
+		if got := strings.Join(ResponseFormats("openai", model), ","); got != "text,json" {
+			t.Errorf("ResponseFormats(openai, %s) = %s, want text,json", model, got)
+		}
+	}
+	if IsSupportedResponseFormat("openai", "gpt-4o-transcribe", ResponseFormatSRT) {
+		t.Error("gpt-4o-transcribe should not support srt")
+	}
+	if !IsSupportedResponseFormat("openai", "whisper-1", ResponseFormatSRT) {
+		t.Error("whisper-1 should still support srt")
+	}
+
+	// Translation is refused before any request is sent
+	adapter := NewOpenAIAdapter(Config{Provider: "openai", Task: TaskTranslate, APIKey: "test-key", Model: "gpt-4o-transcribe"})
+	if _, err := adapter.Transcribe(context.Background(), make([]byte, 320)); err == nil || !strings.Contains(err.Error(), "cannot translate") {
+		t.Errorf("Transcribe() error = %v, want cannot translate", err)
+	}
+}
+
+func TestUpsamplePCM(t *testing.T) {
+	pcm := func(samples ...int16) []byte {
+		var b []byte
+		for _, s := range samples {
+			b = binary.LittleEndian.AppendUint16(b, uint16(s))
+		}
+		return b
+	}
+
+	out, next := upsamplePCM(pcm(0, 300, 600, 900), 0)
+	if !bytes.Equal(out, pcm(0, 200, 400, 600, 800)) || next != 5 {
+		t.Errorf("upsamplePCM() = %v, %d", out, next)
+	}
+
+	// More audio continues exactly where the last chunk stopped
+	out, next = upsamplePCM(pcm(0, 300, 600, 900, 1200), next)
+	if !bytes.Equal(out, pcm(1000, 1200)) || next != 7 {
+		t.Errorf("upsamplePCM() continued = %v, %d", out, next)
+	}
+}
+
+// fakeRealtimeServer answers the websocket handshake and hands the server side of the
+// connection to handle
+func fakeRealtimeServer(t *testing.T, handle func(ws *wsConn)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+			wsAcceptKey(r.Header.Get("Sec-WebSocket-Key")))
+		handle(newWSConn(conn, brw.Reader, false))
+	}))
+}
+
+func TestRealtimeTranscriber_Streams(t *testing.T) {
+	var mu sync.Mutex
+	var streamed int
+	server := fakeRealtimeServer(t, func(ws *wsConn) {
+		reply := func(event string) { ws.WriteText([]byte(event)) }
+		for {
+			_, message, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var event struct {
+				Type  string `json:"type"`
+				Audio string `json:"audio"`
+			}
+			json.Unmarshal(message, &event)
+			switch event.Type {
+			case "input_audio_buffer.append":
+				audio, _ := base64.StdEncoding.DecodeString(event.Audio)
+				mu.Lock()
+				first := streamed == 0
+				streamed += len(audio)
+				mu.Unlock()
+				if first {
+					reply(`{"type":"input_audio_buffer.committed","item_id":"item_1"}`)
+					reply(`{"type":"conversation.item.input_audio_transcription.delta","item_id":"item_1","delta":"Hello"}`)
+					reply(`{"type":"conversation.item.input_audio_transcription.completed","item_id":"item_1","transcript":"Hello world."}`)
+				}
+			case "input_audio_buffer.commit":
+				reply(`{"type":"input_audio_buffer.committed","item_id":"item_2"}`)
+				reply(`{"type":"conversation.item.input_audio_transcription.completed","item_id":"item_2","transcript":" Bye."}`)
+			}
+		}
+	})
+	defer server.Close()
+
+	adapter := &MockTranscriptionAdapter{
+		TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+			t.Error("batch adapter called while the websocket worked")
+			return "", nil
+		},
+	}
+	rt := NewRealtimeTranscriber(Config{Provider: "openai", APIKey: "test-key", Model: "gpt-4o-transcribe"}, adapter)
+	rt.url = "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	frameCh := make(chan recording.AudioFrame, 10)
+	if _, err := rt.Start(ctx, frameCh); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	frameCh <- recording.AudioFrame{Data: make([]byte, 3200)}
+	<-rt.connReady
+	frameCh <- recording.AudioFrame{Data: make([]byte, 3200)}
+	close(frameCh)
+
+	if err := rt.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	result, _ := rt.GetFinalTranscription()
+	if result.Text != "Hello world. Bye." {
+		t.Errorf("GetFinalTranscription() = %q, want %q", result.Text, "Hello world. Bye.")
+	}
+	if want := []string{"Hello world.", "Bye."}; fmt.Sprint(result.Segments) != fmt.Sprint(want) {
+		t.Errorf("Segments = %q, want %q", result.Segments, want)
+	}
+	if result.Duration != 200*time.Millisecond {
+		t.Errorf("Duration = %v, want 200ms", result.Duration)
+	}
+
+	var partials []string
+	for partial := range rt.Partials() {
+		partials = append(partials, partial)
+	}
+	if len(partials) == 0 || partials[0] != "Hello" {
+		t.Errorf("Partials() = %q, want to start with %q", partials, "Hello")
+	}
+
+	// 3200 samples at 16 kHz become 4800 at 24 kHz, less the last one awaiting its neighbour
+	mu.Lock()
+	defer mu.Unlock()
+	if streamed != 2*4799 {
+		t.Errorf("streamed %d bytes, want %d", streamed, 2*4799)
+	}
+}
+
+func TestRealtimeTranscriber_StalledServer(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	server := fakeRealtimeServer(t, func(ws *wsConn) {
+		ws.ReadMessage() // The session update, then nothing is read again
+		<-release
+	})
+	defer server.Close()
+
+	rt := NewRealtimeTranscriber(Config{Provider: "openai", APIKey: "test-key", Model: "gpt-4o-transcribe"}, &MockTranscriptionAdapter{})
+	rt.url = "ws" + strings.TrimPrefix(server.URL, "http")
+
+	frameCh := make(chan recording.AudioFrame)
+	if _, err := rt.Start(context.Background(), frameCh); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-rt.connReady
+
+	// Far more audio than the socket buffers hold: recording must keep going regardless
+	frame := recording.AudioFrame{Data: make([]byte, 32000)}
+	for i := 0; i < 200; i++ {
+		select {
+		case frameCh <- frame:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("frame %d blocked behind the stalled websocket", i)
+		}
+	}
+	close(frameCh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- rt.Stop(ctx) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Stop() error = %v, want the context's deadline", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop() hung on the stalled websocket")
+	}
+}
+
+func TestRealtimeTranscriber_FallsBackToBatch(t *testing.T) {
+	server := fakeRealtimeServer(t, func(ws *wsConn) {})
+	server.Close() // Nothing listens: the dial fails
+
+	var batched int
+	adapter := &MockTranscriptionAdapter{
+		TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+			batched = len(audioData)
+			return "batch result", nil
+		},
+	}
+	rt := NewRealtimeTranscriber(Config{Provider: "openai", APIKey: "test-key", Model: "whisper-1"}, adapter)
+	rt.url = "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	frameCh := make(chan recording.AudioFrame, 10)
+	if _, err := rt.Start(ctx, frameCh); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	frameCh <- recording.AudioFrame{Data: make([]byte, 3200)}
+	frameCh <- recording.AudioFrame{Data: make([]byte, 3200)}
+	close(frameCh)
+
+	if err := rt.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if result, _ := rt.GetFinalTranscription(); result.Text != "batch result" {
+		t.Errorf("GetFinalTranscription() = %q, want batch result", result.Text)
+	}
+	if batched != 6400 {
+		t.Errorf("batch got %d bytes, want 6400", batched)
+	}
+}
+
+func TestNewTranscriber_Realtime(t *testing.T) {
+	config := Config{Provider: "openai", APIKey: "test-key", Model: "gpt-4o-transcribe", Realtime: true}
+	tr, err := NewTranscriber(config)
+	if err != nil {
+		t.Fatalf("NewTranscriber() error = %v", err)
+	}
+	if _, ok := tr.(PartialTranscriber); !ok {
+		t.Errorf("NewTranscriber() = %T, want a realtime transcriber", tr)
+	}
+
+	// File transcription formats other than text stay on the batch endpoint
+	config.ResponseFormat = ResponseFormatJSON
+	tr, err = NewTranscriber(config)
+	if err != nil {
+		t.Fatalf("NewTranscriber() error = %v", err)
+	}
+	if _, ok := tr.(*SimpleTranscriber); !ok {
+		t.Errorf("NewTranscriber() with json = %T, want *SimpleTranscriber", tr)
+	}
+}
+
+func TestDeferredTranscriber(t *testing.T) {
+	var calls int
+	var uploaded int
+	adapter := &MockTranscriptionAdapter{
+		TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+			calls++
+			uploaded = len(audioData)
+			return "deferred text", nil
+		},
+	}
+	tr := NewDeferredTranscriber(NewSimpleTranscriber(Config{Provider: "groq"}, adapter))
+
+	frameCh := make(chan recording.AudioFrame, 2)
+	errCh, err := tr.Start(context.Background(), frameCh)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	frameCh <- recording.AudioFrame{Data: make([]byte, 1600)}
+	frameCh <- recording.AudioFrame{Data: make([]byte, 1600)}
+	close(frameCh)
+
+	time.Sleep(20 * time.Millisecond)
+	if calls != 0 {
+		t.Fatalf("adapter called %d times before Stop, want 0", calls)
+	}
+
+	if err := tr.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if calls != 1 || uploaded != 3200 {
+		t.Errorf("adapter got %d calls with %d bytes, want 1 call with 3200", calls, uploaded)
+	}
+	if result, _ := tr.GetFinalTranscription(); result.Text != "deferred text" {
+		t.Errorf("GetFinalTranscription() = %q, want %q", result.Text, "deferred text")
+	}
+	for range errCh {
+	}
+}
+
+func TestNewTranscriber_Deferred(t *testing.T) {
+	tr, err := NewTranscriber(Config{Provider: "openai", APIKey: "test-key", Model: "whisper-1", Deferred: true})
+	if err != nil {
+		t.Fatalf("NewTranscriber() error = %v", err)
+	}
+	if _, ok := tr.(*DeferredTranscriber); !ok {
+		t.Errorf("NewTranscriber() = %T, want *DeferredTranscriber", tr)
+	}
+
+	// The API key is still checked up front
+	if _, err := NewTranscriber(Config{Provider: "openai", Model: "whisper-1", Deferred: true}); err == nil {
+		t.Error("NewTranscriber() without an API key succeeded, want error")
+	}
+}
+
+func TestAzureOpenAIAdapter(t *testing.T) {
+	var gotPath, gotVersion, gotKey, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotVersion = r.URL.Path, r.URL.Query().Get("api-version")
+		gotKey, gotAuth = r.Header.Get("api-key"), r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"text":"hello azure"}`)
+	}))
+	defer server.Close()
+
+	tr, err := NewTranscriber(Config{
+		Provider:   "azure-openai",
+		APIKey:     "azure-key",
+		Model:      "my-whisper.v1",
+		Endpoint:   server.URL,
+		APIVersion: "2024-06-01",
+	})
+	if err != nil {
+		t.Fatalf("NewTranscriber() error = %v", err)
+	}
+	adapter := tr.(*SimpleTranscriber).adapter
+
+	text, err := adapter.Transcribe(context.Background(), make([]byte, 3200))
+	if err != nil {
+		t.Fatalf("Transcribe() error = %v", err)
+	}
+	if text != "hello azure" {
+		t.Errorf("Transcribe() = %q, want %q", text, "hello azure")
+	}
+	// The deployment name is used as is, dots included
+	if gotPath != "/openai/deployments/my-whisper.v1/audio/transcriptions" {
+		t.Errorf("request path = %q", gotPath)
+	}
+	if gotVersion != "2024-06-01" {
+		t.Errorf("api-version = %q, want 2024-06-01", gotVersion)
+	}
+	if gotKey != "azure-key" || gotAuth != "" {
+		t.Errorf("api-key = %q, Authorization = %q, want api-key auth only", gotKey, gotAuth)
+	}
+
+	if _, err := NewTranscriber(Config{Provider: "azure-openai", APIKey: "azure-key", Model: "my-whisper"}); err == nil {
+		t.Error("NewTranscriber() without an endpoint succeeded, want error")
+	}
+}
@@ -0,0 +1,70 @@
+package transcriber
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// UploadProgressFunc is called while audio is uploaded with the bytes sent so far and the total size
+type UploadProgressFunc func(sent, total int64)
+
+// uploadProgressStep is the fraction of the upload between two progress reports
+const uploadProgressStep = 0.25
+
+// progressHTTPClient wraps an HTTP client and reports upload progress of request bodies.
+// It satisfies openai.HTTPDoer so it can be plugged into the go-openai client config.
+type progressHTTPClient struct {
+	client     *http.Client
+	onProgress UploadProgressFunc
+}
+
+func newProgressHTTPClient(onProgress UploadProgressFunc) *progressHTTPClient {
+	return &progressHTTPClient{
+		client:     &http.Client{},
+		onProgress: onProgress,
+	}
+}
+
+func (c *progressHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.ContentLength > 0 && c.onProgress != nil {
+		req.Body = &progressReader{
+			ctx:        req.Context(),
+			body:       req.Body,
+			total:      req.ContentLength,
+			onProgress: c.onProgress,
+		}
+	}
+	return c.client.Do(req)
+}
+
+// progressReader reports read progress every uploadProgressStep and stops reading once its context is done,
+// so a cancelled pipeline aborts the upload mid-flight instead of finishing it
+type progressReader struct {
+	ctx        context.Context
+	body       io.ReadCloser
+	total      int64
+	onProgress UploadProgressFunc
+
+	sent     int64
+	lastStep int
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := r.body.Read(p)
+
+	r.sent += int64(n)
+	if step := int(float64(r.sent) / float64(r.total) / uploadProgressStep); step > r.lastStep {
+		r.lastStep = step
+		r.onProgress(r.sent, r.total)
+	}
+	return n, err
+}
+
+func (r *progressReader) Close() error {
+	return r.body.Close()
+}